@@ -0,0 +1,307 @@
+// Package alertmanager sends alerts for the checks reported down by Pingdom
+// to Alertmanager, without Prometheus evaluating alerting rules, and manages
+// Alertmanager silences.
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+	"github.com/giantswarm/prometheus-pingdom-exporter/rules"
+)
+
+// AlertName is the name of the alerts of down checks, the same as the one of
+// the generated alerting rules.
+const AlertName = "PingdomCheckDown"
+
+// CheckSource returns the checks of the last poll, see
+// exporter.Exporter.Checks.
+type CheckSource interface {
+	Checks() []pingdom.CheckResponse
+}
+
+// Alert is an alert of the Alertmanager API v2.
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+}
+
+// Config represents the configuration used to create a Notifier.
+type Config struct {
+	// URLs are the addresses of the Alertmanagers, e.g.
+	// http://alertmanager:9093. Alerts are sent to every Alertmanager of a
+	// cluster.
+	URLs   []string
+	Checks CheckSource
+	// Rules holds the label templates of the alerts, like for the generated
+	// alerting rules. Its durations are not used, checks fire as soon as a
+	// poll reports them down.
+	Rules *rules.File
+	// FiringStatuses are the statuses of the checks which fire an alert.
+	FiringStatuses []string
+	// ResendInterval is the time between sends of the firing alerts while no
+	// check changes, which keeps them from being resolved by Alertmanager.
+	ResendInterval time.Duration
+	// HTTPClient sends the requests, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	Logger     log.Logger
+}
+
+// DefaultConfig provides a default configuration to create a Notifier.
+func DefaultConfig() Config {
+	return Config{
+		URLs:           nil,
+		Checks:         nil,
+		Rules:          rules.DefaultFile(),
+		FiringStatuses: []string{"down"},
+		ResendInterval: time.Minute,
+		HTTPClient:     http.DefaultClient,
+		Logger:         log.NewNopLogger(),
+	}
+}
+
+// Notifier tracks the transitions of the checks between successive polls,
+// and sends alerts for the firing checks to Alertmanager, and resolves them
+// once the checks recover.
+type Notifier struct {
+	urls           []string
+	checks         CheckSource
+	rules          *rules.File
+	firingStatuses map[string]bool
+	resendInterval time.Duration
+	httpClient     *http.Client
+	logger         log.Logger
+
+	mu sync.Mutex
+	// firing are the alerts of the firing checks, by check ID.
+	firing map[int]*Alert
+	// resolved are the alerts resolved since the last successful send.
+	resolved []Alert
+	lastPush time.Time
+	lastSent time.Time
+}
+
+// New creates a new configured Notifier.
+func New(config Config) (*Notifier, error) {
+	if len(config.URLs) == 0 {
+		return nil, fmt.Errorf("config.URLs must not be empty")
+	}
+	if config.Checks == nil {
+		return nil, fmt.Errorf("config.Checks must not be empty")
+	}
+	if config.Rules == nil {
+		return nil, fmt.Errorf("config.Rules must not be empty")
+	}
+	if len(config.FiringStatuses) == 0 {
+		return nil, fmt.Errorf("config.FiringStatuses must not be empty")
+	}
+	if config.ResendInterval <= 0 {
+		return nil, fmt.Errorf("config.ResendInterval must be positive")
+	}
+	if config.HTTPClient == nil {
+		return nil, fmt.Errorf("config.HTTPClient must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, fmt.Errorf("config.Logger must not be empty")
+	}
+
+	n := &Notifier{
+		urls:           config.URLs,
+		checks:         config.Checks,
+		rules:          config.Rules,
+		firingStatuses: map[string]bool{},
+		resendInterval: config.ResendInterval,
+		httpClient:     config.HTTPClient,
+		logger:         config.Logger,
+
+		firing: map[int]*Alert{},
+	}
+	for _, status := range config.FiringStatuses {
+		n.firingStatuses[status] = true
+	}
+
+	return n, nil
+}
+
+// Push compares the checks with the ones of the previous push, and sends the
+// alerts if a check started or stopped firing, or the resend interval passed.
+// It implements push.Pusher, to run after every poll.
+func (n *Notifier) Push(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := time.Now()
+	changed := n.update(n.checks.Checks(), now)
+
+	// Firing alerts end after a few pushes without being sent again, so that
+	// they resolve if the exporter stops.
+	validity := n.resendInterval
+	if !n.lastPush.IsZero() && now.Sub(n.lastPush) > validity {
+		validity = now.Sub(n.lastPush)
+	}
+	n.lastPush = now
+
+	if !changed && len(n.resolved) == 0 && now.Sub(n.lastSent) < n.resendInterval {
+		return nil
+	}
+	if len(n.firing) == 0 && len(n.resolved) == 0 {
+		return nil
+	}
+
+	alerts := append([]Alert(nil), n.resolved...)
+	for _, alert := range n.firing {
+		a := *alert
+		a.EndsAt = now.Add(4 * validity)
+		alerts = append(alerts, a)
+	}
+
+	var lastErr error
+	for _, u := range n.urls {
+		if err := n.send(ctx, u, alerts); err != nil {
+			level.Error(n.logger).Log("msg", "failed to send alerts", "alertmanager", u, "err", err)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		// The resolved alerts are sent again with the next push.
+		return lastErr
+	}
+
+	level.Debug(n.logger).Log("msg", "sent alerts", "firing", len(n.firing), "resolved", len(n.resolved))
+	n.resolved = nil
+	n.lastSent = now
+
+	return nil
+}
+
+// update updates the firing alerts from the checks, and returns true if an
+// alert started or stopped firing.
+func (n *Notifier) update(checks []pingdom.CheckResponse, now time.Time) bool {
+	changed := false
+	seen := map[int]bool{}
+
+	for _, check := range checks {
+		seen[check.ID] = true
+		alert, ok := n.firing[check.ID]
+
+		if !n.firingStatuses[check.Status] {
+			if ok {
+				level.Info(n.logger).Log("msg", "check recovered", "check_id", check.ID, "status", check.Status)
+				n.resolve(check.ID, now)
+				changed = true
+			}
+			continue
+		}
+
+		labels, err := CheckLabels(n.rules, check)
+		if err != nil {
+			level.Error(n.logger).Log("msg", "failed to generate alert labels", "check_id", check.ID, "err", err)
+			continue
+		}
+		annotations := map[string]string{
+			"summary":     fmt.Sprintf("Pingdom check %s is %s", check.Name, check.Status),
+			"description": fmt.Sprintf("Pingdom reports %s (%s) %s.", check.Name, check.Hostname, check.Status),
+		}
+
+		if ok && reflect.DeepEqual(alert.Labels, labels) {
+			alert.Annotations = annotations
+			continue
+		}
+		if ok {
+			// The labels identify the alert, so the alert with the old labels
+			// is resolved.
+			n.resolve(check.ID, now)
+		} else {
+			level.Info(n.logger).Log("msg", "check firing", "check_id", check.ID, "status", check.Status)
+		}
+
+		n.firing[check.ID] = &Alert{
+			Labels:      labels,
+			Annotations: annotations,
+			StartsAt:    now,
+		}
+		changed = true
+	}
+
+	// The alerts of deleted checks are resolved.
+	for id := range n.firing {
+		if !seen[id] {
+			n.resolve(id, now)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func (n *Notifier) resolve(id int, now time.Time) {
+	alert := *n.firing[id]
+	alert.EndsAt = now
+	n.resolved = append(n.resolved, alert)
+	delete(n.firing, id)
+}
+
+// CheckLabels returns the labels of the alerts of the check: the ones of the
+// alerting rules configuration, and the labels of the check metrics.
+func CheckLabels(file *rules.File, check pingdom.CheckResponse) (map[string]string, error) {
+	labels, err := rules.CheckLabels(file, check)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := exporter.WithoutPauseTags(check)
+
+	labels["alertname"] = AlertName
+	labels["id"] = strconv.Itoa(check.ID)
+	labels["name"] = check.Name
+	labels["hostname"] = check.Hostname
+	if len(tags) > 0 {
+		labels["tags"] = strings.Join(tags, ",")
+	}
+
+	return labels, nil
+}
+
+// send posts the alerts to the Alertmanager at u.
+func (n *Notifier) send(ctx context.Context, u string, alerts []Alert) error {
+	content, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(u, "/")+"/api/v2/alerts", bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected response %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
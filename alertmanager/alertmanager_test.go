@@ -0,0 +1,170 @@
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/rules"
+)
+
+type staticChecks struct {
+	checks []pingdom.CheckResponse
+}
+
+func (s *staticChecks) Checks() []pingdom.CheckResponse {
+	return s.checks
+}
+
+// alertmanager records the alerts posted to it.
+type alertmanager struct {
+	mu       sync.Mutex
+	requests [][]Alert
+	fail     bool
+}
+
+func (a *alertmanager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || r.URL.Path != "/api/v2/alerts" {
+		http.NotFound(w, r)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.fail {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var alerts []Alert
+	if err := json.NewDecoder(r.Body).Decode(&alerts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.requests = append(a.requests, alerts)
+}
+
+// last returns the alerts of the last request, and the number of requests.
+func (a *alertmanager) last() ([]Alert, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.requests) == 0 {
+		return nil, 0
+	}
+
+	return a.requests[len(a.requests)-1], len(a.requests)
+}
+
+func TestNotifier(t *testing.T) {
+	am := &alertmanager{}
+	server := httptest.NewServer(am)
+	defer server.Close()
+
+	checks := &staticChecks{checks: []pingdom.CheckResponse{
+		{ID: 1, Name: "Shop", Hostname: "shop.example.com", Status: "up", Tags: []pingdom.CheckResponseTag{{Name: "team-shop"}}},
+		{ID: 2, Name: "API", Hostname: "api.example.com", Status: "up"},
+	}}
+
+	file := rules.DefaultFile()
+	file.Labels["team"] = `{{ tagPrefix "team-" }}`
+
+	c := DefaultConfig()
+	c.URLs = []string{server.URL + "/"}
+	c.Checks = checks
+	c.Rules = file
+	c.ResendInterval = time.Hour
+	n, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	// No alert is sent while every check is up.
+	if err := n.Push(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, count := am.last(); count != 0 {
+		t.Fatalf("expected no request, got %d", count)
+	}
+
+	checks.checks[0].Status = "down"
+	if err := n.Push(ctx); err != nil {
+		t.Fatal(err)
+	}
+	alerts, count := am.last()
+	if count != 1 || len(alerts) != 1 {
+		t.Fatalf("expected 1 request with 1 alert, got %d requests and %v", count, alerts)
+	}
+	labels := alerts[0].Labels
+	if labels["alertname"] != AlertName || labels["id"] != "1" || labels["check_id"] != "1" || labels["name"] != "Shop" || labels["tags"] != "team-shop" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+	if labels["team"] != "shop" || labels["severity"] != "warning" {
+		t.Errorf("expected the labels of the rules configuration, got %v", labels)
+	}
+	if !alerts[0].EndsAt.After(time.Now()) {
+		t.Errorf("expected the firing alert to end in the future, got %v", alerts[0].EndsAt)
+	}
+	startsAt := alerts[0].StartsAt
+
+	// The alert is not sent again before the resend interval.
+	if err := n.Push(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, count := am.last(); count != 1 {
+		t.Fatalf("expected no new request, got %d requests", count)
+	}
+
+	// Resolved alerts are sent again after a failure.
+	checks.checks[0].Status = "up"
+	am.fail = true
+	if err := n.Push(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+	am.fail = false
+	if err := n.Push(ctx); err != nil {
+		t.Fatal(err)
+	}
+	alerts, count = am.last()
+	if count != 2 || len(alerts) != 1 {
+		t.Fatalf("expected 2 requests and 1 alert, got %d requests and %v", count, alerts)
+	}
+	if !alerts[0].StartsAt.Equal(startsAt) || alerts[0].EndsAt.After(time.Now()) {
+		t.Errorf("expected the alert to be resolved, got %+v", alerts[0])
+	}
+
+	// The alerts of deleted checks are resolved.
+	checks.checks[1].Status = "down"
+	if err := n.Push(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checks.checks = checks.checks[:1]
+	if err := n.Push(ctx); err != nil {
+		t.Fatal(err)
+	}
+	alerts, count = am.last()
+	if count != 4 || len(alerts) != 1 || alerts[0].Labels["id"] != "2" || alerts[0].EndsAt.After(time.Now()) {
+		t.Fatalf("expected the alert of check 2 to be resolved, got %d requests and %v", count, alerts)
+	}
+}
+
+func TestCheckLabelsWithoutPauseTags(t *testing.T) {
+	check := pingdom.CheckResponse{ID: 1, Name: "a", Paused: true, Tags: []pingdom.CheckResponseTag{{Name: "production"}, {Name: "paused-until-201805041330"}, {Name: "paused-by-jane"}}}
+
+	labels, err := CheckLabels(rules.DefaultFile(), check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels["tags"] != "production" {
+		t.Errorf("tags = %q, want production", labels["tags"])
+	}
+}
@@ -0,0 +1,188 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Silence states of the Alertmanager API v2.
+const (
+	SilenceActive  = "active"
+	SilencePending = "pending"
+	SilenceExpired = "expired"
+)
+
+// Silence is a silence of the Alertmanager API v2.
+type Silence struct {
+	ID        string    `json:"id,omitempty"`
+	Matchers  []Matcher `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+	// Status is set by Alertmanager.
+	Status *SilenceStatus `json:"status,omitempty"`
+}
+
+// SilenceStatus is the status of a silence.
+type SilenceStatus struct {
+	State string `json:"state"`
+}
+
+// State returns the state of the silence, or an empty string if it is unknown.
+func (s Silence) State() string {
+	if s.Status == nil {
+		return ""
+	}
+
+	return s.Status.State
+}
+
+// Matcher matches the alerts with a label, like in a silence.
+type Matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	// IsEqual is false for negative matchers, and true if it is not set.
+	IsEqual *bool `json:"isEqual,omitempty"`
+}
+
+// Equal returns true if the matcher is not negative.
+func (m Matcher) Equal() bool {
+	return m.IsEqual == nil || *m.IsEqual
+}
+
+// Matches returns true if the labels match. Missing labels have an empty
+// value, and regular expressions are anchored, like in Alertmanager.
+func (m Matcher) Matches(labels map[string]string) (bool, error) {
+	value := labels[m.Name]
+
+	matches := value == m.Value
+	if m.IsRegex {
+		re, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return false, err
+		}
+		matches = re.MatchString(value)
+	}
+
+	return matches == m.Equal(), nil
+}
+
+// ClientConfig represents the configuration used to create a Client.
+type ClientConfig struct {
+	// URL is the address of the Alertmanager, e.g. http://alertmanager:9093.
+	URL string
+	// HTTPClient sends the requests, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// DefaultClientConfig provides a default configuration to create a Client.
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		URL:        "",
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Client manages the silences of an Alertmanager. Alertmanagers of a cluster
+// share their silences, so a single one of them is enough.
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewClient creates a new configured Client.
+func NewClient(config ClientConfig) (*Client, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("config.URL must not be empty")
+	}
+	if config.HTTPClient == nil {
+		return nil, fmt.Errorf("config.HTTPClient must not be empty")
+	}
+
+	c := &Client{
+		url:        strings.TrimSuffix(config.URL, "/"),
+		httpClient: config.HTTPClient,
+	}
+
+	return c, nil
+}
+
+// ListSilences returns the silences, including the expired ones Alertmanager
+// still keeps.
+func (c *Client) ListSilences(ctx context.Context) ([]Silence, error) {
+	var silences []Silence
+	if err := c.do(ctx, "GET", "/api/v2/silences", nil, &silences); err != nil {
+		return nil, err
+	}
+
+	return silences, nil
+}
+
+// CreateSilence creates the silence, or updates it if its ID is set, and
+// returns the ID of the silence. Alertmanager replaces active silences by a
+// new one when their matchers or start change.
+func (c *Client) CreateSilence(ctx context.Context, silence Silence) (string, error) {
+	silence.Status = nil
+
+	var resp struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := c.do(ctx, "POST", "/api/v2/silences", silence, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.SilenceID, nil
+}
+
+// ExpireSilence ends the silence.
+func (c *Client) ExpireSilence(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/api/v2/silence/"+url.PathEscape(id), nil, nil)
+}
+
+// do sends a request with the JSON encoded body, if any, and decodes the
+// response into v, if not nil.
+func (c *Client) do(ctx context.Context, method, path string, body, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, c.url+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		content, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected response %s: %s", resp.Status, bytes.TrimSpace(content))
+	}
+	if v == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
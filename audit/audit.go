@@ -0,0 +1,240 @@
+// Package audit reports the checks of a Pingdom account violating
+// configuration policies, e.g. checks nobody is notified about.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+// Rules of the policy.
+const (
+	// RuleNoContacts reports checks without active notification contacts or
+	// integrations.
+	RuleNoContacts = "no-contacts"
+	// RulePaused reports checks paused for longer than MaxPaused.
+	RulePaused = "paused"
+	// RuleResolution reports checks tested less often than MaxResolution.
+	RuleResolution = "resolution"
+	// RuleShouldContain reports HTTP checks not verifying the response body.
+	RuleShouldContain = "should-contain"
+	// RuleDuplicate reports checks testing the same hostname as another
+	// check.
+	RuleDuplicate = "duplicate"
+	// RuleRequiredTags reports checks missing a required tag.
+	RuleRequiredTags = "required-tags"
+)
+
+// Rules are all the rules, in the order of the findings of a check.
+var Rules = []string{RuleNoContacts, RulePaused, RuleResolution, RuleShouldContain, RuleDuplicate, RuleRequiredTags}
+
+// API is the part of the Pingdom API used by the audit. exporter.Client
+// implements it.
+type API interface {
+	ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error)
+	ReadCheck(ctx context.Context, id int) (*pingdom.CheckResponse, error)
+	ListContacts(ctx context.Context) ([]pingdom.ContactResponse, error)
+}
+
+// Policy configures the rules.
+type Policy struct {
+	// MaxPaused is how long checks may be paused, 0 disables the rule.
+	// Paused checks are not tested, so their last test is taken as the time
+	// they were paused.
+	MaxPaused time.Duration
+	// MaxResolution is the highest number of minutes between tests, 0
+	// disables the rule.
+	MaxResolution int
+	// RequiredTags are the tags every check must have. A required tag ending
+	// with - is a prefix, e.g. team- requires a tag like team-web.
+	RequiredTags []string
+	// Ignore are the rules not to check.
+	Ignore []string
+}
+
+// DefaultPolicy is the policy used without flags.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxPaused:     30 * 24 * time.Hour,
+		MaxResolution: 5,
+		RequiredTags:  nil,
+		Ignore:        nil,
+	}
+}
+
+// Finding is a policy violation of a check.
+type Finding struct {
+	Rule      string `json:"rule"`
+	CheckID   int    `json:"check_id"`
+	CheckName string `json:"check_name"`
+	Hostname  string `json:"hostname"`
+	Message   string `json:"message"`
+}
+
+// Load lists the checks and reads their details, including their contacts
+// and the settings of their type, and lists the contacts.
+func Load(ctx context.Context, api API) ([]pingdom.CheckResponse, []pingdom.ContactResponse, error) {
+	list, err := api.ListChecks(ctx, map[string]string{"include_tags": "true"})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var checks []pingdom.CheckResponse
+	for _, check := range list {
+		details, err := api.ReadCheck(ctx, check.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read check %d: %v", check.ID, err)
+		}
+		if len(details.Tags) == 0 {
+			details.Tags = check.Tags
+		}
+		if details.Status == "" {
+			details.Status = check.Status
+		}
+		if details.LastTestTime == 0 {
+			details.LastTestTime = check.LastTestTime
+		}
+		checks = append(checks, *details)
+	}
+
+	contacts, err := api.ListContacts(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return checks, contacts, nil
+}
+
+// Audit returns the findings of the checks, sorted by check ID and in the
+// order of Rules.
+func Audit(policy Policy, checks []pingdom.CheckResponse, contacts []pingdom.ContactResponse, now time.Time) []Finding {
+	ignored := map[string]bool{}
+	for _, rule := range policy.Ignore {
+		ignored[rule] = true
+	}
+
+	active := map[int]bool{}
+	for _, contact := range contacts {
+		if !contact.Paused {
+			active[contact.ID] = true
+		}
+	}
+
+	sorted := append([]pingdom.CheckResponse(nil), checks...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	hostnames := map[string][]pingdom.CheckResponse{}
+	for _, check := range sorted {
+		hostnames[hostname(check)] = append(hostnames[hostname(check)], check)
+	}
+
+	var findings []Finding
+	for _, check := range sorted {
+		add := func(rule, format string, a ...interface{}) {
+			if ignored[rule] {
+				return
+			}
+			findings = append(findings, Finding{
+				Rule:      rule,
+				CheckID:   check.ID,
+				CheckName: check.Name,
+				Hostname:  check.Hostname,
+				Message:   fmt.Sprintf(format, a...),
+			})
+		}
+
+		if len(check.IntegrationIds) == 0 {
+			activeContacts := 0
+			for _, id := range check.ContactIds {
+				if active[id] {
+					activeContacts++
+				}
+			}
+			if len(check.ContactIds) == 0 {
+				add(RuleNoContacts, "no notification contacts or integrations")
+			} else if activeContacts == 0 {
+				add(RuleNoContacts, "all %d notification contacts are paused or deleted", len(check.ContactIds))
+			}
+		}
+
+		if paused := exporter.IsPaused(check); paused && policy.MaxPaused > 0 {
+			since := time.Unix(check.LastTestTime, 0)
+			if check.LastTestTime == 0 {
+				since = time.Unix(check.Created, 0)
+			}
+			if now.Sub(since) > policy.MaxPaused {
+				add(RulePaused, "paused since %s, more than %s", since.UTC().Format("2006-01-02"), formatDays(policy.MaxPaused))
+			}
+		}
+
+		if policy.MaxResolution > 0 && check.Resolution > policy.MaxResolution {
+			add(RuleResolution, "tested every %d minutes, more than %d", check.Resolution, policy.MaxResolution)
+		}
+
+		if h := check.Type.HTTP; h != nil && h.ShouldContain == "" && h.ShouldNotContain == "" {
+			add(RuleShouldContain, "HTTP check does not verify the response body")
+		}
+
+		var others []string
+		for _, other := range hostnames[hostname(check)] {
+			if other.ID != check.ID {
+				others = append(others, fmt.Sprintf("%d (%s)", other.ID, other.Name))
+			}
+		}
+		if len(others) > 0 {
+			add(RuleDuplicate, "same hostname as checks %s", strings.Join(others, ", "))
+		}
+
+		if missing := missingTags(check, policy.RequiredTags); len(missing) > 0 {
+			add(RuleRequiredTags, "missing tags %s", strings.Join(missing, ", "))
+		}
+	}
+
+	return findings
+}
+
+// hostname returns the hostname tested by a check, normalized to compare it
+// with other checks.
+func hostname(check pingdom.CheckResponse) string {
+	return strings.TrimSuffix(strings.ToLower(check.Hostname), ".")
+}
+
+// missingTags returns the required tags the check does not have.
+func missingTags(check pingdom.CheckResponse, required []string) []string {
+	var missing []string
+
+	for _, want := range required {
+		found := false
+		for _, tag := range check.Tags {
+			if tag.Name == want || (strings.HasSuffix(want, "-") && strings.HasPrefix(tag.Name, want)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, want)
+		}
+	}
+
+	return missing
+}
+
+func formatDays(d time.Duration) string {
+	if d%(24*time.Hour) != 0 {
+		return d.String()
+	}
+
+	days := int(d / (24 * time.Hour))
+	if days == 1 {
+		return "1 day"
+	}
+
+	return fmt.Sprintf("%d days", days)
+}
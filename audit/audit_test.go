@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom/fakepingdomtest"
+)
+
+func TestAudit(t *testing.T) {
+	fixtures := fakepingdom.DefaultFixtures()
+	fixtures.Contacts = append(fixtures.Contacts, pingdom.ContactResponse{ID: 2099, Name: "Away", Paused: true})
+	fixtures.Checks[0].Type.HTTP.ShouldContain = ""
+	fixtures.Checks[1].ContactIds = nil
+	fixtures.Checks[3].ContactIds = []int{2099}
+	dup := fixtures.Checks[0]
+	dup.ID = 1006
+	dup.Name = "Website again"
+	dup.IntegrationIds = []int{1}
+	dup.ContactIds = nil
+	// Only the hostname matters, in any case.
+	dup.Hostname = "WWW.example.com"
+	http := *dup.Type.HTTP
+	http.Url = "/login"
+	dup.Type.HTTP = &http
+	fixtures.Checks = append(fixtures.Checks, dup)
+
+	fake := fakepingdomtest.NewServer(t, fixtures)
+	defer fake.Close()
+	api := fake.Client
+
+	checks, contacts, err := Load(context.Background(), api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := DefaultPolicy()
+	policy.RequiredTags = []string{"team-"}
+	policy.Ignore = []string{RuleShouldContain}
+	findings := Audit(policy, checks, contacts, time.Unix(1500000000, 0))
+
+	type finding struct {
+		rule    string
+		checkID int
+	}
+	var got []finding
+	for _, f := range findings {
+		got = append(got, finding{f.Rule, f.CheckID})
+	}
+	want := []finding{
+		{RuleDuplicate, 1001},
+		{RuleNoContacts, 1002},
+		{RuleRequiredTags, 1002},
+		{RuleRequiredTags, 1003},
+		{RuleNoContacts, 1004},
+		{RuleRequiredTags, 1004},
+		{RuleResolution, 1005},
+		{RuleRequiredTags, 1005},
+		{RuleDuplicate, 1006},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got findings %v, want %v", got, want)
+	}
+	if findings[0].Message != "same hostname as checks 1006 (Website again)" {
+		t.Errorf("unexpected message %q", findings[0].Message)
+	}
+
+	// The paused check was last tested a week before.
+	policy.MaxPaused = 24 * time.Hour
+	policy.Ignore = nil
+	policy.RequiredTags = nil
+	findings = Audit(policy, checks, contacts, time.Unix(1500000000, 0))
+	var paused, shouldContain []int
+	for _, f := range findings {
+		switch f.Rule {
+		case RulePaused:
+			paused = append(paused, f.CheckID)
+		case RuleShouldContain:
+			shouldContain = append(shouldContain, f.CheckID)
+		}
+	}
+	if !reflect.DeepEqual(paused, []int{1003}) || !reflect.DeepEqual(shouldContain, []int{1001, 1006}) {
+		t.Errorf("unexpected paused %v and should-contain %v findings", paused, shouldContain)
+	}
+}
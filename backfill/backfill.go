@@ -0,0 +1,411 @@
+// Package backfill writes the history of Pingdom checks as OpenMetrics, to
+// be imported into Prometheus with promtool tsdb create-blocks-from
+// openmetrics.
+package backfill
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+const (
+	// resultsWindow is the time range of a results request. Pingdom returns
+	// at most 1000 results per request and accepts offsets up to 43200, so
+	// results are paged through one day at a time.
+	resultsWindow = 24 * time.Hour
+	// resultsLimit is the maximum number of results per request.
+	resultsLimit = 1000
+	// performanceWindow is the time range of a summary.performance request.
+	// Pingdom limits hourly summaries to a week.
+	performanceWindow = 7 * 24 * time.Hour
+)
+
+// PingdomAPI is the part of the Pingdom API used to backfill.
+// exporter.Client implements it.
+type PingdomAPI interface {
+	ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error)
+	Results(ctx context.Context, checkID int, params map[string]string) ([]exporter.Result, error)
+	Performance(ctx context.Context, checkID int, resolution string, params map[string]string) ([]exporter.PerformanceSummary, error)
+}
+
+// family is a metric family of the output.
+type family struct {
+	name string
+	help string
+}
+
+var (
+	checkStatus = family{
+		name: "pingdom_check_status",
+		help: "The status of the check, from the test results",
+	}
+	checkResponseTime = family{
+		name: "pingdom_check_response_time",
+		help: "The response time of the test in milliseconds, from the test results",
+	}
+	performanceAvgResponse = family{
+		name: "pingdom_check_performance_avg_response_time",
+		help: "The average response time of the check in milliseconds, from summary.performance",
+	}
+	performanceUptime = family{
+		name: "pingdom_check_performance_uptime_seconds",
+		help: "The time the check was up, from summary.performance",
+	}
+	performanceDowntime = family{
+		name: "pingdom_check_performance_downtime_seconds",
+		help: "The time the check was down, from summary.performance",
+	}
+	performanceUnmonitored = family{
+		name: "pingdom_check_performance_unmonitored_seconds",
+		help: "The time the check was not monitored, from summary.performance",
+	}
+
+	families = []family{
+		checkStatus,
+		checkResponseTime,
+		performanceAvgResponse,
+		performanceUptime,
+		performanceDowntime,
+		performanceUnmonitored,
+	}
+)
+
+// resultStatuses maps the statuses of test results to check statuses, where
+// they differ.
+var resultStatuses = map[string]string{
+	"unconfirmed": "unconfirmed_down",
+}
+
+// Config represents the configuration used to create a Backfiller.
+type Config struct {
+	API    PingdomAPI
+	Logger log.Logger
+
+	// StatusValues maps check statuses to the values of
+	// pingdom_check_status, see exporter.ParseStatusValues.
+	StatusValues map[string]float64
+	// CheckIDs restricts the backfill to the given checks. All checks are
+	// backfilled if it is empty.
+	CheckIDs []int
+
+	From time.Time
+	To   time.Time
+}
+
+// DefaultConfig provides a default configuration to create a Backfiller.
+func DefaultConfig() Config {
+	return Config{
+		API:          nil,
+		Logger:       log.NewNopLogger(),
+		StatusValues: exporter.DefaultStatusValues,
+		CheckIDs:     nil,
+		From:         time.Time{},
+		To:           time.Time{},
+	}
+}
+
+// Backfiller writes the test results and hourly performance summaries of
+// checks as OpenMetrics, with the original timestamps.
+type Backfiller struct {
+	api          PingdomAPI
+	logger       log.Logger
+	statusValues map[string]float64
+	checkIDs     []int
+	from         time.Time
+	to           time.Time
+}
+
+// New creates a new configured Backfiller.
+func New(config Config) (*Backfiller, error) {
+	if config.API == nil {
+		return nil, fmt.Errorf("config.API must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, fmt.Errorf("config.Logger must not be empty")
+	}
+	if len(config.StatusValues) == 0 {
+		return nil, fmt.Errorf("config.StatusValues must not be empty")
+	}
+	if config.From.IsZero() {
+		return nil, fmt.Errorf("config.From must not be empty")
+	}
+	if config.To.IsZero() {
+		return nil, fmt.Errorf("config.To must not be empty")
+	}
+	if !config.From.Before(config.To) {
+		return nil, fmt.Errorf("config.From must be before config.To")
+	}
+
+	b := &Backfiller{
+		api:          config.API,
+		logger:       config.Logger,
+		statusValues: config.StatusValues,
+		checkIDs:     config.CheckIDs,
+		from:         config.From,
+		to:           config.To,
+	}
+
+	return b, nil
+}
+
+// Write writes the history of the checks to w. The samples of a metric
+// family must be contiguous in OpenMetrics, so each family is buffered in a
+// temporary file until all checks are fetched.
+func (b *Backfiller) Write(ctx context.Context, w io.Writer) error {
+	checks, err := b.checks(ctx)
+	if err != nil {
+		return err
+	}
+
+	out := map[string]*familyWriter{}
+	for _, f := range families {
+		fw, err := newFamilyWriter(f)
+		if err != nil {
+			return err
+		}
+		defer fw.Close()
+
+		out[f.name] = fw
+	}
+
+	for _, check := range checks {
+		level.Info(b.logger).Log("msg", "backfilling check", "check_id", check.ID, "name", check.Name)
+
+		if err := b.writeResults(ctx, check, out); err != nil {
+			return fmt.Errorf("failed to backfill results of check %d: %v", check.ID, err)
+		}
+		if err := b.writePerformance(ctx, check, out); err != nil {
+			return fmt.Errorf("failed to backfill performance of check %d: %v", check.ID, err)
+		}
+	}
+
+	for _, f := range families {
+		if err := out[f.name].copyTo(w); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "# EOF\n")
+	return err
+}
+
+// checks returns the checks to backfill, sorted by ID.
+func (b *Backfiller) checks(ctx context.Context) ([]pingdom.CheckResponse, error) {
+	all, err := b.api.ListChecks(ctx, map[string]string{"include_tags": "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []pingdom.CheckResponse
+	if len(b.checkIDs) == 0 {
+		checks = all
+	} else {
+		byID := map[int]pingdom.CheckResponse{}
+		for _, check := range all {
+			byID[check.ID] = check
+		}
+		for _, id := range b.checkIDs {
+			check, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("check %d not found", id)
+			}
+			checks = append(checks, check)
+		}
+	}
+
+	sort.Slice(checks, func(i, j int) bool { return checks[i].ID < checks[j].ID })
+
+	return checks, nil
+}
+
+// writeResults writes pingdom_check_status and pingdom_check_response_time
+// from the test results of the check, oldest first.
+func (b *Backfiller) writeResults(ctx context.Context, check pingdom.CheckResponse, out map[string]*familyWriter) error {
+	labels := checkLabels(check)
+	var last int64
+
+	for start := b.from; start.Before(b.to); start = start.Add(resultsWindow) {
+		end := start.Add(resultsWindow)
+		if end.After(b.to) {
+			end = b.to
+		}
+
+		var results []exporter.Result
+		for offset := 0; ; offset += resultsLimit {
+			page, err := b.api.Results(ctx, check.ID, map[string]string{
+				"from":   strconv.FormatInt(start.Unix(), 10),
+				"to":     strconv.FormatInt(end.Unix()-1, 10),
+				"limit":  strconv.Itoa(resultsLimit),
+				"offset": strconv.Itoa(offset),
+			})
+			if err != nil {
+				return err
+			}
+
+			results = append(results, page...)
+			if len(page) < resultsLimit {
+				break
+			}
+		}
+
+		sort.Slice(results, func(i, j int) bool { return results[i].Time < results[j].Time })
+
+		for _, result := range results {
+			// Samples of a series must be in order, and probes can test a
+			// check in the same second.
+			if result.Time <= last {
+				continue
+			}
+			last = result.Time
+
+			status := result.Status
+			if s, ok := resultStatuses[status]; ok {
+				status = s
+			}
+			if value, ok := b.statusValues[status]; ok {
+				out[checkStatus.name].Sample(labels, value, result.Time)
+			} else {
+				level.Warn(b.logger).Log("msg", "result has a status without a configured value", "check_id", check.ID, "status", result.Status, "time", result.Time)
+			}
+
+			out[checkResponseTime.name].Sample(labels, float64(result.ResponseTime), result.Time)
+		}
+	}
+
+	return nil
+}
+
+// writePerformance writes the pingdom_check_performance metrics from the
+// hourly performance summaries of the check.
+func (b *Backfiller) writePerformance(ctx context.Context, check pingdom.CheckResponse, out map[string]*familyWriter) error {
+	labels := [][2]string{
+		{"id", strconv.Itoa(check.ID)},
+		{"name", check.Name},
+		{"resolution", "hour"},
+	}
+	var last int64
+
+	for start := b.from; start.Before(b.to); start = start.Add(performanceWindow) {
+		end := start.Add(performanceWindow)
+		if end.After(b.to) {
+			end = b.to
+		}
+
+		summaries, err := b.api.Performance(ctx, check.ID, "hour", map[string]string{
+			"from": strconv.FormatInt(start.Unix(), 10),
+			"to":   strconv.FormatInt(end.Unix(), 10),
+		})
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(summaries, func(i, j int) bool { return summaries[i].StartTime < summaries[j].StartTime })
+
+		for _, summary := range summaries {
+			if summary.StartTime <= last {
+				continue
+			}
+			last = summary.StartTime
+
+			out[performanceAvgResponse.name].Sample(labels, float64(summary.AvgResponse), summary.StartTime)
+			out[performanceUptime.name].Sample(labels, float64(summary.Uptime), summary.StartTime)
+			out[performanceDowntime.name].Sample(labels, float64(summary.Downtime), summary.StartTime)
+			out[performanceUnmonitored.name].Sample(labels, float64(summary.Unmonitored), summary.StartTime)
+		}
+	}
+
+	return nil
+}
+
+// checkLabels returns the labels of the check metrics, like the exporter.
+func checkLabels(check pingdom.CheckResponse) [][2]string {
+	names := []string{"id", "name", "hostname", "resolution", "paused", "tags"}
+	values := exporter.CheckLabelValues(check)
+
+	labels := make([][2]string, len(names))
+	for i := range names {
+		labels[i] = [2]string{names[i], values[i]}
+	}
+
+	return labels
+}
+
+// labelValueEscaper escapes label values in OpenMetrics.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// familyWriter buffers the samples of a metric family in a temporary file.
+type familyWriter struct {
+	family family
+	file   *os.File
+	buf    *bufio.Writer
+	err    error
+}
+
+func newFamilyWriter(f family) (*familyWriter, error) {
+	file, err := ioutil.TempFile("", "pingdom-backfill-")
+	if err != nil {
+		return nil, err
+	}
+
+	fw := &familyWriter{
+		family: f,
+		file:   file,
+		buf:    bufio.NewWriter(file),
+	}
+
+	return fw, nil
+}
+
+// Sample writes a sample with a timestamp in seconds. Errors are returned by
+// copyTo.
+func (fw *familyWriter) Sample(labels [][2]string, value float64, timestamp int64) {
+	if fw.err != nil {
+		return
+	}
+
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		pairs[i] = label[0] + `="` + labelValueEscaper.Replace(label[1]) + `"`
+	}
+
+	_, fw.err = fmt.Fprintf(fw.buf, "%s{%s} %s %d\n", fw.family.name, strings.Join(pairs, ","), strconv.FormatFloat(value, 'g', -1, 64), timestamp)
+}
+
+// copyTo writes the metadata and buffered samples of the family to w.
+func (fw *familyWriter) copyTo(w io.Writer) error {
+	if fw.err != nil {
+		return fw.err
+	}
+	if err := fw.buf.Flush(); err != nil {
+		return err
+	}
+	if _, err := fw.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", fw.family.name, fw.family.help, fw.family.name); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, fw.file)
+	return err
+}
+
+// Close removes the temporary file.
+func (fw *familyWriter) Close() error {
+	fw.file.Close()
+	return os.Remove(fw.file.Name())
+}
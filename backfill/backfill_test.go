@@ -0,0 +1,108 @@
+package backfill
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom/fakepingdomtest"
+)
+
+func TestWrite(t *testing.T) {
+	fixtures := fakepingdom.DefaultFixtures()
+	now := fixtures.Checks[1].LastTestTime + 60
+
+	// Two days of results every 30 seconds, more than a page per day.
+	fixtures.Results[1002] = nil
+	for ts := now - 48*3600; ts < now; ts += 30 {
+		status := "up"
+		if ts >= now-3600 {
+			status = "unconfirmed"
+		}
+		fixtures.Results[1002] = append(fixtures.Results[1002], fakepingdom.Result{ProbeID: 1, Time: ts, Status: status, ResponseTime: 100})
+	}
+
+	fake := fakepingdomtest.NewServer(t, fixtures)
+	defer fake.Close()
+	api := fake.Client
+
+	c := DefaultConfig()
+	c.API = api
+	c.CheckIDs = []int{1002}
+	c.From = time.Unix(now-48*3600, 0)
+	c.To = time.Unix(now, 0)
+	b, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := b.Write(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if lines[len(lines)-1] != "# EOF" {
+		t.Errorf("output does not end with # EOF")
+	}
+
+	counts := map[string]int{}
+	values := map[string]map[string]int{}
+	last := map[string]int64{}
+	seen := map[string]bool{}
+	current := ""
+	for _, line := range lines[:len(lines)-1] {
+		if strings.HasPrefix(line, "# TYPE ") {
+			current = strings.Fields(line)[2]
+			if seen[current] {
+				t.Errorf("family %s is not contiguous", current)
+			}
+			seen[current] = true
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name := line[:strings.Index(line, "{")]
+		if name != current {
+			t.Fatalf("sample of %s in family %s", name, current)
+		}
+
+		fields := strings.Fields(line[strings.Index(line, "}")+1:])
+		timestamp, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if timestamp <= last[name] {
+			t.Errorf("%s samples are not in order: %d after %d", name, timestamp, last[name])
+		}
+		last[name] = timestamp
+
+		counts[name]++
+		if values[name] == nil {
+			values[name] = map[string]int{}
+		}
+		values[name][fields[0]]++
+	}
+
+	if counts["pingdom_check_status"] != 48*120 {
+		t.Errorf("got %d pingdom_check_status samples, want %d", counts["pingdom_check_status"], 48*120)
+	}
+	if values["pingdom_check_status"]["1"] != 120 {
+		t.Errorf("got %d unconfirmed_down samples, want 120", values["pingdom_check_status"]["1"])
+	}
+	if counts["pingdom_check_performance_downtime_seconds"] != 24 {
+		t.Errorf("got %d hourly summaries, want 24", counts["pingdom_check_performance_downtime_seconds"])
+	}
+	if values["pingdom_check_performance_downtime_seconds"]["3600"] != 4 {
+		t.Errorf("got %d hours down, want 4", values["pingdom_check_performance_downtime_seconds"]["3600"])
+	}
+	if !strings.Contains(buf.String(), `pingdom_check_response_time{id="1002",name="API",hostname="api.example.com",resolution="1",paused="false",tags="production"} 100 `) {
+		t.Errorf("check labels differ from the exporter")
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+	"github.com/giantswarm/prometheus-pingdom-exporter/manifest"
+)
+
+var (
+	checksApplyCmd = &cobra.Command{
+		Use:   "apply -f checks.yaml [username] [password] [api-key]",
+		Short: "Create, update and delete checks to match a file of definitions",
+		Long: `Create, update and delete checks to match a file of definitions, e.g.
+
+  checks:
+  - name: Website
+    type: http
+    hostname: www.example.com
+    resolution: 1
+    tags: [frontend]
+    contact_ids: [11111]
+    notify_after_failures: 2
+    http:
+      url: /health
+      encryption: true
+      should_contain: OK
+  - name: Gateway
+    type: ping
+    hostname: gw.example.com
+
+Checks are matched by name. The checks created or updated are tagged with
+--managed-tag. A check which is not tagged yet but has the name of a declared
+check is adopted: it is updated and tagged. With --prune, the tagged checks
+which are not declared anymore are deleted. Checks without the tag are never
+deleted.
+
+The plan is printed before it is applied. With --dry-run, nothing is changed.`,
+		Run: checksApplyRun,
+	}
+
+	checksApplyFile       string
+	checksApplyDryRun     bool
+	checksApplyPrune      bool
+	checksApplyManagedTag string
+)
+
+func init() {
+	checksCmd.AddCommand(checksApplyCmd)
+
+	checksApplyCmd.Flags().StringVarP(&checksApplyFile, "file", "f", "", "file of check definitions")
+	checksApplyCmd.Flags().BoolVar(&checksApplyDryRun, "dry-run", false, "print the plan without applying it")
+	checksApplyCmd.Flags().BoolVar(&checksApplyPrune, "prune", false, "delete the managed checks which are not declared")
+	checksApplyCmd.Flags().StringVar(&checksApplyManagedTag, "managed-tag", manifest.DefaultManagedTag, "tag identifying the checks managed by apply")
+}
+
+func checksApplyRun(cmd *cobra.Command, args []string) {
+	if checksApplyFile == "" {
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	f, err := manifest.LoadFile(checksApplyFile)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to load check definitions", "err", err)
+		os.Exit(1)
+	}
+
+	client, api, err := newClient(args)
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	c := manifest.DefaultConfig()
+	c.API = api
+	c.Logger = log.With(logger, "account", exporter.AccountName(client))
+	c.ManagedTag = checksApplyManagedTag
+	c.Prune = checksApplyPrune
+
+	r, err := manifest.New(c)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to create reconciler", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	plan, err := r.Plan(ctx, f)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to plan changes", "err", err)
+		os.Exit(1)
+	}
+	if err := plan.Write(os.Stdout); err != nil {
+		level.Error(logger).Log("msg", "failed to write plan", "err", err)
+		os.Exit(1)
+	}
+
+	if checksApplyDryRun {
+		return
+	}
+	if err := r.Apply(ctx, plan); err != nil {
+		level.Error(logger).Log("msg", "failed to apply changes", "err", err)
+		os.Exit(1)
+	}
+}
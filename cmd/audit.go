@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/audit"
+)
+
+// auditFindingsExitCode is the exit status of audit when checks violate the
+// policy, to tell them from errors in CI.
+const auditFindingsExitCode = 2
+
+var (
+	auditCmd = &cobra.Command{
+		Use:   "audit [username] [password] [api-key]",
+		Short: "Report checks violating the configuration policy",
+		Long: `Read every check and the notification contacts, and report the checks
+violating the policy, by rule:
+
+  no-contacts     no active notification contacts or integrations
+  paused          paused for more than --max-paused-days
+  resolution      tested less often than every --max-resolution minutes
+  should-contain  HTTP check not verifying the response body
+  duplicate       same hostname as another check
+  required-tags   missing a --required-tag, e.g. production, or team- for any
+                  tag starting with team-
+
+The command exits with status 2 if checks violate the policy, and 1 on errors.
+Rules can be skipped with --ignore.`,
+		Run: auditRun,
+	}
+
+	auditMaxPausedDays int
+	auditMaxResolution int
+	auditRequiredTags  []string
+	auditIgnore        []string
+	auditFormat        string
+)
+
+func init() {
+	RootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().IntVar(&auditMaxPausedDays, "max-paused-days", 30, "days checks may be paused, 0 to allow any")
+	auditCmd.Flags().IntVar(&auditMaxResolution, "max-resolution", 5, "highest number of minutes between tests, 0 to allow any")
+	auditCmd.Flags().StringSliceVar(&auditRequiredTags, "required-tag", nil, "tag every check must have, or prefix ending with - (can be repeated)")
+	auditCmd.Flags().StringSliceVar(&auditIgnore, "ignore", nil, "rule to skip (can be repeated)")
+	auditCmd.Flags().StringVar(&auditFormat, "format", "table", "output format, table or json")
+}
+
+func auditRun(cmd *cobra.Command, args []string) {
+	_, api, err := newClient(args)
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	for _, rule := range auditIgnore {
+		if !containsString(audit.Rules, rule) {
+			level.Error(logger).Log("msg", "invalid rule, expected "+strings.Join(audit.Rules, ", "), "rule", rule)
+			os.Exit(1)
+		}
+	}
+	if auditFormat != "table" && auditFormat != "json" {
+		level.Error(logger).Log("msg", "invalid format, expected table or json", "format", auditFormat)
+		os.Exit(1)
+	}
+
+	policy := audit.DefaultPolicy()
+	policy.MaxPaused = time.Duration(auditMaxPausedDays) * 24 * time.Hour
+	policy.MaxResolution = auditMaxResolution
+	policy.RequiredTags = auditRequiredTags
+	policy.Ignore = auditIgnore
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	checks, contacts, err := audit.Load(ctx, api)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to load checks and contacts", "err", err)
+		os.Exit(1)
+	}
+
+	findings := audit.Audit(policy, checks, contacts, time.Now())
+	if err := writeFindings(os.Stdout, findings, auditFormat); err != nil {
+		level.Error(logger).Log("msg", "failed to write findings", "err", err)
+		os.Exit(1)
+	}
+
+	if len(findings) > 0 {
+		os.Exit(auditFindingsExitCode)
+	}
+}
+
+func writeFindings(w io.Writer, findings []audit.Finding, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "RULE\tID\tNAME\tHOSTNAME\tMESSAGE")
+		for _, f := range findings {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", f.Rule, f.CheckID, f.CheckName, f.Hostname, f.Message)
+		}
+		return tw.Flush()
+	case "json":
+		if findings == nil {
+			findings = []audit.Finding{}
+		}
+		return writeJSON(w, findings)
+	default:
+		return fmt.Errorf("invalid format %q, expected table or json", format)
+	}
+}
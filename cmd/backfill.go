@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/backfill"
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+var (
+	backfillCmd = &cobra.Command{
+		Use:   "backfill [username] [password] [api-key]",
+		Short: "Write the history of the checks as OpenMetrics",
+		Long: `Write the test results and hourly performance summaries of the checks
+between --from and --to as OpenMetrics, with their original timestamps.
+
+The output can be turned into Prometheus TSDB blocks with promtool, e.g.
+
+  prometheus-pingdom-exporter backfill --from 2017-01-01 --output-file pingdom.om user password key
+  promtool tsdb create-blocks-from openmetrics pingdom.om ./data`,
+		Run: backfillRun,
+	}
+
+	backfillFrom       string
+	backfillTo         string
+	backfillCheckIDs   []int
+	backfillOutputFile string
+)
+
+func init() {
+	RootCmd.AddCommand(backfillCmd)
+
+	backfillCmd.Flags().StringVar(&backfillFrom, "from", "", "start of the history, as a date (2006-01-02) or RFC 3339 time")
+	backfillCmd.Flags().StringVar(&backfillTo, "to", "", "end of the history, as a date (2006-01-02) or RFC 3339 time (default now)")
+	backfillCmd.Flags().IntSliceVar(&backfillCheckIDs, "check", nil, "ID of a check to backfill (can be repeated, default all checks)")
+	backfillCmd.Flags().StringVarP(&backfillOutputFile, "output-file", "o", "", "file to write the OpenMetrics to, instead of stdout")
+	addStatusValueFlag(backfillCmd)
+}
+
+func backfillRun(cmd *cobra.Command, args []string) {
+	client, api, err := newClient(args)
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	b, err := newBackfiller(client, api)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to create backfiller", "err", err)
+		os.Exit(1)
+	}
+
+	var out io.WriteCloser = os.Stdout
+	if backfillOutputFile != "" {
+		out, err = os.Create(backfillOutputFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create output file", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := b.Write(context.Background(), out); err != nil {
+		level.Error(logger).Log("msg", "failed to backfill", "err", err)
+		os.Exit(1)
+	}
+	if err := out.Close(); err != nil {
+		level.Error(logger).Log("msg", "failed to write output file", "err", err)
+		os.Exit(1)
+	}
+}
+
+// newBackfiller returns a backfiller configured with the backfill flags.
+func newBackfiller(client *pingdom.Client, api *exporter.Client) (*backfill.Backfiller, error) {
+	statusValues, err := exporter.ParseStatusValues(statusValueFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	if backfillFrom == "" {
+		return nil, fmt.Errorf("--from must be set")
+	}
+	from, err := parseTime(backfillFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	to := time.Now()
+	if backfillTo != "" {
+		to, err = parseTime(backfillTo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c := backfill.DefaultConfig()
+	c.API = api
+	c.Logger = log.With(logger, "account", exporter.AccountName(client))
+	c.StatusValues = statusValues
+	c.CheckIDs = backfillCheckIDs
+	c.From = from
+	c.To = to
+
+	return backfill.New(c)
+}
+
+// parseTime parses a date or RFC 3339 time.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected a date (2006-01-02) or RFC 3339 time", s)
+	}
+
+	return t, nil
+}
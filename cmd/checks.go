@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+var (
+	checksCmd = &cobra.Command{
+		Use:   "checks",
+		Short: "Inspect and manage Pingdom checks",
+	}
+
+	checksListCmd = &cobra.Command{
+		Use:   "list [username] [password] [api-key]",
+		Short: "List the checks",
+		Run:   checksListRun,
+	}
+
+	checksGetCmd = &cobra.Command{
+		Use:   "get <check-id> [username] [password] [api-key]",
+		Short: "Show the details of a check",
+		Run:   checksGetRun,
+	}
+
+	checksTags     []string
+	checksStatuses []string
+	checksFormat   string
+)
+
+// checkColumns are the columns of the table and CSV check lists.
+var checkColumns = []string{"id", "name", "type", "hostname", "status", "resolution", "last_test", "response_time", "tags"}
+
+func init() {
+	RootCmd.AddCommand(checksCmd)
+	checksCmd.AddCommand(checksListCmd)
+	checksCmd.AddCommand(checksGetCmd)
+
+	checksListCmd.Flags().StringSliceVar(&checksTags, "tag", nil, "only list checks with the tag (can be repeated, checks with any of the tags are listed)")
+	checksListCmd.Flags().StringSliceVar(&checksStatuses, "status", nil, "only list checks with the status, e.g. down (can be repeated)")
+	checksListCmd.Flags().StringVar(&checksFormat, "format", "table", "output format, table, json or csv")
+	checksGetCmd.Flags().StringVar(&checksFormat, "format", "table", "output format, table or json")
+}
+
+func checksListRun(cmd *cobra.Command, args []string) {
+	_, api, err := newClient(args)
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	params := map[string]string{
+		"include_tags": "true",
+	}
+	if len(checksTags) > 0 {
+		params["tags"] = strings.Join(checksTags, ",")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	all, err := api.ListChecks(ctx, params)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to get checks", "err", err)
+		os.Exit(1)
+	}
+
+	var checks []pingdom.CheckResponse
+	for _, check := range all {
+		if len(checksStatuses) == 0 || containsString(checksStatuses, check.Status) {
+			checks = append(checks, check)
+		}
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].ID < checks[j].ID })
+
+	if err := writeChecks(os.Stdout, checks, checksFormat); err != nil {
+		level.Error(logger).Log("msg", "failed to write checks", "err", err)
+		os.Exit(1)
+	}
+}
+
+func checksGetRun(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		cmd.Help()
+		os.Exit(1)
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		level.Error(logger).Log("msg", "invalid check ID", "id", args[0])
+		os.Exit(1)
+	}
+
+	_, api, err := newClient(args[1:])
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	check, err := api.ReadCheck(ctx, id)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to get check", "check_id", id, "err", err)
+		os.Exit(1)
+	}
+
+	if err := writeCheck(os.Stdout, check, checksFormat); err != nil {
+		level.Error(logger).Log("msg", "failed to write check", "err", err)
+		os.Exit(1)
+	}
+}
+
+// checkJSON is a check in JSON output. The type is written like the Pingdom
+// API does: as a name in lists, and as an object holding the settings of the
+// type in details.
+type checkJSON struct {
+	pingdom.CheckResponse
+	Type interface{} `json:"type"`
+}
+
+func writeChecks(w io.Writer, checks []pingdom.CheckResponse, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(checkColumns, "\t")))
+		for _, check := range checks {
+			fmt.Fprintln(tw, strings.Join(checkRow(check), "\t"))
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(checkColumns)
+		for _, check := range checks {
+			cw.Write(checkRow(check))
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		out := []checkJSON{}
+		for _, check := range checks {
+			out = append(out, checkJSON{CheckResponse: check, Type: check.Type.Name})
+		}
+		return writeJSON(w, out)
+	default:
+		return fmt.Errorf("invalid format %q, expected table, json or csv", format)
+	}
+}
+
+// checkRow returns the values of checkColumns for the check.
+func checkRow(check pingdom.CheckResponse) []string {
+	lastTest := ""
+	if check.LastTestTime != 0 {
+		lastTest = time.Unix(check.LastTestTime, 0).UTC().Format(time.RFC3339)
+	}
+
+	var tags []string
+	for _, tag := range check.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	return []string{
+		strconv.Itoa(check.ID),
+		check.Name,
+		check.Type.Name,
+		check.Hostname,
+		check.Status,
+		strconv.Itoa(check.Resolution),
+		lastTest,
+		strconv.FormatInt(check.LastResponseTime, 10),
+		strings.Join(tags, ","),
+	}
+}
+
+func writeCheck(w io.Writer, check *pingdom.CheckResponse, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		values := checkRow(*check)
+		for i, column := range checkColumns {
+			fmt.Fprintf(tw, "%s:\t%s\n", column, values[i])
+		}
+		fmt.Fprintf(tw, "paused:\t%t\n", exporter.IsPaused(*check))
+		fmt.Fprintf(tw, "created:\t%s\n", time.Unix(check.Created, 0).UTC().Format(time.RFC3339))
+		fmt.Fprintf(tw, "contact_ids:\t%s\n", joinInts(check.ContactIds))
+		fmt.Fprintf(tw, "integration_ids:\t%s\n", joinInts(check.IntegrationIds))
+		fmt.Fprintf(tw, "notify_after_failures:\t%d\n", check.SendNotificationWhenDown)
+		fmt.Fprintf(tw, "notify_again_every:\t%d\n", check.NotifyAgainEvery)
+		fmt.Fprintf(tw, "notify_when_back_up:\t%t\n", check.NotifyWhenBackup)
+
+		if h := check.Type.HTTP; h != nil {
+			fmt.Fprintf(tw, "http.url:\t%s\n", h.Url)
+			fmt.Fprintf(tw, "http.encryption:\t%t\n", h.Encryption)
+			fmt.Fprintf(tw, "http.port:\t%d\n", h.Port)
+			fmt.Fprintf(tw, "http.username:\t%s\n", h.Username)
+			fmt.Fprintf(tw, "http.should_contain:\t%s\n", h.ShouldContain)
+			fmt.Fprintf(tw, "http.should_not_contain:\t%s\n", h.ShouldNotContain)
+			fmt.Fprintf(tw, "http.post_data:\t%s\n", h.PostData)
+
+			var headers []string
+			for name := range h.RequestHeaders {
+				headers = append(headers, name)
+			}
+			sort.Strings(headers)
+			for _, name := range headers {
+				fmt.Fprintf(tw, "http.request_headers.%s:\t%s\n", name, h.RequestHeaders[name])
+			}
+		}
+
+		return tw.Flush()
+	case "json":
+		details := map[string]interface{}{}
+		if check.Type.HTTP != nil {
+			// The password is left out like in the table.
+			h := *check.Type.HTTP
+			h.Password = ""
+			details[check.Type.Name] = h
+		} else {
+			details[check.Type.Name] = struct{}{}
+		}
+		return writeJSON(w, checkJSON{CheckResponse: *check, Type: details})
+	default:
+		return fmt.Errorf("invalid format %q, expected table or json", format)
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", content)
+	return err
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+
+	return strings.Join(s, ",")
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
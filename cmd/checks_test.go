@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+)
+
+func TestWriteChecks(t *testing.T) {
+	checks := fakepingdom.DefaultFixtures().Checks[:2]
+
+	var buf bytes.Buffer
+	if err := writeChecks(&buf, checks, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,name,type,hostname,status,resolution,last_test,response_time,tags\n" +
+		"1001,Website,http,www.example.com,up,1,2017-07-14T02:39:00Z,123,\"production,team-web\"\n" +
+		"1002,API,http,api.example.com,down,1,2017-07-14T02:39:00Z,0,production\n"
+	if buf.String() != want {
+		t.Errorf("got CSV\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := writeChecks(&buf, checks, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0]["type"] != "http" {
+		t.Errorf("unexpected JSON %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeChecks(&buf, []pingdom.CheckResponse{}, "table"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "ID  ") {
+		t.Errorf("table has no header: %q", buf.String())
+	}
+
+	if err := writeChecks(&buf, checks, "yaml"); err == nil {
+		t.Errorf("invalid format was accepted")
+	}
+}
+
+func TestWriteCheck(t *testing.T) {
+	check := fakepingdom.DefaultFixtures().Checks[2]
+	check.Type.HTTP.Username = "monitor"
+	check.Type.HTTP.Password = "s3cr3t"
+	check.Type.HTTP.RequestHeaders = map[string]string{"User-Agent": "pingdom", "Accept": "text/plain"}
+
+	var buf bytes.Buffer
+	if err := writeCheck(&buf, &check, "table"); err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]string{}
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		fields[parts[0]] = strings.TrimSpace(parts[1])
+		names = append(names, parts[0])
+	}
+	want := map[string]string{
+		"id":                              "1003",
+		"status":                          "paused",
+		"paused":                          "true",
+		"contact_ids":                     "2001",
+		"http.url":                        "/",
+		"http.encryption":                 "true",
+		"http.port":                       "443",
+		"http.username":                   "monitor",
+		"http.should_contain":             "OK",
+		"http.request_headers.Accept":     "text/plain",
+		"http.request_headers.User-Agent": "pingdom",
+	}
+	for name, value := range want {
+		if fields[name] != value {
+			t.Errorf("%s = %q, want %q", name, fields[name], value)
+		}
+	}
+	if names[len(names)-2] != "http.request_headers.Accept" {
+		t.Errorf("request headers are not sorted: %v", names)
+	}
+
+	buf.Reset()
+	if err := writeCheck(&buf, &check, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		ID   int `json:"id"`
+		Type struct {
+			HTTP struct {
+				URL           string `json:"url"`
+				ShouldContain string `json:"shouldcontain"`
+			} `json:"http"`
+		} `json:"type"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != 1003 || decoded.Type.HTTP.URL != "/" || decoded.Type.HTTP.ShouldContain != "OK" {
+		t.Errorf("unexpected JSON %s", buf.String())
+	}
+	if strings.Contains(buf.String(), "s3cr3t") {
+		t.Errorf("JSON contains the password %s", buf.String())
+	}
+	if check.Type.HTTP.Password != "s3cr3t" {
+		t.Errorf("the check password was cleared")
+	}
+
+	if err := writeCheck(&buf, &check, "csv"); err == nil {
+		t.Errorf("invalid format was accepted")
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+var (
+	pingdomURL     string
+	pingdomTimeout time.Duration
+	recordDir      string
+	replayDir      string
+)
+
+// errUsage is returned by newClient when the arguments do not hold
+// credentials, so that the command can print its usage.
+var errUsage = errors.New("expected [username] [password] [api-key] [account-email] arguments")
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&pingdomURL, "pingdom-url", "https://api.pingdom.com", "base URL of the Pingdom API")
+	RootCmd.PersistentFlags().DurationVar(&pingdomTimeout, "pingdom-timeout", time.Minute, "time limit of each Pingdom API request")
+	RootCmd.PersistentFlags().StringVar(&recordDir, "record-dir", "", "directory to record the Pingdom API requests and responses to, with credentials redacted")
+	RootCmd.PersistentFlags().StringVar(&replayDir, "replay-dir", "", "directory to replay recorded Pingdom API responses from, instead of calling the API")
+}
+
+// newClient returns the Pingdom client for the credentials in args, which
+// are a username, password, API key and, for multi-user accounts, the
+// account email. The client honours the --pingdom-url, --pingdom-timeout,
+// --record-dir and --replay-dir flags.
+func newClient(args []string) (*pingdom.Client, *exporter.Client, error) {
+	var client *pingdom.Client
+
+	if len(args) == 3 {
+		client = pingdom.NewClient(
+			args[0],
+			args[1],
+			args[2],
+		)
+	} else if len(args) == 4 {
+		client = pingdom.NewMultiUserClient(
+			args[0],
+			args[1],
+			args[2],
+			args[3],
+		)
+	} else if len(args) == 0 && replayDir != "" {
+		// Recordings carry no credentials, so none are needed to replay them.
+		client = pingdom.NewClient("", "", "")
+	} else {
+		return nil, nil, errUsage
+	}
+
+	baseURL, err := url.Parse(pingdomURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Pingdom URL %q: %v", pingdomURL, err)
+	}
+	client.BaseURL = baseURL
+
+	if recordDir != "" && replayDir != "" {
+		return nil, nil, fmt.Errorf("--record-dir and --replay-dir cannot be used together")
+	}
+	if pingdomTimeout <= 0 {
+		return nil, nil, fmt.Errorf("--pingdom-timeout must be positive")
+	}
+	// A request which never completes would stall the poller otherwise.
+	httpClient := &http.Client{Timeout: pingdomTimeout}
+	if recordDir != "" {
+		transport, err := newRecordingTransport(recordDir, http.DefaultTransport)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to set up recording in %s: %v", recordDir, err)
+		}
+		httpClient.Transport = transport
+	}
+	if replayDir != "" {
+		transport, err := newReplayTransport(replayDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load recordings from %s: %v", replayDir, err)
+		}
+		httpClient.Transport = transport
+	}
+
+	c := exporter.DefaultClientConfig()
+	c.Pingdom = client
+	c.HTTPClient = httpClient
+	c.Logger = logger
+
+	api, err := exporter.NewClient(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, api, nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewClientTimeout(t *testing.T) {
+	stop := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stop
+	}))
+	defer server.Close()
+	defer close(stop)
+
+	defer func(url string, timeout time.Duration) {
+		pingdomURL, pingdomTimeout = url, timeout
+	}(pingdomURL, pingdomTimeout)
+	pingdomURL, pingdomTimeout = server.URL, 100*time.Millisecond
+
+	_, api, err := newClient([]string{"user", "password", "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := api.ListChecks(context.Background(), nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("expected an error for a Pingdom API not answering")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not time out")
+	}
+
+	pingdomTimeout = 0
+	if _, _, err := newClient([]string{"user", "password", "key"}); err == nil {
+		t.Errorf("expected an error for a timeout of 0")
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	collectCmd = &cobra.Command{
+		Use:   "collect [username] [password] [api-key]",
+		Short: "Poll Pingdom once and print the metrics",
+		Long: `Poll Pingdom once and print the metrics in the Prometheus text format.
+
+With --output-file the metrics are written atomically to a file, e.g. for the
+node_exporter textfile collector:
+
+  prometheus-pingdom-exporter collect --output-file /var/lib/node_exporter/pingdom.prom user password key
+
+The metrics can also be pushed, see the --pushgateway and --remote-write flags.
+
+The command exits with status 1 if the poll or a push failed, after writing
+pingdom_up 0.`,
+		Run: collectRun,
+	}
+
+	collectOutputFile string
+	collectTimeout    time.Duration
+)
+
+func init() {
+	RootCmd.AddCommand(collectCmd)
+
+	collectCmd.Flags().StringVarP(&collectOutputFile, "output-file", "o", "", "file to write the metrics to, instead of stdout")
+	collectCmd.Flags().DurationVar(&collectTimeout, "timeout", 30*time.Second, "time to wait for the Pingdom API")
+	addStatusValueFlag(collectCmd)
+	addPushFlags(collectCmd)
+}
+
+func collectRun(cmd *cobra.Command, args []string) {
+	client, api, err := newClient(args)
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	exp, err := newExporter(client, api)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to create exporter", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	pushers, err := newPushers(exp)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to configure pushing", "err", err)
+		os.Exit(1)
+	}
+
+	pollErr := exp.Poll(ctx)
+	pushErr := pushAll(context.Background(), pushers)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(exp)
+
+	var buf bytes.Buffer
+	if err := writeMetrics(&buf, registry); err != nil {
+		level.Error(logger).Log("msg", "failed to gather metrics", "err", err)
+		os.Exit(1)
+	}
+
+	if collectOutputFile == "" {
+		_, err = buf.WriteTo(os.Stdout)
+	} else {
+		err = writeFileAtomically(collectOutputFile, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to write metrics", "err", err)
+		os.Exit(1)
+	}
+
+	if pollErr != nil || pushErr != nil {
+		os.Exit(1)
+	}
+}
+
+// writeMetrics writes the metrics gathered from g in the Prometheus text
+// format.
+func writeMetrics(w io.Writer, g prometheus.Gatherer) error {
+	families, err := g.Gather()
+	if err != nil {
+		return err
+	}
+
+	for _, family := range families {
+		if _, err := expfmt.MetricFamilyToText(w, family); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFileAtomically writes the content to a temporary file next to path
+// with the mode, and renames it to path, so that readers never see a partial
+// file.
+func writeFileAtomically(path string, content []byte, mode os.FileMode) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	f, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
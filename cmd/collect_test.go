@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: "pingdom_up", Help: "Whether the last poll succeeded."})
+	up.Set(1)
+	registry.MustRegister(up)
+
+	var buf bytes.Buffer
+	if err := writeMetrics(&buf, registry); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# HELP pingdom_up Whether the last poll succeeded.\n# TYPE pingdom_up gauge\npingdom_up 1\n"
+	if buf.String() != want {
+		t.Errorf("unexpected metrics\nwant:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestWriteFileAtomically(t *testing.T) {
+	dir, err := ioutil.TempDir("", "collect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "pingdom.prom")
+	for _, content := range []string{"first\n", "second\n"} {
+		if err := writeFileAtomically(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("file contains %q, want %q", got, content)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("file mode is %v, want 0644", info.Mode().Perm())
+	}
+
+	// Files with secrets are only readable by their owner.
+	secret := filepath.Join(dir, "checks.yaml")
+	if err := writeFileAtomically(secret, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	info, err = os.Stat(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("file mode is %v, want 0600", info.Mode().Perm())
+	}
+	os.Remove(secret)
+
+	// No temporary files are left behind.
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected only the written file in the directory, got %d files", len(files))
+	}
+
+	if err := writeFileAtomically(filepath.Join(dir, "missing", "pingdom.prom"), []byte("x"), 0644); err == nil {
+		t.Errorf("expected an error for a missing directory")
+	}
+}
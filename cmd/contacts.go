@@ -0,0 +1,476 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+var (
+	contactsCmd = &cobra.Command{
+		Use:   "contacts",
+		Short: "Inspect and manage Pingdom notification contacts",
+	}
+
+	contactsListCmd = &cobra.Command{
+		Use:   "list [username] [password] [api-key]",
+		Short: "List the notification contacts",
+		Run:   contactsListRun,
+	}
+
+	contactsCreateCmd = &cobra.Command{
+		Use:   "create [username] [password] [api-key]",
+		Short: "Create a notification contact",
+		Run:   contactsCreateRun,
+	}
+
+	contactsUpdateCmd = &cobra.Command{
+		Use:   "update <contact> [username] [password] [api-key]",
+		Short: "Change the settings of a notification contact",
+		Long: `Change the settings of a notification contact, given by ID or name. Only the
+settings of the flags given are changed.`,
+		Run: contactsUpdateRun,
+	}
+
+	contactsDeleteCmd = &cobra.Command{
+		Use:   "delete <contact> [username] [password] [api-key]",
+		Short: "Delete a notification contact",
+		Run:   contactsDeleteRun,
+	}
+
+	contactsPauseCmd = &cobra.Command{
+		Use:   "pause <contact> [username] [password] [api-key]",
+		Short: "Stop notifying a contact",
+		Run: func(cmd *cobra.Command, args []string) {
+			contactsSetPaused(cmd, args, true)
+		},
+	}
+
+	contactsResumeCmd = &cobra.Command{
+		Use:   "resume <contact> [username] [password] [api-key]",
+		Short: "Notify a paused contact again",
+		Run: func(cmd *cobra.Command, args []string) {
+			contactsSetPaused(cmd, args, false)
+		},
+	}
+
+	contactsRotateCmd = &cobra.Command{
+		Use:   "rotate [username] [password] [api-key]",
+		Short: "Replace the contacts of the checks with a tag",
+		Long: `Replace the --from contacts of the checks with any of the --tag tags by the
+--to contacts, e.g. for on-call rotations. Other contacts of the checks are
+kept. Contacts are given by ID or name. Give every contact of the rotation as
+--from, so that the checks notify the --to contacts only, whoever was on call
+before:
+
+  prometheus-pingdom-exporter contacts rotate --tag on-call --from Alice,Bob,Carol --to Bob user password key`,
+		Run: contactsRotateRun,
+	}
+
+	contactsFormat       string
+	contactName          string
+	contactEmail         string
+	contactCellphone     string
+	contactCountryISO    string
+	contactSMSProvider   string
+	contactTwitterUser   string
+	contactDirectTwitter bool
+	contactPaused        bool
+	contactsRotateTags   []string
+	contactsRotateFrom   []string
+	contactsRotateTo     []string
+	contactsRotateDryRun bool
+)
+
+// contactColumns are the columns of the contact table.
+var contactColumns = []string{"id", "name", "email", "cellphone", "country_iso", "paused", "type"}
+
+func init() {
+	RootCmd.AddCommand(contactsCmd)
+	for _, cmd := range []*cobra.Command{contactsListCmd, contactsCreateCmd, contactsUpdateCmd, contactsDeleteCmd, contactsPauseCmd, contactsResumeCmd, contactsRotateCmd} {
+		contactsCmd.AddCommand(cmd)
+	}
+
+	for _, cmd := range []*cobra.Command{contactsListCmd, contactsCreateCmd} {
+		cmd.Flags().StringVar(&contactsFormat, "format", "table", "output format, table or json")
+	}
+	for _, cmd := range []*cobra.Command{contactsCreateCmd, contactsUpdateCmd} {
+		cmd.Flags().StringVar(&contactName, "name", "", "name of the contact")
+		cmd.Flags().StringVar(&contactEmail, "email", "", "email address to notify")
+		cmd.Flags().StringVar(&contactCellphone, "cellphone", "", "cellphone number to notify by SMS, without the country code")
+		cmd.Flags().StringVar(&contactCountryISO, "country-iso", "", "country of the cellphone number, e.g. DE")
+		cmd.Flags().StringVar(&contactSMSProvider, "sms-provider", "", "SMS provider, e.g. nexmo, bulksms, esendex or cellsynt")
+		cmd.Flags().StringVar(&contactTwitterUser, "twitter-user", "", "Twitter user to notify")
+		cmd.Flags().BoolVar(&contactDirectTwitter, "direct-twitter", false, "notify the Twitter user by direct message")
+		cmd.Flags().BoolVar(&contactPaused, "paused", false, "do not notify the contact")
+	}
+	contactsRotateCmd.Flags().StringSliceVar(&contactsRotateTags, "tag", nil, "rotate the contacts of the checks with the tag (can be repeated, checks with any of the tags are rotated)")
+	contactsRotateCmd.Flags().StringSliceVar(&contactsRotateFrom, "from", nil, "contact to remove from the checks (can be repeated)")
+	contactsRotateCmd.Flags().StringSliceVar(&contactsRotateTo, "to", nil, "contact to add to the checks (can be repeated)")
+	contactsRotateCmd.Flags().BoolVar(&contactsRotateDryRun, "dry-run", false, "show the changes without applying them")
+}
+
+func contactsListRun(cmd *cobra.Command, args []string) {
+	_, api, err := newClient(args)
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	if err := validateContactsFormat(contactsFormat); err != nil {
+		level.Error(logger).Log("msg", "invalid format", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	contacts, err := api.ListContacts(ctx)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to get contacts", "err", err)
+		os.Exit(1)
+	}
+	sort.Slice(contacts, func(i, j int) bool { return contacts[i].ID < contacts[j].ID })
+
+	if err := writeContacts(os.Stdout, contacts, contactsFormat); err != nil {
+		level.Error(logger).Log("msg", "failed to write contacts", "err", err)
+		os.Exit(1)
+	}
+}
+
+func contactsCreateRun(cmd *cobra.Command, args []string) {
+	_, api, err := newClient(args)
+	if err == errUsage || contactName == "" {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	contact := pingdom.Contact{}
+	setContactFlags(cmd, &contact)
+
+	if err := createContact(ctx, api, contact, os.Stdout, contactsFormat); err != nil {
+		level.Error(logger).Log("msg", "failed to create contact", "err", err)
+		os.Exit(1)
+	}
+}
+
+// createContact creates the contact, and writes it in the format, which is
+// validated before the contact is created.
+func createContact(ctx context.Context, api *exporter.Client, contact pingdom.Contact, w io.Writer, format string) error {
+	if err := validateContactsFormat(format); err != nil {
+		return err
+	}
+
+	created, err := api.CreateContact(ctx, contact)
+	if err != nil {
+		return err
+	}
+	level.Info(logger).Log("msg", "created contact", "contact_id", created.ID, "name", contact.Name)
+
+	// The response only has the ID and name of the contact.
+	contacts, err := api.ListContacts(ctx)
+	if err == nil {
+		for _, c := range contacts {
+			if c.ID == created.ID {
+				created = &c
+				break
+			}
+		}
+	}
+
+	return writeContacts(w, []pingdom.ContactResponse{*created}, format)
+}
+
+func contactsUpdateRun(cmd *cobra.Command, args []string) {
+	api, contact := contactCommandSetup(cmd, args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c := contactFromResponse(contact)
+	setContactFlags(cmd, &c)
+
+	if err := api.UpdateContact(ctx, contact.ID, c); err != nil {
+		level.Error(logger).Log("msg", "failed to update contact", "contact_id", contact.ID, "err", err)
+		os.Exit(1)
+	}
+	level.Info(logger).Log("msg", "updated contact", "contact_id", contact.ID, "name", c.Name)
+}
+
+func contactsDeleteRun(cmd *cobra.Command, args []string) {
+	api, contact := contactCommandSetup(cmd, args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := api.DeleteContact(ctx, contact.ID); err != nil {
+		level.Error(logger).Log("msg", "failed to delete contact", "contact_id", contact.ID, "err", err)
+		os.Exit(1)
+	}
+	level.Info(logger).Log("msg", "deleted contact", "contact_id", contact.ID, "name", contact.Name)
+}
+
+func contactsSetPaused(cmd *cobra.Command, args []string, paused bool) {
+	api, contact := contactCommandSetup(cmd, args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c := contactFromResponse(contact)
+	c.Paused = paused
+
+	if err := api.UpdateContact(ctx, contact.ID, c); err != nil {
+		level.Error(logger).Log("msg", "failed to update contact", "contact_id", contact.ID, "err", err)
+		os.Exit(1)
+	}
+	level.Info(logger).Log("msg", "updated contact", "contact_id", contact.ID, "name", contact.Name, "paused", paused)
+}
+
+func contactsRotateRun(cmd *cobra.Command, args []string) {
+	_, api, err := newClient(args)
+	if err == errUsage || len(contactsRotateTags) == 0 || len(contactsRotateTo) == 0 {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	contacts, err := api.ListContacts(ctx)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to get contacts", "err", err)
+		os.Exit(1)
+	}
+	resolve := func(refs []string) []int {
+		var ids []int
+		for _, ref := range refs {
+			contact, err := findContact(contacts, ref)
+			if err != nil {
+				level.Error(logger).Log("msg", "invalid contact", "contact", ref, "err", err)
+				os.Exit(1)
+			}
+			ids = append(ids, contact.ID)
+		}
+		return ids
+	}
+	from := resolve(contactsRotateFrom)
+	to := resolve(contactsRotateTo)
+
+	checks, err := api.ListChecks(ctx, map[string]string{"tags": strings.Join(contactsRotateTags, ",")})
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to get checks", "err", err)
+		os.Exit(1)
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].ID < checks[j].ID })
+
+	failed := false
+	for _, check := range checks {
+		details, err := api.ReadCheck(ctx, check.ID)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to read check", "check_id", check.ID, "err", err)
+			failed = true
+			continue
+		}
+
+		ids, changed := rotateContacts(details.ContactIds, from, to)
+		if !changed {
+			continue
+		}
+		if contactsRotateDryRun {
+			level.Info(logger).Log("msg", "would rotate contacts", "check_id", check.ID, "name", check.Name, "from", joinInts(details.ContactIds), "to", joinInts(ids))
+			continue
+		}
+
+		if err := api.SetCheckContacts(ctx, check.ID, ids); err != nil {
+			level.Error(logger).Log("msg", "failed to rotate contacts", "check_id", check.ID, "err", err)
+			failed = true
+			continue
+		}
+		level.Info(logger).Log("msg", "rotated contacts", "check_id", check.ID, "name", check.Name, "from", joinInts(details.ContactIds), "to", joinInts(ids))
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// contactCommandSetup returns the client and the contact given as first
+// argument, for the commands changing a contact.
+func contactCommandSetup(cmd *cobra.Command, args []string) (*exporter.Client, pingdom.ContactResponse) {
+	if len(args) == 0 {
+		cmd.Help()
+		os.Exit(1)
+	}
+
+	_, api, err := newClient(args[1:])
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	contacts, err := api.ListContacts(ctx)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to get contacts", "err", err)
+		os.Exit(1)
+	}
+	contact, err := findContact(contacts, args[0])
+	if err != nil {
+		level.Error(logger).Log("msg", "invalid contact", "contact", args[0], "err", err)
+		os.Exit(1)
+	}
+
+	return api, contact
+}
+
+// findContact returns the contact with the ID or name.
+func findContact(contacts []pingdom.ContactResponse, ref string) (pingdom.ContactResponse, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		for _, contact := range contacts {
+			if contact.ID == id {
+				return contact, nil
+			}
+		}
+	}
+
+	var found []pingdom.ContactResponse
+	for _, contact := range contacts {
+		if contact.Name == ref {
+			found = append(found, contact)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return pingdom.ContactResponse{}, fmt.Errorf("no contact with ID or name %q", ref)
+	case 1:
+		return found[0], nil
+	default:
+		return pingdom.ContactResponse{}, fmt.Errorf("%d contacts are named %q, use the ID", len(found), ref)
+	}
+}
+
+// contactFromResponse returns the settings of a listed contact.
+func contactFromResponse(contact pingdom.ContactResponse) pingdom.Contact {
+	return pingdom.Contact{
+		Name:               contact.Name,
+		Email:              contact.Email,
+		Cellphone:          contact.Cellphone,
+		CountryISO:         contact.CountryISO,
+		DefaultSMSProvider: contact.DefaultSMSProvider,
+		DirectTwitter:      contact.DirectTwitter,
+		TwitterUser:        contact.TwitterUser,
+		Paused:             contact.Paused,
+	}
+}
+
+// setContactFlags sets the settings of the contact given as flags.
+func setContactFlags(cmd *cobra.Command, contact *pingdom.Contact) {
+	flags := cmd.Flags()
+	if flags.Changed("name") {
+		contact.Name = contactName
+	}
+	if flags.Changed("email") {
+		contact.Email = contactEmail
+	}
+	if flags.Changed("cellphone") {
+		contact.Cellphone = contactCellphone
+	}
+	if flags.Changed("country-iso") {
+		contact.CountryISO = contactCountryISO
+	}
+	if flags.Changed("sms-provider") {
+		contact.DefaultSMSProvider = contactSMSProvider
+	}
+	if flags.Changed("twitter-user") {
+		contact.TwitterUser = contactTwitterUser
+	}
+	if flags.Changed("direct-twitter") {
+		contact.DirectTwitter = contactDirectTwitter
+	}
+	if flags.Changed("paused") {
+		contact.Paused = contactPaused
+	}
+}
+
+// rotateContacts returns the contacts of a check with the from contacts
+// replaced by the to contacts, and whether they changed.
+func rotateContacts(current, from, to []int) ([]int, bool) {
+	removed := map[int]bool{}
+	for _, id := range append(append([]int(nil), from...), to...) {
+		removed[id] = true
+	}
+
+	var ids []int
+	for _, id := range current {
+		if !removed[id] {
+			ids = append(ids, id)
+		}
+	}
+	ids = append(ids, to...)
+
+	before := append([]int(nil), current...)
+	after := append([]int(nil), ids...)
+	sort.Ints(before)
+	sort.Ints(after)
+
+	return ids, joinInts(before) != joinInts(after)
+}
+
+// validateContactsFormat returns an error if writeContacts does not support
+// the format, so that commands fail before calling the API.
+func validateContactsFormat(format string) error {
+	switch format {
+	case "table", "json":
+		return nil
+	default:
+		return fmt.Errorf("invalid format %q, expected table or json", format)
+	}
+}
+
+func writeContacts(w io.Writer, contacts []pingdom.ContactResponse, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(contactColumns, "\t")))
+		for _, c := range contacts {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%t\t%s\n", c.ID, c.Name, c.Email, c.Cellphone, c.CountryISO, c.Paused, c.Type)
+		}
+		return tw.Flush()
+	case "json":
+		if contacts == nil {
+			contacts = []pingdom.ContactResponse{}
+		}
+		return writeJSON(w, contacts)
+	default:
+		return fmt.Errorf("invalid format %q, expected table or json", format)
+	}
+}
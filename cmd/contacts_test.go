@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom/fakepingdomtest"
+)
+
+func TestRotateContacts(t *testing.T) {
+	tests := []struct {
+		current, from, to []int
+		want              []int
+		changed           bool
+	}{
+		{current: []int{1, 9}, from: []int{1, 2, 3}, to: []int{2}, want: []int{9, 2}, changed: true},
+		{current: []int{2, 9}, from: []int{1, 2, 3}, to: []int{2}, want: []int{9, 2}, changed: false},
+		{current: nil, from: nil, to: []int{2}, want: []int{2}, changed: true},
+	}
+
+	for _, tt := range tests {
+		got, changed := rotateContacts(tt.current, tt.from, tt.to)
+		if !reflect.DeepEqual(got, tt.want) || changed != tt.changed {
+			t.Errorf("rotateContacts(%v, %v, %v) = %v, %t, want %v, %t", tt.current, tt.from, tt.to, got, changed, tt.want, tt.changed)
+		}
+	}
+}
+
+func TestFindContact(t *testing.T) {
+	contacts := append(fakepingdom.DefaultFixtures().Contacts, pingdom.ContactResponse{ID: 2003, Name: "Backup"})
+
+	if c, err := findContact(contacts, "2001"); err != nil || c.Name != "On-call" {
+		t.Errorf("expected On-call, got %+v, %v", c, err)
+	}
+	if c, err := findContact(contacts, "On-call"); err != nil || c.ID != 2001 {
+		t.Errorf("expected contact 2001, got %+v, %v", c, err)
+	}
+	if _, err := findContact(contacts, "Backup"); err == nil {
+		t.Errorf("expected an error for an ambiguous name")
+	}
+	if _, err := findContact(contacts, "Nobody"); err == nil {
+		t.Errorf("expected an error for an unknown contact")
+	}
+}
+
+func TestWriteContacts(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeContacts(&buf, fakepingdom.DefaultFixtures().Contacts, "table"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID  ") || !strings.Contains(lines[2], "true") {
+		t.Errorf("unexpected table\n%s", buf.String())
+	}
+
+	if err := writeContacts(&buf, nil, "csv"); err == nil {
+		t.Errorf("invalid format was accepted")
+	}
+}
+
+func TestContactsRotate(t *testing.T) {
+	defer func() {
+		contactsRotateTags, contactsRotateFrom, contactsRotateTo = nil, nil, nil
+	}()
+
+	fixtures := fakepingdom.DefaultFixtures()
+	fixtures.Contacts = append(fixtures.Contacts, pingdom.ContactResponse{ID: 2003, Name: "Manager", Type: "user"})
+	fixtures.Checks[1].ContactIds = []int{2003, 2001}
+	fake := fakepingdomtest.NewServer(t, fixtures)
+	defer fake.Close()
+
+	runCommand(t, fake, "contacts", "rotate", "--tag", "production", "--from", "On-call", "--to", "Backup")
+
+	// The production checks notify Backup instead of On-call, and keep
+	// their other contacts. Other checks are left unchanged.
+	want := map[int][]int{
+		1001: {2002},
+		1002: {2003, 2002},
+		1003: {2001},
+		1004: {2001},
+		1005: {2001},
+	}
+	for id, ids := range want {
+		check, err := fake.Client.ReadCheck(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(check.ContactIds, ids) {
+			t.Errorf("check %d has contacts %v, want %v", id, check.ContactIds, ids)
+		}
+	}
+}
+
+func TestContactsChanges(t *testing.T) {
+	fake := fakepingdomtest.NewServer(t, fakepingdom.DefaultFixtures())
+	defer fake.Close()
+
+	contact := func(name string) *pingdom.ContactResponse {
+		contacts, err := fake.Client.ListContacts(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range contacts {
+			if c.Name == name {
+				return &c
+			}
+		}
+		return nil
+	}
+
+	// Only the settings given as flags are changed.
+	runCommand(t, fake, "contacts", "update", "Backup", "--email", "standby@example.com", "--paused=false")
+	c := contact("Backup")
+	if c == nil || c.ID != 2002 || c.Email != "standby@example.com" || c.Paused || c.Cellphone != "4915100000000" || c.CountryISO != "DE" {
+		t.Errorf("unexpected updated contact %+v", c)
+	}
+
+	runCommand(t, fake, "contacts", "create", "--name", "Manager", "--email", "manager@example.com", "--format", "json")
+	c = contact("Manager")
+	if c == nil || c.ID != 2003 || c.Email != "manager@example.com" || c.Paused {
+		t.Errorf("unexpected created contact %+v", c)
+	}
+
+	runCommand(t, fake, "contacts", "delete", "2003")
+	if c := contact("Manager"); c != nil {
+		t.Errorf("expected the contact to be deleted, got %+v", c)
+	}
+	if c := contact("On-call"); c == nil {
+		t.Errorf("expected the other contacts to be kept")
+	}
+}
+
+func TestCreateContactInvalidFormat(t *testing.T) {
+	fake := fakepingdomtest.NewServer(t, fakepingdom.DefaultFixtures())
+	defer fake.Close()
+
+	var buf bytes.Buffer
+	if err := createContact(context.Background(), fake.Client, pingdom.Contact{Name: "Manager"}, &buf, "yaml"); err == nil {
+		t.Errorf("invalid format was accepted")
+	}
+	if requests := fake.Requests(); len(requests) != 0 {
+		t.Errorf("expected no API requests, got %v", requests)
+	}
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/controller"
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+	"github.com/giantswarm/prometheus-pingdom-exporter/kube"
+)
+
+var (
+	controllerCmd = &cobra.Command{
+		Use:   "controller [username] [password] [api-key]",
+		Short: "Manage Pingdom checks from Kubernetes annotations",
+		Long: `Watch Kubernetes Ingresses, and optionally Services and HTTPRoutes, and
+create, update and delete Pingdom HTTP checks for the objects annotated with
+pingdom.giantswarm.io/enabled: "true". Other annotations configure the check:
+
+  pingdom.giantswarm.io/name            check name (default <kind>/<namespace>/<name>)
+  pingdom.giantswarm.io/host            host to check (default the first host of the object)
+  pingdom.giantswarm.io/path            path and query to request (default /)
+  pingdom.giantswarm.io/encryption      true to use HTTPS (default true for Ingress TLS hosts)
+  pingdom.giantswarm.io/resolution      minutes between tests, 1, 5, 15, 30 or 60 (default 5)
+  pingdom.giantswarm.io/contacts        comma separated contact IDs
+  pingdom.giantswarm.io/tags            comma separated tags
+  pingdom.giantswarm.io/should-contain  string the response must contain
+  pingdom.giantswarm.io/paused          true to pause the check
+
+The checks created are tagged with --managed-tag. Checks with the tag whose
+object is gone or not annotated anymore are deleted.
+
+In a pod, the service account is used to access the Kubernetes API. It needs to
+list and watch the resources. Outside of a cluster, use kubectl proxy and
+--kubernetes.url http://localhost:8001.`,
+		Run: controllerRun,
+	}
+
+	controllerKubernetesURL  string
+	controllerTokenFile      string
+	controllerCAFile         string
+	controllerNamespace      string
+	controllerResources      []string
+	controllerManagedTag     string
+	controllerResyncInterval time.Duration
+	controllerMinInterval    time.Duration
+	controllerDryRun         bool
+)
+
+// controllerResourceNames maps the values of --resource to resources.
+var controllerResourceNames = map[string]kube.Resource{
+	"ingress":   kube.Ingresses,
+	"service":   kube.Services,
+	"httproute": kube.HTTPRoutes,
+}
+
+func init() {
+	RootCmd.AddCommand(controllerCmd)
+
+	controllerCmd.Flags().StringVar(&controllerKubernetesURL, "kubernetes.url", "", "URL of the Kubernetes API (default the in-cluster API)")
+	controllerCmd.Flags().StringVar(&controllerTokenFile, "kubernetes.token-file", "", "file of the bearer token for the Kubernetes API")
+	controllerCmd.Flags().StringVar(&controllerCAFile, "kubernetes.ca-file", "", "file of the CA certificates of the Kubernetes API")
+	controllerCmd.Flags().StringVar(&controllerNamespace, "namespace", "", "namespace to watch (default all namespaces)")
+	controllerCmd.Flags().StringSliceVar(&controllerResources, "resource", []string{"ingress"}, "kind of objects to watch, ingress, service or httproute (can be repeated)")
+	controllerCmd.Flags().StringVar(&controllerManagedTag, "managed-tag", controller.DefaultManagedTag, "tag identifying the checks owned by the controller")
+	controllerCmd.Flags().DurationVar(&controllerResyncInterval, "resync-interval", 5*time.Minute, "time between reconciliations when no object changes")
+	controllerCmd.Flags().DurationVar(&controllerMinInterval, "min-reconcile-interval", 30*time.Second, "minimum time between reconciliations, object changes in between are reconciled together")
+	controllerCmd.Flags().BoolVar(&controllerDryRun, "dry-run", false, "log the changes without applying them")
+}
+
+func controllerRun(cmd *cobra.Command, args []string) {
+	client, api, err := newClient(args)
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	kubernetes, err := newKubernetesClient()
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to create Kubernetes client", "err", err)
+		os.Exit(1)
+	}
+
+	c := controller.DefaultConfig()
+	c.Kubernetes = kubernetes
+	c.Pingdom = api
+	c.Logger = log.With(logger, "account", exporter.AccountName(client))
+	c.Namespace = controllerNamespace
+	c.ManagedTag = controllerManagedTag
+	c.ResyncInterval = controllerResyncInterval
+	c.MinReconcileInterval = controllerMinInterval
+	c.DryRun = controllerDryRun
+	c.Resources = nil
+	for _, name := range controllerResources {
+		resource, ok := controllerResourceNames[name]
+		if !ok {
+			level.Error(logger).Log("msg", "invalid resource, expected ingress, service or httproute", "resource", name)
+			os.Exit(1)
+		}
+		c.Resources = append(c.Resources, resource)
+	}
+
+	ctrl, err := controller.New(c)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to create controller", "err", err)
+		os.Exit(1)
+	}
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signalChan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		sig := <-signalChan
+		level.Info(logger).Log("msg", "received signal, shutting down", "signal", sig)
+		cancel()
+	}()
+
+	level.Info(logger).Log("msg", "starting controller", "resources", fmt.Sprint(controllerResources), "namespace", controllerNamespace)
+	ctrl.Run(ctx)
+}
+
+// newKubernetesClient returns a Kubernetes client configured with the
+// kubernetes flags, or the in-cluster configuration.
+func newKubernetesClient() (*kube.Client, error) {
+	c := kube.DefaultConfig()
+	if controllerKubernetesURL == "" {
+		var err error
+		c, err = kube.InClusterConfig()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		c.URL = controllerKubernetesURL
+	}
+	if controllerTokenFile != "" {
+		c.TokenFile = controllerTokenFile
+	}
+	if controllerCAFile != "" {
+		c.CAFile = controllerCAFile
+	}
+	c.Logger = logger
+
+	return kube.NewClient(c)
+}
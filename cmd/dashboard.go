@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/dashboard"
+)
+
+var (
+	dashboardCmd = &cobra.Command{
+		Use:   "dashboard [username] [password] [api-key]",
+		Short: "Generate a Grafana dashboard for the metrics of the exporter",
+		Long: `Generate a Grafana dashboard for the metrics of the exporter, with an
+overview of all checks by status, a row per check with its response time,
+status, uptime and outages, and the health of the exporter.
+
+With --group-by-tag, the checks of the account are listed to add a row per
+Pingdom tag, which requires credentials.`,
+		Run: dashboardRun,
+	}
+
+	dashboardTitle      string
+	dashboardUID        string
+	dashboardGroupByTag bool
+	dashboardOutputFile string
+)
+
+func init() {
+	RootCmd.AddCommand(dashboardCmd)
+
+	dashboardCmd.Flags().StringVar(&dashboardTitle, "title", "Pingdom", "title of the dashboard")
+	dashboardCmd.Flags().StringVar(&dashboardUID, "uid", "pingdom", "UID of the dashboard")
+	dashboardCmd.Flags().BoolVar(&dashboardGroupByTag, "group-by-tag", false, "add a row per Pingdom tag")
+	dashboardCmd.Flags().StringVarP(&dashboardOutputFile, "output-file", "o", "", "file to write the dashboard to, instead of stdout")
+}
+
+func dashboardRun(cmd *cobra.Command, args []string) {
+	c := dashboard.DefaultConfig()
+	c.Title = dashboardTitle
+	c.UID = dashboardUID
+
+	if dashboardGroupByTag {
+		_, api, err := newClient(args)
+		if err == errUsage {
+			cmd.Help()
+			os.Exit(1)
+		} else if err != nil {
+			level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		c.Checks, err = api.ListChecks(ctx, map[string]string{"include_tags": "true"})
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to get checks", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	content, err := json.MarshalIndent(dashboard.Generate(c), "", "  ")
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to generate dashboard", "err", err)
+		os.Exit(1)
+	}
+	content = append(content, '\n')
+
+	if dashboardOutputFile == "" {
+		_, err = os.Stdout.Write(content)
+	} else {
+		err = writeFileAtomically(dashboardOutputFile, content, 0644)
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to write dashboard", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/manifest"
+)
+
+var (
+	checksExportCmd = &cobra.Command{
+		Use:   "export [username] [password] [api-key]",
+		Short: "Write the checks as definitions for checks apply",
+		Long: `Write the checks as YAML definitions, in the format read by checks apply.
+
+The details of every check are read, one request per check. The checks are
+sorted by name, so that exports can be diffed, e.g. for nightly backups:
+
+  prometheus-pingdom-exporter checks export --output-file checks.yaml user password key
+
+The --managed-tag tag is left out of the tags, as checks apply adds it. Checks
+of other types than http and ping are skipped with a warning, as checks apply
+does not support them. HTTP basic authentication passwords are left out unless
+--include-secrets is set, so exports can be committed; add them back before
+applying an export. With --include-secrets, the output file is only readable by
+its owner.`,
+		Run: checksExportRun,
+	}
+
+	contactsExportCmd = &cobra.Command{
+		Use:   "export [username] [password] [api-key]",
+		Short: "Write the notification contacts as YAML definitions",
+		Long: `Write the notification contacts as YAML definitions, in the format read by
+checks apply, sorted by name. checks apply does not apply contacts.`,
+		Run: contactsExportRun,
+	}
+
+	exportOutputFile     string
+	exportManagedTag     string
+	exportIncludeSecrets bool
+)
+
+func init() {
+	checksCmd.AddCommand(checksExportCmd)
+	contactsCmd.AddCommand(contactsExportCmd)
+
+	for _, cmd := range []*cobra.Command{checksExportCmd, contactsExportCmd} {
+		cmd.Flags().StringVarP(&exportOutputFile, "output-file", "o", "", "file to write the definitions to, instead of stdout")
+	}
+	checksExportCmd.Flags().BoolVar(&exportIncludeSecrets, "include-secrets", false, "include the HTTP basic authentication passwords of the checks")
+	checksExportCmd.Flags().StringVar(&exportManagedTag, "managed-tag", manifest.DefaultManagedTag, "tag identifying the checks managed by apply, left out of the tags")
+}
+
+func checksExportRun(cmd *cobra.Command, args []string) {
+	_, api, err := newClient(args)
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	checks, err := manifest.ExportChecks(ctx, api, exportManagedTag, exportIncludeSecrets)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to export checks", "err", err)
+		os.Exit(1)
+	}
+	checks, unsupported := manifest.SupportedChecks(checks)
+	for _, check := range unsupported {
+		level.Warn(logger).Log("msg", "skipping check of unsupported type", "name", check.Name, "type", check.Type)
+	}
+	for _, name := range manifest.DuplicateNames(checks) {
+		level.Warn(logger).Log("msg", "several checks have the same name, rename them before applying the export", "name", name)
+	}
+
+	writeManifest(&manifest.File{Checks: checks})
+}
+
+func contactsExportRun(cmd *cobra.Command, args []string) {
+	_, api, err := newClient(args)
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	contacts, err := manifest.ExportContacts(ctx, api)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to export contacts", "err", err)
+		os.Exit(1)
+	}
+
+	writeManifest(&manifest.File{Contacts: contacts})
+}
+
+// writeManifest writes the definitions to the output file, or stdout.
+func writeManifest(f *manifest.File) {
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		level.Error(logger).Log("msg", "failed to encode definitions", "err", err)
+		os.Exit(1)
+	}
+
+	var err error
+	if exportOutputFile == "" {
+		_, err = buf.WriteTo(os.Stdout)
+	} else {
+		mode := os.FileMode(0644)
+		if exportIncludeSecrets {
+			mode = 0600
+		}
+		err = writeFileAtomically(exportOutputFile, buf.Bytes(), mode)
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to write definitions", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+)
+
+var (
+	fakePingdomCmd = &cobra.Command{
+		Use:   "fake-pingdom",
+		Short: "Serve a fake Pingdom API for local development",
+		Long: `Serve a fake Pingdom API for local development.
+
+Point the exporter at it with --pingdom-url, e.g.
+
+  prometheus-pingdom-exporter fake-pingdom --port 8080 &
+  prometheus-pingdom-exporter server --pingdom-url http://localhost:8080 user password key`,
+		Run: fakePingdomRun,
+	}
+
+	fakePingdomPort     int
+	fakePingdomFixtures string
+	fakePingdomUser     string
+	fakePingdomPassword string
+	fakePingdomAppKey   string
+)
+
+func init() {
+	RootCmd.AddCommand(fakePingdomCmd)
+
+	fakePingdomCmd.Flags().IntVar(&fakePingdomPort, "port", 8080, "port to listen on")
+	fakePingdomCmd.Flags().StringVar(&fakePingdomFixtures, "fixtures", "", "JSON file with the fixtures to serve (default built-in fixtures)")
+	fakePingdomCmd.Flags().StringVar(&fakePingdomUser, "user", "", "username to accept (default any credentials)")
+	fakePingdomCmd.Flags().StringVar(&fakePingdomPassword, "password", "", "password to accept")
+	fakePingdomCmd.Flags().StringVar(&fakePingdomAppKey, "app-key", "", "application key to accept")
+}
+
+func fakePingdomRun(cmd *cobra.Command, args []string) {
+	fixtures := fakepingdom.DefaultFixtures()
+	if fakePingdomFixtures != "" {
+		var err error
+		fixtures, err = fakepingdom.LoadFixtures(fakePingdomFixtures)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to load fixtures", "file", fakePingdomFixtures, "err", err)
+			os.Exit(1)
+		}
+	}
+
+	server := fakepingdom.New(fixtures)
+	server.User = fakePingdomUser
+	server.Password = fakePingdomPassword
+	server.AppKey = fakePingdomAppKey
+
+	level.Info(logger).Log("msg", "listening", "port", fakePingdomPort)
+
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", fakePingdomPort), server); err != nil {
+		level.Error(logger).Log("msg", "failed to serve HTTP", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logLevel  string
+	logFormat string
+
+	// logger is used by all commands. It is replaced with one honouring the
+	// --log.level and --log.format flags before any command runs.
+	logger = newLogger(level.AllowInfo(), "logfmt")
+)
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&logLevel, "log.level", "info", "only log messages with the given severity or above (debug, info, warn, error)")
+	RootCmd.PersistentFlags().StringVar(&logFormat, "log.format", "logfmt", "output format of log messages (logfmt, json)")
+
+	RootCmd.PersistentPreRunE = setupLogger
+}
+
+func setupLogger(cmd *cobra.Command, args []string) error {
+	var allowed level.Option
+	switch logLevel {
+	case "debug":
+		allowed = level.AllowDebug()
+	case "info":
+		allowed = level.AllowInfo()
+	case "warn":
+		allowed = level.AllowWarn()
+	case "error":
+		allowed = level.AllowError()
+	default:
+		return fmt.Errorf("unrecognized log level %q", logLevel)
+	}
+
+	if logFormat != "logfmt" && logFormat != "json" {
+		return fmt.Errorf("unrecognized log format %q", logFormat)
+	}
+
+	logger = newLogger(allowed, logFormat)
+
+	return nil
+}
+
+func newLogger(allowed level.Option, format string) log.Logger {
+	var l log.Logger
+	if format == "json" {
+		l = log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
+	} else {
+		l = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	}
+
+	l = level.NewFilter(l, allowed)
+	l = log.With(l, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
+
+	return l
+}
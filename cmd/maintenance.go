@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/alertmanager"
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+	"github.com/giantswarm/prometheus-pingdom-exporter/maintenance"
+	"github.com/giantswarm/prometheus-pingdom-exporter/rules"
+)
+
+var (
+	maintenanceBridgeCmd = &cobra.Command{
+		Use:   "maintenance-bridge [username] [password] [api-key]",
+		Short: "Sync Alertmanager silences and Pingdom maintenance windows",
+		Long: `Create Pingdom maintenance windows for the Alertmanager silences matching
+checks, and Alertmanager silences for the Pingdom maintenance windows.
+
+Silences match the labels of the PingdomCheckDown alerts of the checks: id,
+check_id, name, hostname, tags (comma separated) and the labels of the
+--alertmanager.config file. Only silences with a matcher on id, check_id, name,
+hostname or tags create maintenance windows. Windows end when their silence
+expires.
+
+Silences created for maintenance windows match the id label of the checks of
+the window, and expire when the window is deleted. Recurring windows are not
+synced.`,
+		Run: maintenanceBridgeRun,
+	}
+
+	maintenanceAlertmanagerURL string
+	maintenanceConfigFile      string
+	maintenanceCreatedBy       string
+	maintenanceSilences        bool
+	maintenanceWindows         bool
+	maintenanceInterval        time.Duration
+	maintenanceDryRun          bool
+)
+
+func init() {
+	RootCmd.AddCommand(maintenanceBridgeCmd)
+
+	maintenanceBridgeCmd.Flags().StringVar(&maintenanceAlertmanagerURL, "alertmanager.url", "", "Alertmanager to sync the silences of")
+	maintenanceBridgeCmd.Flags().StringVar(&maintenanceConfigFile, "alertmanager.config", "", "alerting rules configuration file with the label templates of the alerts, see the rules command")
+	maintenanceBridgeCmd.Flags().StringVar(&maintenanceCreatedBy, "created-by", maintenance.DefaultCreatedBy, "creator of the silences created for maintenance windows")
+	maintenanceBridgeCmd.Flags().BoolVar(&maintenanceSilences, "silences", true, "create maintenance windows for silences")
+	maintenanceBridgeCmd.Flags().BoolVar(&maintenanceWindows, "windows", true, "create silences for maintenance windows")
+	maintenanceBridgeCmd.Flags().DurationVar(&maintenanceInterval, "interval", time.Minute, "time between syncs")
+	maintenanceBridgeCmd.Flags().BoolVar(&maintenanceDryRun, "dry-run", false, "log the changes without applying them")
+}
+
+func maintenanceBridgeRun(cmd *cobra.Command, args []string) {
+	client, api, err := newClient(args)
+	if err == errUsage || maintenanceAlertmanagerURL == "" {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	file := rules.DefaultFile()
+	if maintenanceConfigFile != "" {
+		file, err = rules.LoadFile(maintenanceConfigFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to load alerting rules configuration", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	ac := alertmanager.DefaultClientConfig()
+	ac.URL = maintenanceAlertmanagerURL
+	ac.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	am, err := alertmanager.NewClient(ac)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to create Alertmanager client", "err", err)
+		os.Exit(1)
+	}
+
+	c := maintenance.DefaultConfig()
+	c.Pingdom = api
+	c.Alertmanager = am
+	c.Logger = log.With(logger, "account", exporter.AccountName(client))
+	c.Rules = file
+	c.CreatedBy = maintenanceCreatedBy
+	c.Silences = maintenanceSilences
+	c.Windows = maintenanceWindows
+	c.Interval = maintenanceInterval
+	c.DryRun = maintenanceDryRun
+
+	bridge, err := maintenance.New(c)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to create maintenance bridge", "err", err)
+		os.Exit(1)
+	}
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signalChan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		sig := <-signalChan
+		level.Info(logger).Log("msg", "received signal, shutting down", "signal", sig)
+		cancel()
+	}()
+
+	level.Info(logger).Log("msg", "starting maintenance bridge", "alertmanager", maintenanceAlertmanagerURL)
+	bridge.Run(ctx)
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"os/user"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+var (
+	checksPauseCmd = &cobra.Command{
+		Use:   "pause [username] [password] [api-key]",
+		Short: "Pause the checks with a tag for a while",
+		Long: `Pause the checks with any of the --tag tags for --for, e.g. during a deploy,
+and record who paused them and until when in the tags of the checks:
+
+  paused-until-<YYYYMMDDhhmm, UTC>
+  paused-by-<--by, default the current user>
+
+The exporter exports the pause as pingdom_check_paused_until_timestamp_seconds.
+Checks which were already paused without the tags are left alone.
+
+With --wait, the command waits and resumes the checks when the pause expires.
+Otherwise, resume them with checks resume, e.g. checks resume --expired from a
+cron job.`,
+		Run: checksPauseRun,
+	}
+
+	checksResumeCmd = &cobra.Command{
+		Use:   "resume [username] [password] [api-key]",
+		Short: "Resume checks paused with checks pause",
+		Long: `Resume the checks paused with checks pause which have any of the --tag tags,
+or whose pause expired with --expired, and remove the tags of the pause.
+Checks paused otherwise are only resumed with --all.`,
+		Run: checksResumeRun,
+	}
+
+	pauseTags    []string
+	pauseFor     time.Duration
+	pauseBy      string
+	pauseWait    bool
+	pauseExpired bool
+	pauseAll     bool
+	pauseDryRun  bool
+)
+
+func init() {
+	checksCmd.AddCommand(checksPauseCmd)
+	checksCmd.AddCommand(checksResumeCmd)
+
+	for _, cmd := range []*cobra.Command{checksPauseCmd, checksResumeCmd} {
+		cmd.Flags().StringSliceVar(&pauseTags, "tag", nil, "checks with the tag (can be repeated, checks with any of the tags match)")
+		cmd.Flags().BoolVar(&pauseDryRun, "dry-run", false, "log the changes without applying them")
+	}
+	checksPauseCmd.Flags().DurationVar(&pauseFor, "for", 0, "how long to pause the checks, e.g. 2h")
+	checksPauseCmd.Flags().StringVar(&pauseBy, "by", "", "who pauses the checks (default the current user)")
+	checksPauseCmd.Flags().BoolVar(&pauseWait, "wait", false, "wait and resume the checks when the pause expires")
+	checksResumeCmd.Flags().BoolVar(&pauseExpired, "expired", false, "resume the checks whose pause expired")
+	checksResumeCmd.Flags().BoolVar(&pauseAll, "all", false, "also resume the checks with the tags paused without checks pause")
+}
+
+func checksPauseRun(cmd *cobra.Command, args []string) {
+	_, api, err := newClient(args)
+	if err == errUsage || len(pauseTags) == 0 || pauseFor <= 0 {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	by := pauseBy
+	if by == "" {
+		if u, err := user.Current(); err == nil {
+			by = u.Username
+		}
+	}
+	// The tags are precise to the minute, round up to pause for at least --for.
+	until := time.Now().Add(pauseFor).Add(time.Minute - 1).Truncate(time.Minute)
+	pause := exporter.Pause{Until: until, By: by}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	checks, err := taggedChecks(ctx, api, pauseTags)
+	if err != nil {
+		cancel()
+		level.Error(logger).Log("msg", "failed to get checks", "err", err)
+		os.Exit(1)
+	}
+
+	var paused []pingdom.CheckResponse
+	failed := false
+	for _, check := range checks {
+		if _, ok := exporter.CheckPause(check); !ok && exporter.IsPaused(check) {
+			level.Info(logger).Log("msg", "check already paused, leaving it alone", "check_id", check.ID, "name", check.Name)
+			continue
+		}
+
+		tags := append(exporter.WithoutPauseTags(check), pause.Tags()...)
+		if pauseDryRun {
+			level.Info(logger).Log("msg", "would pause check", "check_id", check.ID, "name", check.Name, "until", pause.Until.Format(time.RFC3339))
+			continue
+		}
+		if err := api.SetCheckPaused(ctx, check.ID, true, tags); err != nil {
+			level.Error(logger).Log("msg", "failed to pause check", "check_id", check.ID, "err", err)
+			failed = true
+			continue
+		}
+		level.Info(logger).Log("msg", "paused check", "check_id", check.ID, "name", check.Name, "until", pause.Until.Format(time.RFC3339))
+		paused = append(paused, check)
+	}
+	cancel()
+
+	if pauseWait && !pauseDryRun && len(paused) > 0 {
+		if !waitUntil(pause.Until) {
+			level.Warn(logger).Log("msg", "interrupted, the checks stay paused until resumed with checks resume")
+			os.Exit(1)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		if !resumeChecks(ctx, api, paused, func(check pingdom.CheckResponse) bool {
+			// Checks paused again meanwhile are left paused.
+			p, ok := exporter.CheckPause(check)
+			return ok && !p.Until.After(pause.Until)
+		}) {
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func checksResumeRun(cmd *cobra.Command, args []string) {
+	_, api, err := newClient(args)
+	if err == errUsage || (len(pauseTags) == 0 && !pauseExpired) {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	checks, err := taggedChecks(ctx, api, pauseTags)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to get checks", "err", err)
+		os.Exit(1)
+	}
+
+	now := time.Now()
+	selected := func(check pingdom.CheckResponse) bool {
+		p, ok := exporter.CheckPause(check)
+		if pauseExpired {
+			return ok && !p.Until.After(now)
+		}
+		return ok || (pauseAll && exporter.IsPaused(check))
+	}
+
+	// The listed checks have their tags and status, only the checks selected
+	// are read again.
+	var candidates []pingdom.CheckResponse
+	for _, check := range checks {
+		if selected(check) {
+			candidates = append(candidates, check)
+		}
+	}
+	if !resumeChecks(ctx, api, candidates, selected) {
+		os.Exit(1)
+	}
+}
+
+// resumeChecks reads the checks again, and resumes those selected, removing
+// the tags of their pause. It returns false if a check failed to resume.
+func resumeChecks(ctx context.Context, api *exporter.Client, checks []pingdom.CheckResponse, selected func(pingdom.CheckResponse) bool) bool {
+	ok := true
+
+	for _, c := range checks {
+		check, err := api.ReadCheck(ctx, c.ID)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to read check", "check_id", c.ID, "err", err)
+			ok = false
+			continue
+		}
+		if !selected(*check) {
+			continue
+		}
+
+		if pauseDryRun {
+			level.Info(logger).Log("msg", "would resume check", "check_id", check.ID, "name", check.Name)
+			continue
+		}
+		if err := api.SetCheckPaused(ctx, check.ID, false, exporter.WithoutPauseTags(*check)); err != nil {
+			level.Error(logger).Log("msg", "failed to resume check", "check_id", check.ID, "err", err)
+			ok = false
+			continue
+		}
+		level.Info(logger).Log("msg", "resumed check", "check_id", check.ID, "name", check.Name)
+	}
+
+	return ok
+}
+
+// taggedChecks returns the checks with any of the tags, or all checks without
+// tags, sorted by ID.
+func taggedChecks(ctx context.Context, api *exporter.Client, tags []string) ([]pingdom.CheckResponse, error) {
+	params := map[string]string{"include_tags": "true"}
+	if len(tags) > 0 {
+		params["tags"] = strings.Join(tags, ",")
+	}
+
+	checks, err := api.ListChecks(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].ID < checks[j].ID })
+
+	return checks, nil
+}
+
+// waitUntil waits until the time, and returns false if interrupted by a
+// signal before. Tests replace it, so as not to wait.
+var waitUntil = func(t time.Time) bool {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signalChan)
+
+	level.Info(logger).Log("msg", "waiting to resume the checks", "until", t.Format(time.RFC3339))
+
+	select {
+	case <-signalChan:
+		return false
+	case <-time.After(time.Until(t)):
+		return true
+	}
+}
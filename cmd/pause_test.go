@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom/fakepingdomtest"
+)
+
+// pauseFixtures returns the default fixtures with Staging also tagged
+// production, Status page paused until a time passed and Mail paused for
+// another hour, by checks pause.
+func pauseFixtures() *fakepingdom.Fixtures {
+	fixtures := fakepingdom.DefaultFixtures()
+	fixtures.Checks[2].Tags = []pingdom.CheckResponseTag{{Name: "production", Type: "u"}}
+	fixtures.Checks[3].Paused = true
+	fixtures.Checks[3].Status = "paused"
+	fixtures.Checks[3].Tags = []pingdom.CheckResponseTag{{Name: "team-ops", Type: "u"}, {Name: "paused-until-201805041330", Type: "u"}, {Name: "paused-by-jane", Type: "u"}}
+	fixtures.Checks[4].Paused = true
+	fixtures.Checks[4].Status = "paused"
+	fixtures.Checks[4].Tags = []pingdom.CheckResponseTag{{Name: exporter.PausedUntilTagPrefix + time.Now().Add(time.Hour).UTC().Format(exporter.PausedUntilLayout), Type: "u"}}
+
+	return fixtures
+}
+
+// readPause returns whether the check is paused, and its sorted tags.
+func readPause(t *testing.T, fake *fakepingdomtest.Server, id int) (bool, []string) {
+	check, err := fake.Client.ReadCheck(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tags []string
+	for _, tag := range check.Tags {
+		tags = append(tags, tag.Name)
+	}
+	sort.Strings(tags)
+
+	return exporter.IsPaused(*check), tags
+}
+
+func TestChecksPause(t *testing.T) {
+	defer func() {
+		pauseTags = nil
+	}()
+
+	fake := fakepingdomtest.NewServer(t, pauseFixtures())
+	defer fake.Close()
+
+	start := time.Now()
+	runCommand(t, fake, "checks", "pause", "--tag", "production", "--for", "1h", "--by", "Jane Doe")
+
+	for _, id := range []int{1001, 1002} {
+		check, err := fake.Client.ReadCheck(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, ok := exporter.CheckPause(*check)
+		if !exporter.IsPaused(*check) || !ok {
+			t.Fatalf("expected check %d to be paused with tags, got %+v", id, check)
+		}
+		if p.By != "jane-doe" {
+			t.Errorf("check %d paused by %q, want jane-doe", id, p.By)
+		}
+		// The pause is rounded up to the minute.
+		if min := start.Add(time.Hour).Truncate(time.Minute); p.Until.Before(min) || p.Until.After(min.Add(2*time.Minute)) {
+			t.Errorf("check %d paused until %v, want about %v", id, p.Until, start.Add(time.Hour))
+		}
+	}
+
+	// Staging was already paused without the tags, and is left alone.
+	if paused, tags := readPause(t, fake, 1003); !paused || !reflect.DeepEqual(tags, []string{"production"}) {
+		t.Errorf("expected Staging to be left alone, got paused %t, tags %v", paused, tags)
+	}
+
+	runCommand(t, fake, "checks", "resume", "--tag", "production")
+
+	if paused, tags := readPause(t, fake, 1001); paused || !reflect.DeepEqual(tags, []string{"production", "team-web"}) {
+		t.Errorf("expected Website to be resumed without the pause tags, got paused %t, tags %v", paused, tags)
+	}
+	if paused, tags := readPause(t, fake, 1002); paused || !reflect.DeepEqual(tags, []string{"production"}) {
+		t.Errorf("expected API to be resumed without the pause tags, got paused %t, tags %v", paused, tags)
+	}
+	// Checks paused otherwise are only resumed with --all.
+	if paused, _ := readPause(t, fake, 1003); !paused {
+		t.Errorf("expected Staging to stay paused")
+	}
+}
+
+func TestChecksResumeExpired(t *testing.T) {
+	fake := fakepingdomtest.NewServer(t, pauseFixtures())
+	defer fake.Close()
+
+	runCommand(t, fake, "checks", "resume", "--expired")
+
+	// Only the checks selected from the list are read.
+	var reads []string
+	for _, r := range fake.Requests() {
+		if r.Method == "GET" && r.Path != "/api/2.0/checks" {
+			reads = append(reads, r.Path)
+		}
+	}
+	if want := []string{"/api/2.0/checks/1004"}; !reflect.DeepEqual(reads, want) {
+		t.Errorf("expected the requests %v, got %v", want, reads)
+	}
+
+	if paused, tags := readPause(t, fake, 1004); paused || !reflect.DeepEqual(tags, []string{"team-ops"}) {
+		t.Errorf("expected Status page to be resumed without the pause tags, got paused %t, tags %v", paused, tags)
+	}
+	if paused, _ := readPause(t, fake, 1005); !paused {
+		t.Errorf("expected Mail to stay paused until its pause expires")
+	}
+	if paused, _ := readPause(t, fake, 1003); !paused {
+		t.Errorf("expected Staging to stay paused")
+	}
+}
+
+func TestChecksPauseWait(t *testing.T) {
+	defer func(wait func(time.Time) bool) {
+		waitUntil = wait
+		pauseTags = nil
+	}(waitUntil)
+
+	fake := fakepingdomtest.NewServer(t, pauseFixtures())
+	defer fake.Close()
+
+	// API is paused again for longer while waiting.
+	later := exporter.PausedUntilTagPrefix + time.Now().Add(2*time.Hour).UTC().Format(exporter.PausedUntilLayout)
+	var waited time.Time
+	waitUntil = func(until time.Time) bool {
+		waited = until
+		if err := fake.Client.SetCheckPaused(context.Background(), 1002, true, []string{"production", later}); err != nil {
+			t.Fatal(err)
+		}
+		return true
+	}
+
+	runCommand(t, fake, "checks", "pause", "--tag", "production", "--for", "1h", "--wait")
+
+	if waited.Before(time.Now().Add(59 * time.Minute)) {
+		t.Errorf("expected to wait for the pause, waited until %v", waited)
+	}
+	if paused, tags := readPause(t, fake, 1001); paused || !reflect.DeepEqual(tags, []string{"production", "team-web"}) {
+		t.Errorf("expected Website to be resumed without the pause tags, got paused %t, tags %v", paused, tags)
+	}
+	if paused, tags := readPause(t, fake, 1002); !paused || !reflect.DeepEqual(tags, []string{later, "production"}) {
+		t.Errorf("expected API to stay paused, got paused %t, tags %v", paused, tags)
+	}
+	if paused, _ := readPause(t, fake, 1003); !paused {
+		t.Errorf("expected Staging to stay paused")
+	}
+}
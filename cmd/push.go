@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/alertmanager"
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+	"github.com/giantswarm/prometheus-pingdom-exporter/push"
+	"github.com/giantswarm/prometheus-pingdom-exporter/rules"
+)
+
+// pushTimeout bounds every push, so that an unreachable Pushgateway or
+// remote write endpoint does not hold up polling.
+const pushTimeout = 30 * time.Second
+
+var (
+	pushgatewayURL      string
+	pushgatewayJob      string
+	pushgatewayGrouping []string
+	remoteWriteURL      string
+	remoteWriteLabels   []string
+
+	alertmanagerURLs           []string
+	alertmanagerConfigFile     string
+	alertmanagerStatuses       []string
+	alertmanagerResendInterval time.Duration
+)
+
+// addPushFlags adds the flags used by newPushers to the command.
+func addPushFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&pushgatewayURL, "pushgateway.url", "", "Pushgateway to push the metrics to after every poll")
+	cmd.Flags().StringVar(&pushgatewayJob, "pushgateway.job", "pingdom", "job label of the metrics pushed to the Pushgateway")
+	cmd.Flags().StringSliceVar(&pushgatewayGrouping, "pushgateway.grouping", nil, "grouping key label of the metrics pushed to the Pushgateway, as name=value (can be repeated)")
+	cmd.Flags().StringVar(&remoteWriteURL, "remote-write.url", "", "Prometheus remote write endpoint to send the metrics to after every poll, timestamped with the last test time of the checks")
+	cmd.Flags().StringSliceVar(&remoteWriteLabels, "remote-write.label", nil, "label added to the metrics sent through remote write, as name=value (can be repeated)")
+	cmd.Flags().StringSliceVar(&alertmanagerURLs, "alertmanager.url", nil, "Alertmanager to send alerts for down checks to after every poll (can be repeated)")
+	cmd.Flags().StringVar(&alertmanagerConfigFile, "alertmanager.config", "", "alerting rules configuration file with the label templates of the alerts, see the rules command")
+	cmd.Flags().StringSliceVar(&alertmanagerStatuses, "alertmanager.status", []string{"down"}, "check status firing an alert (can be repeated)")
+	cmd.Flags().DurationVar(&alertmanagerResendInterval, "alertmanager.resend-interval", time.Minute, "time between sends of the firing alerts while no check changes")
+}
+
+// newPushers returns the pushers configured with the push flags, pushing
+// the metrics of the exporter and the alerts of its checks.
+func newPushers(exp *exporter.Exporter) ([]push.Pusher, error) {
+	var pushers []push.Pusher
+
+	if pushgatewayURL != "" {
+		grouping, err := parseLabels(pushgatewayGrouping)
+		if err != nil {
+			return nil, err
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(exp)
+
+		c := push.DefaultGatewayConfig()
+		c.URL = pushgatewayURL
+		c.Job = pushgatewayJob
+		c.Grouping = grouping
+		c.Gatherer = registry
+		c.HTTPClient = &http.Client{Timeout: pushTimeout}
+
+		gateway, err := push.NewGateway(c)
+		if err != nil {
+			return nil, err
+		}
+		pushers = append(pushers, gateway)
+	}
+
+	if remoteWriteURL != "" {
+		labels, err := parseLabels(remoteWriteLabels)
+		if err != nil {
+			return nil, err
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(exp.WithTestTimestamps())
+
+		c := push.DefaultRemoteWriterConfig()
+		c.URL = remoteWriteURL
+		c.Labels = labels
+		c.Gatherer = registry
+		c.HTTPClient = &http.Client{Timeout: pushTimeout}
+
+		writer, err := push.NewRemoteWriter(c)
+		if err != nil {
+			return nil, err
+		}
+		pushers = append(pushers, writer)
+	}
+
+	if len(alertmanagerURLs) > 0 {
+		file := rules.DefaultFile()
+		if alertmanagerConfigFile != "" {
+			var err error
+			file, err = rules.LoadFile(alertmanagerConfigFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		c := alertmanager.DefaultConfig()
+		c.URLs = alertmanagerURLs
+		c.Checks = exp
+		c.Rules = file
+		c.FiringStatuses = alertmanagerStatuses
+		c.ResendInterval = alertmanagerResendInterval
+		c.HTTPClient = &http.Client{Timeout: pushTimeout}
+		c.Logger = logger
+
+		notifier, err := alertmanager.New(c)
+		if err != nil {
+			return nil, err
+		}
+		pushers = append(pushers, notifier)
+	}
+
+	return pushers, nil
+}
+
+// pushAll pushes the metrics with every pusher, and returns the last error.
+func pushAll(ctx context.Context, pushers []push.Pusher) error {
+	var lastErr error
+
+	for _, pusher := range pushers {
+		pushCtx, cancel := context.WithTimeout(ctx, pushTimeout)
+		err := pusher.Push(pushCtx)
+		cancel()
+
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to push metrics", "pusher", fmt.Sprintf("%T", pusher), "err", err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// runPoller polls Pingdom every interval until the context is cancelled,
+// pushing the metrics after every poll.
+func runPoller(ctx context.Context, exp *exporter.Exporter, interval time.Duration, pushers []push.Pusher) {
+	for {
+		if err := exp.Poll(ctx); err != context.Canceled && len(pushers) > 0 {
+			pushAll(ctx, pushers)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// parseLabels parses name=value flags into labels.
+func parseLabels(flags []string) (map[string]string, error) {
+	labels := map[string]string{}
+
+	for _, flag := range flags {
+		parts := strings.SplitN(flag, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid label %q, expected name=value", flag)
+		}
+		labels[parts[0]] = parts[1]
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedHeaders are the request headers carrying credentials, which are
+// never written to recordings.
+var redactedHeaders = []string{"Authorization", "App-Key"}
+
+// redactedParams are the query parameters carrying credentials, e.g. the
+// basic authentication of HTTP checks created or updated.
+var redactedParams = []string{"auth"}
+
+// redactedFields are the fields of response bodies carrying credentials,
+// e.g. the basic authentication password of HTTP checks, which are blanked
+// in recordings.
+var redactedFields = []string{"password"}
+
+// exchange is a recorded Pingdom API request and its response.
+type exchange struct {
+	Time          time.Time   `json:"time"`
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	RequestHeader http.Header `json:"request_header"`
+	StatusCode    int         `json:"status_code"`
+	Header        http.Header `json:"header"`
+	Body          string      `json:"body"`
+}
+
+// key identifies the requests an exchange can be replayed for.
+func (e *exchange) key() string {
+	return e.Method + " " + e.URL
+}
+
+// recordingTransport writes every request and response to a directory, one
+// JSON file per exchange, numbered in the order the requests were sent.
+type recordingTransport struct {
+	dir  string
+	next http.RoundTripper
+
+	mu  sync.Mutex
+	seq int
+}
+
+func newRecordingTransport(dir string, next http.RoundTripper) (*recordingTransport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	// Continue numbering after existing recordings, so that restarting the
+	// exporter appends to them.
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &recordingTransport{
+		dir:  dir,
+		next: next,
+		seq:  len(files),
+	}
+
+	return t, nil
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	requestHeader := http.Header{}
+	for k, v := range req.Header {
+		requestHeader[k] = v
+	}
+	for _, h := range redactedHeaders {
+		if requestHeader.Get(h) != "" {
+			requestHeader.Set(h, "REDACTED")
+		}
+	}
+
+	e := &exchange{
+		Time:          time.Now().UTC(),
+		Method:        req.Method,
+		URL:           redactURI(req.URL),
+		RequestHeader: requestHeader,
+		StatusCode:    resp.StatusCode,
+		Header:        resp.Header,
+		Body:          string(redactBody(body)),
+	}
+
+	if err := t.write(e); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (t *recordingTransport) write(e *exchange) error {
+	content, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.seq++
+	name := fmt.Sprintf("%06d-%s-%s.json", t.seq, strings.ToLower(e.Method), sanitizeFileName(e.URL))
+
+	return ioutil.WriteFile(filepath.Join(t.dir, name), content, 0644)
+}
+
+// redactURI returns the request URI of the URL, with the values of the
+// parameters carrying credentials replaced.
+func redactURI(u *url.URL) string {
+	query := u.Query()
+	redacted := false
+	for _, p := range redactedParams {
+		if query.Get(p) != "" {
+			query.Set(p, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.RequestURI()
+	}
+
+	r := *u
+	r.RawQuery = query.Encode()
+
+	return r.RequestURI()
+}
+
+// redactBody returns the JSON body with the fields carrying credentials
+// blanked. Other bodies are returned unchanged.
+func redactBody(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil || !redactValue(v) {
+		return body
+	}
+
+	redacted, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+
+	return redacted
+}
+
+// redactValue blanks the fields carrying credentials in the decoded JSON
+// value, and returns true if it found any.
+func redactValue(v interface{}) bool {
+	redacted := false
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && s != "" && containsString(redactedFields, key) {
+				v[key] = ""
+				redacted = true
+			} else if redactValue(value) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if redactValue(value) {
+				redacted = true
+			}
+		}
+	}
+
+	return redacted
+}
+
+// sanitizeFileName makes a request URI usable in a file name. File names are
+// only for humans browsing a recording; replay matches the recorded URL.
+func sanitizeFileName(uri string) string {
+	uri = strings.TrimPrefix(uri, "/api/2.0/")
+	if i := strings.Index(uri, "?"); i >= 0 {
+		uri = uri[:i]
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' {
+			return r
+		}
+		return '_'
+	}, uri)
+}
+
+// replayTransport answers requests with the responses recorded by
+// recordingTransport instead of calling the Pingdom API. Responses to the
+// same request are replayed in the recorded order, and the last one is
+// repeated once they run out.
+type replayTransport struct {
+	mu        sync.Mutex
+	exchanges map[string][]*exchange
+}
+
+func newReplayTransport(dir string) (*replayTransport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recordings found in %s", dir)
+	}
+	sort.Strings(files)
+
+	t := &replayTransport{
+		exchanges: map[string][]*exchange{},
+	}
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		e := &exchange{}
+		if err := json.Unmarshal(content, e); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+
+		t.exchanges[e.key()] = append(t.exchanges[e.key()], e)
+	}
+
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Credentials were redacted from the recorded URLs.
+	key := req.Method + " " + redactURI(req.URL)
+
+	t.mu.Lock()
+	exchanges := t.exchanges[key]
+	if len(exchanges) == 0 {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("no recorded response for %s", key)
+	}
+	e := exchanges[0]
+	if len(exchanges) > 1 {
+		t.exchanges[key] = exchanges[1:]
+	}
+	t.mu.Unlock()
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header,
+		Body:          ioutil.NopCloser(strings.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordingRedactsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			w.Write([]byte(`{"message": "Modification of check was successful!"}`))
+			return
+		}
+		w.Write([]byte(`{"check": {"id": 1001, "type": {"http": {"url": "/", "encryption": true, "username": "monitor", "password": "s3cr3t"}}}}`))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "recording")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	transport, err := newRecordingTransport(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: transport}
+
+	send := func(client *http.Client, method, url string) string {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("user", "s3cr3t")
+		req.Header.Set("App-Key", "s3cr3t")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	// The caller gets the response unchanged.
+	if body := send(client, "GET", server.URL+"/api/2.0/checks/1001"); !strings.Contains(body, "s3cr3t") {
+		t.Errorf("expected the response to be unchanged, got %s", body)
+	}
+	send(client, "PUT", server.URL+"/api/2.0/checks/1001?auth=monitor%3As3cr3t&name=Website")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 recordings, got %v", files)
+	}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(content), "s3cr3t") {
+			t.Errorf("recording %s contains credentials:\n%s", file, content)
+		}
+	}
+
+	// Recordings are replayed for the requests with credentials.
+	replay, err := newReplayTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: replay}
+	if body := send(client, "GET", "http://pingdom.invalid/api/2.0/checks/1001"); !strings.Contains(body, `"username":"monitor"`) || !strings.Contains(body, `"password":""`) {
+		t.Errorf("unexpected replayed check %s", body)
+	}
+	if body := send(client, "PUT", "http://pingdom.invalid/api/2.0/checks/1001?auth=monitor%3Aother&name=Website"); !strings.Contains(body, "successful") {
+		t.Errorf("unexpected replayed update %s", body)
+	}
+}
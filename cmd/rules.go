@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+	"github.com/giantswarm/prometheus-pingdom-exporter/rules"
+)
+
+var (
+	rulesCmd = &cobra.Command{
+		Use:   "rules [username] [password] [api-key]",
+		Short: "Generate Prometheus alerting and recording rules for the checks",
+		Long: `Generate Prometheus alerting and recording rules for the checks of the
+account: an alert per check when its pingdom_check_status is the value of the
+down status, set with --status-value like for the exporter, and, with a
+response time threshold, when it is slow, alerts when the exporter fails to
+poll Pingdom, and recording rules for the availability ratio of the checks
+over 1h and 1d and the ratio of checks down.
+
+The rules are written as a rule file, or as a PrometheusRule resource for the
+Prometheus operator with --format prometheusrule. Alert labels, e.g. severity
+and team, are set from templates in the file given with --config.`,
+		Run: rulesRun,
+	}
+
+	rulesConfigFile string
+	rulesFormat     string
+	rulesName       string
+	rulesNamespace  string
+	rulesLabels     []string
+	rulesOutputFile string
+)
+
+func init() {
+	RootCmd.AddCommand(rulesCmd)
+
+	rulesCmd.Flags().StringVar(&rulesConfigFile, "config", "", "file configuring the generated rules (default built-in configuration)")
+	rulesCmd.Flags().StringVar(&rulesFormat, "format", "rules", "output format, rules or prometheusrule")
+	rulesCmd.Flags().StringVar(&rulesName, "name", "pingdom-checks", "name of the PrometheusRule")
+	rulesCmd.Flags().StringVar(&rulesNamespace, "namespace", "", "namespace of the PrometheusRule")
+	rulesCmd.Flags().StringSliceVar(&rulesLabels, "label", nil, "label of the PrometheusRule, as name=value (can be repeated)")
+	rulesCmd.Flags().StringVarP(&rulesOutputFile, "output-file", "o", "", "file to write the rules to, instead of stdout")
+	addStatusValueFlag(rulesCmd)
+}
+
+func rulesRun(cmd *cobra.Command, args []string) {
+	_, api, err := newClient(args)
+	if err == errUsage {
+		cmd.Help()
+		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+
+	content, err := generateRules(api)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to generate rules", "err", err)
+		os.Exit(1)
+	}
+
+	if rulesOutputFile == "" {
+		_, err = os.Stdout.Write(content)
+	} else {
+		err = writeFileAtomically(rulesOutputFile, content, 0644)
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to write rules", "err", err)
+		os.Exit(1)
+	}
+}
+
+// generateRules returns the rules for the checks of the account, in the
+// format of the --format flag.
+func generateRules(api exporter.PingdomAPI) ([]byte, error) {
+	if rulesFormat != "rules" && rulesFormat != "prometheusrule" {
+		return nil, fmt.Errorf("invalid format %q, expected rules or prometheusrule", rulesFormat)
+	}
+
+	file := rules.DefaultFile()
+	if rulesConfigFile != "" {
+		var err error
+		file, err = rules.LoadFile(rulesConfigFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	metadataLabels, err := parseLabels(rulesLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	statusValues, err := exporter.ParseStatusValues(statusValueFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	checks, err := api.ListChecks(ctx, map[string]string{"include_tags": "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	ruleFile, err := rules.Generate(file, checks, statusValues["down"])
+	if err != nil {
+		return nil, err
+	}
+
+	switch rulesFormat {
+	case "rules":
+		return yaml.Marshal(ruleFile)
+	case "prometheusrule":
+		return yaml.Marshal(ruleFile.PrometheusRule(rulesName, rulesNamespace, metadataLabels))
+	default:
+		return nil, fmt.Errorf("invalid format %q, expected rules or prometheusrule", rulesFormat)
+	}
+}
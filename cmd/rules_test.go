@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom/fakepingdomtest"
+)
+
+func TestGenerateRulesInvalidFormat(t *testing.T) {
+	defer func(format string) {
+		rulesFormat = format
+	}(rulesFormat)
+
+	fake := fakepingdomtest.NewServer(t, fakepingdom.DefaultFixtures())
+	defer fake.Close()
+
+	rulesFormat = "json"
+	if _, err := generateRules(fake.Client); err == nil {
+		t.Errorf("invalid format was accepted")
+	}
+	if requests := fake.Requests(); len(requests) != 0 {
+		t.Errorf("expected no API requests, got %v", requests)
+	}
+}
@@ -1,20 +1,24 @@
 package cmd
 
 import (
-	"flag"
+	"context"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
-	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/russellcardullo/go-pingdom/pingdom"
 	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+	"github.com/giantswarm/prometheus-pingdom-exporter/slo"
 )
 
 var (
@@ -24,23 +28,21 @@ var (
 		Run:   serverRun,
 	}
 
-	waitSeconds int
-	port        int
+	waitSeconds         int
+	port                int
+	webConfigFile       string
+	shutdownGracePeriod time.Duration
+	statusValueFlags    []string
 
-	pingdomUp = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "pingdom_up",
-		Help: "Whether the last pingdom scrape was successfull (1: up, 0: down)",
-	})
+	performanceInterval    time.Duration
+	performanceResolutions []string
 
-	pingdomCheckStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "pingdom_check_status",
-		Help: "The current status of the check (0: up, 1: unconfirmed_down, 2: down, -1: paused, -2: unknown)",
-	}, []string{"id", "name", "hostname", "resolution", "paused", "tags"})
+	sloConfigFile string
+	sloInterval   time.Duration
 
-	pingdomCheckResponseTime = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "pingdom_check_response_time",
-		Help: "The response time of last test in milliseconds",
-	}, []string{"id", "name", "hostname", "resolution", "paused", "tags"})
+	webhookEnabled  bool
+	webhookToken    string
+	webhookInsecure bool
 )
 
 func init() {
@@ -48,133 +50,205 @@ func init() {
 
 	serverCmd.Flags().IntVar(&waitSeconds, "wait", 10, "time (in seconds) between accessing the Pingdom  API")
 	serverCmd.Flags().IntVar(&port, "port", 8000, "port to listen on")
-
-	prometheus.MustRegister(pingdomUp)
-	prometheus.MustRegister(pingdomCheckStatus)
-	prometheus.MustRegister(pingdomCheckResponseTime)
-}
-
-func sleep() {
-	time.Sleep(time.Second * time.Duration(waitSeconds))
+	serverCmd.Flags().DurationVar(&shutdownGracePeriod, "shutdown-grace-period", 30*time.Second, "time to wait for in-flight scrapes to finish on shutdown")
+	addStatusValueFlag(serverCmd)
+	addPushFlags(serverCmd)
+	serverCmd.Flags().DurationVar(&performanceInterval, "performance.interval", 0, "time between refreshes of the summary.performance metrics, e.g. 15m (default 0, disabled)")
+	serverCmd.Flags().StringSliceVar(&performanceResolutions, "performance.resolution", []string{"hour", "day", "week"}, "summary.performance resolution to export (can be repeated)")
+	serverCmd.Flags().StringVar(&sloConfigFile, "slo.config.file", "", "path to a file with the availability objectives to export")
+	serverCmd.Flags().DurationVar(&sloInterval, "slo.interval", 5*time.Minute, "time between refreshes of the SLO metrics")
+	serverCmd.Flags().BoolVar(&webhookEnabled, "webhook.enabled", false, "accept Pingdom state change webhooks on /webhook/pingdom")
+	serverCmd.Flags().StringVar(&webhookToken, "webhook.token", "", "token webhooks must pass in the token query parameter")
+	serverCmd.Flags().BoolVar(&webhookInsecure, "webhook.insecure", false, "accept webhooks without --webhook.token, from anyone reaching the exporter")
+	serverCmd.Flags().StringVar(&webConfigFile, "web.config.file", "", "path to a web configuration file enabling TLS and basic authentication")
 }
 
 func serverRun(cmd *cobra.Command, args []string) {
-	var client *pingdom.Client
-	flag.Parse()
-
-	if len(cmd.Flags().Args()) == 3 {
-		client = pingdom.NewClient(
-			flag.Arg(1),
-			flag.Arg(2),
-			flag.Arg(3),
-		)
-	} else if len(cmd.Flags().Args()) == 4 {
-		client = pingdom.NewMultiUserClient(
-			flag.Arg(1),
-			flag.Arg(2),
-			flag.Arg(3),
-			flag.Arg(4),
-		)
-	} else {
+	client, api, err := newClient(args)
+	if err == errUsage {
 		cmd.Help()
 		os.Exit(1)
+	} else if err != nil {
+		level.Error(logger).Log("msg", "failed to create Pingdom client", "err", err)
+		os.Exit(1)
+	}
+	if err := checkWebhookFlags(); err != nil {
+		level.Error(logger).Log("msg", "invalid webhook flags", "err", err)
+		os.Exit(1)
 	}
 
-	go func() {
-		for {
-			params := map[string]string{
-				"include_tags": "true",
-			}
-			checks, err := client.Checks.List(params)
-			if err != nil {
-				log.Println("Error getting checks ", err)
-				pingdomUp.Set(0)
-
-				sleep()
-				continue
-			}
-			pingdomUp.Set(1)
-
-			for _, check := range checks {
-				id := strconv.Itoa(check.ID)
-
-				var status float64
-				switch check.Status {
-				case "unknown":
-					status = -2
-				case "paused":
-					status = -1
-				case "up":
-					status = 0
-				case "unconfirmed_down":
-					status = 1
-				case "down":
-					status = 2
-				default:
-					status = 100
-				}
-
-				resolution := strconv.Itoa(check.Resolution)
-
-				paused := strconv.FormatBool(check.Paused)
-				// Pingdom library doesn't report paused correctly,
-				// so calculate it off the status.
-				if check.Status == "paused" {
-					paused = "true"
-				}
-
-				var tagsRaw []string
-				for _, tag := range check.Tags {
-					tagsRaw = append(tagsRaw, tag.Name)
-				}
-				tags := strings.Join(tagsRaw, ",")
-
-				pingdomCheckStatus.WithLabelValues(
-					id,
-					check.Name,
-					check.Hostname,
-					resolution,
-					paused,
-					tags,
-				).Set(status)
-
-				pingdomCheckResponseTime.WithLabelValues(
-					id,
-					check.Name,
-					check.Hostname,
-					resolution,
-					paused,
-					tags,
-				).Set(float64(check.LastResponseTime))
-			}
-
-			sleep()
+	exp, err := newExporter(client, api)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to create exporter", "err", err)
+		os.Exit(1)
+	}
+	pushers, err := newPushers(exp)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to configure pushing", "err", err)
+		os.Exit(1)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(exp)
+
+	var performance *exporter.PerformanceCollector
+	if performanceInterval > 0 {
+		c := exporter.DefaultPerformanceConfig()
+		c.API = api
+		c.Logger = log.With(logger, "account", exporter.AccountName(client))
+		c.Resolutions = performanceResolutions
+
+		performance, err = exporter.NewPerformanceCollector(c)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create performance collector", "err", err)
+			os.Exit(1)
+		}
+		registry.MustRegister(performance)
+	}
+
+	var objectives *slo.Collector
+	if sloConfigFile != "" {
+		file, err := slo.LoadFile(sloConfigFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to load SLO config", "file", sloConfigFile, "err", err)
+			os.Exit(1)
+		}
+
+		c := slo.DefaultConfig()
+		c.API = api
+		c.Logger = log.With(logger, "account", exporter.AccountName(client))
+		c.File = file
+
+		objectives, err = slo.New(c)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create SLO collector", "err", err)
+			os.Exit(1)
 		}
+		registry.MustRegister(objectives)
+	}
+	registry.MustRegister(prometheus.NewGoCollector())
+	registry.MustRegister(prometheus.NewProcessCollector(os.Getpid(), ""))
+
+	var webServer *webConfigServer
+	if webConfigFile != "" {
+		webServer, err = newWebConfigServer(webConfigFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to load web config", "file", webConfigFile, "err", err)
+			os.Exit(1)
+		}
+	}
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signalChan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pollers sync.WaitGroup
+	pollers.Add(1)
+	go func() {
+		defer pollers.Done()
+		runPoller(ctx, exp, time.Second*time.Duration(waitSeconds), pushers)
 	}()
+	if performance != nil {
+		pollers.Add(1)
+		go func() {
+			defer pollers.Done()
+			performance.Run(ctx, performanceInterval)
+		}()
+	}
+	if objectives != nil {
+		pollers.Add(1)
+		go func() {
+			defer pollers.Done()
+			objectives.Run(ctx, sloInterval)
+		}()
+	}
+
+	authenticate := func(h http.Handler) http.Handler {
+		if webServer == nil {
+			return h
+		}
+		return webServer.authenticate(h)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", authenticate(landingPageHandler(exp)))
+	mux.HandleFunc("/-/healthy", healthyHandler)
+	mux.HandleFunc("/-/ready", readyHandler(exp))
+	mux.Handle("/metrics", authenticate(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+	if webhookEnabled {
+		mux.HandleFunc("/webhook/pingdom", webhookHandler(exp, webhookToken))
+	}
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: mux,
+	}
 
+	serverErr := make(chan error, 1)
 	go func() {
-		intChan := make(chan os.Signal)
-		termChan := make(chan os.Signal)
-
-		signal.Notify(intChan, syscall.SIGINT)
-		signal.Notify(termChan, syscall.SIGTERM)
-
-		select {
-		case <-intChan:
-			log.Print("Received SIGINT, exiting")
-			os.Exit(0)
-		case <-termChan:
-			log.Print("Received SIGTERM, exiting")
-			os.Exit(0)
+		level.Info(logger).Log("msg", "listening", "port", port)
+
+		if webServer != nil {
+			serverErr <- webServer.listenAndServe(server)
+		} else {
+			serverErr <- server.ListenAndServe()
 		}
 	}()
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "")
-	})
-	http.Handle("/metrics", prometheus.Handler())
+	select {
+	case sig := <-signalChan:
+		level.Info(logger).Log("msg", "received signal, shutting down", "signal", sig)
+	case err := <-serverErr:
+		level.Error(logger).Log("msg", "failed to serve HTTP", "err", err)
+		os.Exit(1)
+	}
+
+	// Stop polling first, so that in-flight Pingdom requests are cancelled
+	// while in-flight scrapes are still served from the last results.
+	cancel()
+	pollers.Wait()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer shutdownCancel()
 
-	log.Print("Listening on port ", port)
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		level.Error(logger).Log("msg", "failed to shut down HTTP server", "err", err)
+		os.Exit(1)
+	}
+
+	level.Info(logger).Log("msg", "shut down")
+}
+
+// addStatusValueFlag adds the --status-value flag used by newExporter to the
+// command.
+func addStatusValueFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&statusValueFlags, "status-value", nil, "value of pingdom_check_status for a check status, as status=value (can be repeated)")
+}
+
+// newExporter returns an exporter polling the Pingdom API with the status
+// values from the --status-value flag.
+func newExporter(client *pingdom.Client, api exporter.PingdomAPI) (*exporter.Exporter, error) {
+	statusValues, err := exporter.ParseStatusValues(statusValueFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	c := exporter.DefaultConfig()
+	c.API = api
+	c.Logger = log.With(logger, "account", exporter.AccountName(client))
+	c.StatusValues = statusValues
+
+	return exporter.New(c)
+}
+
+// checkWebhookFlags returns an error if webhooks are enabled without a token,
+// unless they are explicitly accepted from anyone.
+func checkWebhookFlags() error {
+	if webhookEnabled && webhookToken == "" && !webhookInsecure {
+		return fmt.Errorf("--webhook.enabled requires --webhook.token, or --webhook.insecure to accept webhooks from anyone")
+	}
 
-	http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
+	return nil
 }
@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom/fakepingdomtest"
+)
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func get(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+// runServer runs the server command against the fake Pingdom API until the
+// returned function is called, which stops it like SIGTERM would.
+func runServer(t *testing.T, fake *fakepingdom.Server, args ...string) (string, func()) {
+	ts := httptest.NewServer(fake)
+	port := freePort(t)
+
+	RootCmd.SetArgs(append([]string{
+		"server",
+		"--port", fmt.Sprint(port),
+		"--wait", "1",
+		"--pingdom-url", ts.URL,
+	}, args...))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := RootCmd.Execute(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	addr := fmt.Sprintf("http://127.0.0.1:%d", port)
+	for i := 0; ; i++ {
+		if resp, err := http.Get(addr + "/-/healthy"); err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatal("server did not start")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	stop := func() {
+		syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		<-done
+		ts.Close()
+	}
+
+	return addr, stop
+}
+
+// runCommand runs a command against the fake Pingdom API, and resets the
+// flags of the command afterwards. Slice flags are reset by the caller, by
+// setting their variables to nil.
+func runCommand(t *testing.T, fake *fakepingdomtest.Server, args ...string) {
+	cmd, _, err := RootCmd.Find(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if !strings.HasSuffix(f.Value.Type(), "Slice") {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+
+	RootCmd.SetArgs(append(args, "--pingdom-url", fake.URL, "user", "password", "key"))
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	t.Fatal("timed out waiting for condition")
+}
+
+func assertMetrics(t *testing.T, metrics string, want ...string) {
+	for _, line := range want {
+		if !strings.Contains(metrics, "\n"+line+"\n") {
+			t.Errorf("metrics do not contain %q", line)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	fake := fakepingdom.New(fakepingdom.DefaultFixtures())
+	fake.User = "user@example.com"
+	fake.Password = "secret"
+	fake.AppKey = "key"
+
+	addr, stop := runServer(t, fake, "user@example.com", "secret", "key")
+	defer stop()
+
+	waitFor(t, func() bool {
+		code, _ := get(t, addr+"/-/ready")
+		return code == http.StatusOK
+	})
+
+	code, metrics := get(t, addr+"/metrics")
+	if code != http.StatusOK {
+		t.Fatalf("GET /metrics returned %d", code)
+	}
+
+	assertMetrics(t, metrics,
+		`pingdom_up 1`,
+		`pingdom_check_status{hostname="www.example.com",id="1001",name="Website",paused="false",resolution="1",tags="production,team-web"} 0`,
+		`pingdom_check_status{hostname="api.example.com",id="1002",name="API",paused="false",resolution="1",tags="production"} 2`,
+		`pingdom_check_status{hostname="staging.example.com",id="1003",name="Staging",paused="true",resolution="5",tags=""} -1`,
+		`pingdom_check_status{hostname="status.example.com",id="1004",name="Status page",paused="false",resolution="1",tags=""} 1`,
+		`pingdom_check_status{hostname="mail.example.com",id="1005",name="Mail",paused="false",resolution="15",tags=""} -2`,
+		`pingdom_check_state{hostname="api.example.com",id="1002",name="API",paused="false",resolution="1",state="down",tags="production"} 1`,
+		`pingdom_check_state{hostname="api.example.com",id="1002",name="API",paused="false",resolution="1",state="up",tags="production"} 0`,
+		`pingdom_check_response_time{hostname="www.example.com",id="1001",name="Website",paused="false",resolution="1",tags="production,team-web"} 123`,
+	)
+
+	// Checks with an unknown status are removed from pingdom_check_status.
+	fake.SetCheckStatus(1001, "maintenance")
+	waitFor(t, func() bool {
+		_, metrics := get(t, addr+"/metrics")
+		return !strings.Contains(metrics, `pingdom_check_status{hostname="www.example.com"`)
+	})
+
+	_, metrics = get(t, addr+"/metrics")
+	if !strings.Contains(metrics, `pingdom_check_unknown_status_total{status="maintenance"}`) {
+		t.Errorf("unknown status is not counted")
+	}
+}
+
+func TestServerRejectedCredentials(t *testing.T) {
+	fake := fakepingdom.New(fakepingdom.DefaultFixtures())
+	fake.User = "user@example.com"
+	fake.Password = "secret"
+	fake.AppKey = "key"
+
+	addr, stop := runServer(t, fake, "user@example.com", "wrong", "key")
+	defer stop()
+
+	waitFor(t, func() bool {
+		_, metrics := get(t, addr+"/metrics")
+		return strings.Contains(metrics, "\npingdom_up 0\n")
+	})
+
+	code, _ := get(t, addr+"/-/ready")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("GET /-/ready returned %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	code, page := get(t, addr+"/")
+	if code != http.StatusOK || !strings.Contains(page, "Invalid email and/or password") {
+		t.Errorf("landing page does not show the last poll error")
+	}
+}
+
+func TestServerRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pingdom-recording")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		recordDir, replayDir = "", ""
+	}()
+
+	fake := fakepingdom.New(fakepingdom.DefaultFixtures())
+	fake.User = "user@example.com"
+	fake.Password = "s3cr3t-password"
+	fake.AppKey = "s3cr3t-app-key"
+
+	statusLines := func(metrics string) []string {
+		var lines []string
+		for _, line := range strings.Split(metrics, "\n") {
+			if strings.HasPrefix(line, "pingdom_check_status{") {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+
+	addr, stop := runServer(t, fake, "--record-dir", dir, "user@example.com", "s3cr3t-password", "s3cr3t-app-key")
+	waitFor(t, func() bool {
+		code, _ := get(t, addr+"/-/ready")
+		return code == http.StatusOK
+	})
+	_, recorded := get(t, addr+"/metrics")
+	stop()
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no requests were recorded")
+	}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(dir + "/" + file.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(content), "s3cr3t") {
+			t.Errorf("recording %s contains credentials", file.Name())
+		}
+	}
+
+	// Change the fake API, so that only replayed responses give the same
+	// metrics.
+	fake.SetCheckStatus(1002, "up")
+
+	addr, stop = runServer(t, fake, "--record-dir", "", "--replay-dir", dir)
+	defer stop()
+	waitFor(t, func() bool {
+		code, _ := get(t, addr+"/-/ready")
+		return code == http.StatusOK
+	})
+	_, replayed := get(t, addr+"/metrics")
+
+	if want, got := strings.Join(statusLines(recorded), "\n"), strings.Join(statusLines(replayed), "\n"); got != want {
+		t.Errorf("replayed metrics differ from recorded ones\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestServerWebhook(t *testing.T) {
+	defer func() {
+		webhookEnabled, webhookToken = false, ""
+	}()
+
+	webhookEnabled = true
+	if err := checkWebhookFlags(); err == nil {
+		t.Errorf("expected an error for webhooks without a token")
+	}
+	webhookInsecure = true
+	if err := checkWebhookFlags(); err != nil {
+		t.Errorf("expected webhooks without a token to be accepted with --webhook.insecure, got %v", err)
+	}
+	webhookEnabled, webhookInsecure = false, false
+
+	fake := fakepingdom.New(fakepingdom.DefaultFixtures())
+	addr, stop := runServer(t, fake, "--webhook.enabled", "--webhook.token", "t0ken", "user", "password", "key")
+	defer stop()
+
+	waitFor(t, func() bool {
+		code, _ := get(t, addr+"/-/ready")
+		return code == http.StatusOK
+	})
+
+	post := func(url, body string) int {
+		resp, err := http.Post(url, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	change := fmt.Sprintf(`{"check_id": 1001, "check_name": "Website", "previous_state": "UP", "current_state": "DOWN", "state_changed_timestamp": %d}`, time.Now().Unix())
+	if code := post(addr+"/webhook/pingdom?token=wrong", change); code != http.StatusUnauthorized {
+		t.Errorf("webhook with a wrong token returned %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := post(addr+"/webhook/pingdom?token=t0ken", "{"); code != http.StatusBadRequest {
+		t.Errorf("invalid webhook returned %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := post(addr+"/webhook/pingdom?token=t0ken", `{"check_id": 9999, "current_state": "DOWN"}`); code != http.StatusAccepted {
+		t.Errorf("webhook of an unknown check returned %d, want %d", code, http.StatusAccepted)
+	}
+	if code := post(addr+"/webhook/pingdom?token=t0ken", `{"check_id": 1001, "current_state": "BROKEN"}`); code != http.StatusBadRequest {
+		t.Errorf("webhook with an unknown state returned %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := post(addr+"/webhook/pingdom?token=t0ken", change); code != http.StatusOK {
+		t.Fatalf("webhook returned %d, want %d", code, http.StatusOK)
+	}
+
+	// The fake API still reports the check up, with a test older than the
+	// state change, so the status of the webhook is kept across polls. Once
+	// a second poll starts, the first poll after the webhook has finished.
+	polls := func() int {
+		n := 0
+		for _, r := range fake.Requests() {
+			if r.Path == "/api/2.0/checks" {
+				n++
+			}
+		}
+		return n
+	}
+	after := polls()
+	waitFor(t, func() bool { return polls() >= after+2 })
+	_, metrics := get(t, addr+"/metrics")
+	assertMetrics(t, metrics,
+		`pingdom_check_status{hostname="www.example.com",id="1001",name="Website",paused="false",resolution="1",tags="production,team-web"} 2`,
+		`pingdom_check_state_changes_total{from="up",id="1001",name="Website",to="down"} 1`,
+	)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+var landingPageTemplate = template.Must(template.New("landing").Parse(`<html>
+<head><title>Pingdom Exporter</title></head>
+<body>
+<h1>Pingdom Exporter</h1>
+<p>Version: {{ .Version }} (commit {{ .GitCommit }})</p>
+<ul>
+<li><a href="/metrics">Metrics</a></li>
+<li><a href="/-/healthy">Health</a></li>
+<li><a href="/-/ready">Readiness</a></li>
+</ul>
+<h2>Last poll</h2>
+{{ if .Time.IsZero }}<p>No poll has completed yet.</p>{{ else }}<table>
+<tr><td>Time</td><td>{{ .Time.Format "2006-01-02T15:04:05Z07:00" }}</td></tr>
+<tr><td>Duration</td><td>{{ .Duration }}</td></tr>
+<tr><td>Checks</td><td>{{ .Checks }}</td></tr>
+<tr><td>Result</td><td>{{ if .Err }}error: {{ .Err }}{{ else }}ok{{ end }}</td></tr>
+</table>{{ end }}
+</body>
+</html>
+`))
+
+// landingPage is the data rendered by landingPageTemplate.
+type landingPage struct {
+	Version   string
+	GitCommit string
+
+	exporter.Status
+}
+
+func healthyHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Healthy.\n"))
+}
+
+func readyHandler(exp *exporter.Exporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !exp.Status().Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("Not ready.\n"))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Ready.\n"))
+	}
+}
+
+func landingPageHandler(exp *exporter.Exporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		page := landingPage{
+			Version:   version,
+			GitCommit: gitCommit,
+			Status:    exp.Status(),
+		}
+		if err := landingPageTemplate.Execute(w, page); err != nil {
+			level.Error(logger).Log("msg", "failed to render landing page", "err", err)
+		}
+	}
+}
+
+// maxWebhookSize is the maximum size of a webhook body.
+const maxWebhookSize = 1 << 20
+
+// webhookHandler applies the state changes sent by Pingdom webhooks to the
+// exporter. If token is set, requests must have it in the token query
+// parameter, as Pingdom cannot authenticate webhooks otherwise.
+func webhookHandler(exp *exporter.Exporter, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(token)) != 1 {
+			http.Error(w, "Invalid token.", http.StatusUnauthorized)
+			return
+		}
+
+		var change exporter.StateChange
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxWebhookSize)).Decode(&change); err != nil {
+			http.Error(w, "Invalid state change: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err := exp.ApplyStateChange(change)
+		if _, ok := err.(*exporter.UnknownCheckError); ok {
+			// The check is exported from the next poll.
+			level.Warn(logger).Log("msg", "received state change of an unknown check", "check_id", change.CheckID)
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("Unknown check.\n"))
+			return
+		} else if err != nil {
+			http.Error(w, "Invalid state change: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		level.Info(logger).Log("msg", "received state change", "check_id", change.CheckID, "state", change.CurrentState)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK.\n"))
+	}
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/go-kit/kit/log/level"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v2"
+)
+
+// webConfig is the web configuration file, in the format used by the
+// Prometheus exporter-toolkit. The file is read again on every TLS handshake
+// and every request, so changes apply without restarting the exporter.
+type webConfig struct {
+	TLSServerConfig tlsServerConfig   `yaml:"tls_server_config"`
+	BasicAuthUsers  map[string]string `yaml:"basic_auth_users"`
+}
+
+type tlsServerConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ClientAuthType string `yaml:"client_auth_type"`
+	ClientCAFile   string `yaml:"client_ca_file"`
+}
+
+// dummyHash is compared against when a request names an unknown user, so
+// that unknown and known users take the same time to be rejected.
+const dummyHash = "$2y$10$QOauhQNbBCuQDKes6eFzPeMqBSjb7Mr5DUmpZ/VcEd00UAV/LDeSi"
+
+// authCacheSize bounds the number of cached password checks.
+const authCacheSize = 100
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+func loadWebConfig(path string) (*webConfig, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &webConfig{}
+	if err := yaml.UnmarshalStrict(content, c); err != nil {
+		return nil, err
+	}
+
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *webConfig) validate() error {
+	t := c.TLSServerConfig
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("both cert_file and key_file must be set in tls_server_config")
+	}
+	if _, ok := clientAuthTypes[t.ClientAuthType]; !ok {
+		return fmt.Errorf("invalid client_auth_type %q", t.ClientAuthType)
+	}
+	if t.ClientCAFile != "" && !t.enabled() {
+		return fmt.Errorf("client_ca_file requires cert_file and key_file to be set")
+	}
+	if t.ClientCAFile == "" && clientAuthTypes[t.ClientAuthType] == tls.RequireAndVerifyClientCert {
+		return fmt.Errorf("client_auth_type %q requires client_ca_file to be set", t.ClientAuthType)
+	}
+
+	for user, hash := range c.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("invalid bcrypt hash for user %q: %v", user, err)
+		}
+	}
+
+	return nil
+}
+
+func (t *tlsServerConfig) enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+func (t *tlsServerConfig) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   clientAuthTypes[t.ClientAuthType],
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if t.ClientCAFile != "" {
+		content, err := ioutil.ReadFile(t.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no certificates found in %s", t.ClientCAFile)
+		}
+		config.ClientCAs = pool
+	}
+
+	return config, nil
+}
+
+// webConfigServer serves HTTP using the TLS and basic authentication
+// settings from a web configuration file.
+type webConfigServer struct {
+	path string
+
+	mu        sync.Mutex
+	authCache map[string]bool
+}
+
+func newWebConfigServer(path string) (*webConfigServer, error) {
+	if _, err := loadWebConfig(path); err != nil {
+		return nil, err
+	}
+
+	s := &webConfigServer{
+		path:      path,
+		authCache: map[string]bool{},
+	}
+
+	return s, nil
+}
+
+// getConfigForClient reloads the TLS configuration for every handshake, so
+// that renewed certificates are picked up.
+func (s *webConfigServer) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	c, err := loadWebConfig(s.path)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to load web config", "file", s.path, "err", err)
+		return nil, err
+	}
+	if !c.TLSServerConfig.enabled() {
+		return nil, fmt.Errorf("TLS has been disabled in %s, restart the exporter to serve plain HTTP", s.path)
+	}
+
+	return c.TLSServerConfig.tlsConfig()
+}
+
+func (s *webConfigServer) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	config, err := s.getConfigForClient(hello)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config.Certificates[0], nil
+}
+
+// authenticate wraps the handler to require one of the configured basic
+// authentication users. Requests pass through when no users are configured.
+func (s *webConfigServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := loadWebConfig(s.path)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to load web config", "file", s.path, "err", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if len(c.BasicAuthUsers) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, password, ok := r.BasicAuth()
+		if ok && s.checkPassword(c, user, password) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", "Basic")
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// checkPassword compares the password against the user's bcrypt hash.
+// Results are cached, as bcrypt is deliberately too slow to run on every
+// scrape.
+func (s *webConfigServer) checkPassword(c *webConfig, user, password string) bool {
+	hash, found := c.BasicAuthUsers[user]
+	if !found {
+		hash = dummyHash
+	}
+
+	sum := sha256.Sum256([]byte(user + "\x00" + hash + "\x00" + password))
+	key := hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	valid, cached := s.authCache[key]
+	s.mu.Unlock()
+
+	if !cached {
+		valid = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+
+		s.mu.Lock()
+		if len(s.authCache) >= authCacheSize {
+			s.authCache = map[string]bool{}
+		}
+		s.authCache[key] = valid
+		s.mu.Unlock()
+	}
+
+	return found && valid
+}
+
+// listenAndServe runs the server, over TLS if the web configuration file
+// enables it.
+func (s *webConfigServer) listenAndServe(server *http.Server) error {
+	c, err := loadWebConfig(s.path)
+	if err != nil {
+		return err
+	}
+
+	if !c.TLSServerConfig.enabled() {
+		return server.ListenAndServe()
+	}
+
+	server.TLSConfig = &tls.Config{
+		GetCertificate:     s.getCertificate,
+		GetConfigForClient: s.getConfigForClient,
+	}
+
+	return server.ListenAndServeTLS("", "")
+}
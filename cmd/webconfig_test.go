@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeWebConfig(t *testing.T, dir, content string) string {
+	path := filepath.Join(dir, "web.yml")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// writeCert writes a self-signed certificate for 127.0.0.1 with the serial
+// number to cert.pem and key.pem in the directory.
+func writeCert(t *testing.T, dir string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadWebConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "web-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		config string
+		valid  bool
+	}{
+		{config: "", valid: true},
+		{config: "basic_auth_users:\n  alice: " + dummyHash + "\n", valid: true},
+		{config: "basic_auth_users: [", valid: false},
+		{config: "unknown_field: true\n", valid: false},
+		{config: "basic_auth_users:\n  alice: plaintext\n", valid: false},
+		{config: "tls_server_config:\n  cert_file: cert.pem\n", valid: false},
+		{config: "tls_server_config:\n  cert_file: cert.pem\n  key_file: key.pem\n  client_auth_type: Sometimes\n", valid: false},
+		{config: "tls_server_config:\n  cert_file: cert.pem\n  key_file: key.pem\n  client_auth_type: RequireAndVerifyClientCert\n", valid: false},
+		{config: "tls_server_config:\n  client_ca_file: ca.pem\n", valid: false},
+	}
+
+	for _, tt := range tests {
+		_, err := loadWebConfig(writeWebConfig(t, dir, tt.config))
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("loadWebConfig(%q) returned %v, want valid %t", tt.config, err, tt.valid)
+		}
+	}
+
+	if _, err := loadWebConfig(filepath.Join(dir, "missing.yml")); err == nil {
+		t.Errorf("missing file was accepted")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "web-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cr3t"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeWebConfig(t, dir, fmt.Sprintf("basic_auth_users:\n  alice: %s\n", hash))
+
+	s, err := newWebConfigServer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	})))
+	defer ts.Close()
+
+	request := func(user, password string) int {
+		req, err := http.NewRequest("GET", ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		user, password string
+		want           int
+	}{
+		{user: "alice", password: "s3cr3t", want: http.StatusOK},
+		{user: "alice", password: "wrong", want: http.StatusUnauthorized},
+		{user: "bob", password: "s3cr3t", want: http.StatusUnauthorized},
+		{user: "", password: "", want: http.StatusUnauthorized},
+		// Cached results give the same answers.
+		{user: "alice", password: "s3cr3t", want: http.StatusOK},
+		{user: "alice", password: "wrong", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if code := request(tt.user, tt.password); code != tt.want {
+			t.Errorf("request as %q with %q returned %d, want %d", tt.user, tt.password, code, tt.want)
+		}
+	}
+
+	if len(s.authCache) != 3 {
+		t.Errorf("expected 3 cached password checks, got %d", len(s.authCache))
+	}
+
+	// The hash is part of the cache key, so a changed password applies at
+	// once.
+	hash, err = bcrypt.GenerateFromPassword([]byte("n3w"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeWebConfig(t, dir, fmt.Sprintf("basic_auth_users:\n  alice: %s\n", hash))
+	if code := request("alice", "s3cr3t"); code != http.StatusUnauthorized {
+		t.Errorf("old password returned %d after the change", code)
+	}
+	if code := request("alice", "n3w"); code != http.StatusOK {
+		t.Errorf("new password returned %d after the change", code)
+	}
+
+	// The cache is bounded.
+	for i := 0; i < authCacheSize; i++ {
+		s.checkPassword(&webConfig{BasicAuthUsers: map[string]string{"alice": string(hash)}}, "alice", fmt.Sprint(i))
+	}
+	if len(s.authCache) > authCacheSize {
+		t.Errorf("cache grew to %d entries", len(s.authCache))
+	}
+
+	// Invalid configurations reject every request.
+	writeWebConfig(t, dir, "basic_auth_users: [")
+	if code := request("alice", "n3w"); code != http.StatusInternalServerError {
+		t.Errorf("request with an invalid config returned %d", code)
+	}
+}
+
+func TestListenAndServeTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "web-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeCert(t, dir, 1)
+	path := writeWebConfig(t, dir, fmt.Sprintf("tls_server_config:\n  cert_file: %s\n  key_file: %s\n",
+		filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")))
+
+	s, err := newWebConfigServer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := fmt.Sprintf("127.0.0.1:%d", freePort(t))
+	server := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "ok")
+		}),
+	}
+	go s.listenAndServe(server)
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+	}
+	serial := func() int64 {
+		resp, err := client.Get("https://" + addr)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	waitFor(t, func() bool { return serial() == 1 })
+
+	// Certificates are reloaded on every handshake.
+	writeCert(t, dir, 2)
+	if got := serial(); got != 2 {
+		t.Errorf("renewed certificate was not served, got serial %d", got)
+	}
+
+	if resp, err := http.Get("http://" + addr); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Errorf("plain HTTP was served")
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/kube"
+	"github.com/giantswarm/prometheus-pingdom-exporter/manifest"
+)
+
+// Annotations configuring the check of an object.
+const (
+	annotationPrefix = "pingdom.giantswarm.io/"
+
+	// AnnotationEnabled must be true for the object to have a check.
+	AnnotationEnabled = annotationPrefix + "enabled"
+	// AnnotationName is the name of the check, by default the kind,
+	// namespace and name of the object, e.g. ingress/default/web.
+	AnnotationName = annotationPrefix + "name"
+	// AnnotationHost is the host to check, by default the first host of the
+	// object.
+	AnnotationHost = annotationPrefix + "host"
+	// AnnotationPath is the path and query to request, by default /.
+	AnnotationPath = annotationPrefix + "path"
+	// AnnotationEncryption is true to check over HTTPS. Ingresses default to
+	// HTTPS if the host is in their TLS hosts.
+	AnnotationEncryption = annotationPrefix + "encryption"
+	// AnnotationResolution is the interval of the tests in minutes, 1, 5,
+	// 15, 30 or 60, by default 5.
+	AnnotationResolution = annotationPrefix + "resolution"
+	// AnnotationContacts is a comma separated list of contact IDs to notify.
+	AnnotationContacts = annotationPrefix + "contacts"
+	// AnnotationTags is a comma separated list of tags of the check.
+	AnnotationTags = annotationPrefix + "tags"
+	// AnnotationShouldContain is a string the response must contain.
+	AnnotationShouldContain = annotationPrefix + "should-contain"
+	// AnnotationPaused is true to pause the check.
+	AnnotationPaused = annotationPrefix + "paused"
+)
+
+// enabled returns true if the object requests a check.
+func enabled(o kube.Object) bool {
+	v, _ := strconv.ParseBool(o.Metadata.Annotations[AnnotationEnabled])
+	return v
+}
+
+// checkName returns the name of the check of the object.
+func checkName(resource kube.Resource, o kube.Object) string {
+	if name := o.Metadata.Annotations[AnnotationName]; name != "" {
+		return name
+	}
+
+	return strings.ToLower(resource.Kind) + "/" + o.Key()
+}
+
+// checkFor returns the HTTP check requested by the annotations of the object.
+func checkFor(resource kube.Resource, o kube.Object) (manifest.Check, error) {
+	annotations := o.Metadata.Annotations
+
+	hosts, tlsHosts, err := objectHosts(resource, o)
+	if err != nil {
+		return manifest.Check{}, err
+	}
+
+	host := annotations[AnnotationHost]
+	if host == "" {
+		if len(hosts) == 0 {
+			return manifest.Check{}, fmt.Errorf("%s has no host, set the %s annotation", resource.Kind, AnnotationHost)
+		}
+		host = hosts[0]
+	}
+
+	check := manifest.Check{
+		Name:       checkName(resource, o),
+		Type:       manifest.TypeHTTP,
+		Hostname:   host,
+		Resolution: 5,
+		HTTP: &manifest.HTTP{
+			URL:           "/",
+			Encryption:    contains(tlsHosts, host),
+			ShouldContain: annotations[AnnotationShouldContain],
+		},
+	}
+
+	if path := annotations[AnnotationPath]; path != "" {
+		if !strings.HasPrefix(path, "/") {
+			return manifest.Check{}, fmt.Errorf("invalid %s %q, must start with /", AnnotationPath, path)
+		}
+		check.HTTP.URL = path
+	}
+	if v, ok := annotations[AnnotationEncryption]; ok {
+		if check.HTTP.Encryption, err = strconv.ParseBool(v); err != nil {
+			return manifest.Check{}, fmt.Errorf("invalid %s %q", AnnotationEncryption, v)
+		}
+	}
+	if v, ok := annotations[AnnotationPaused]; ok {
+		if check.Paused, err = strconv.ParseBool(v); err != nil {
+			return manifest.Check{}, fmt.Errorf("invalid %s %q", AnnotationPaused, v)
+		}
+	}
+	if v, ok := annotations[AnnotationResolution]; ok {
+		if check.Resolution, err = strconv.Atoi(v); err != nil {
+			return manifest.Check{}, fmt.Errorf("invalid %s %q", AnnotationResolution, v)
+		}
+	}
+	for _, v := range splitList(annotations[AnnotationContacts]) {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return manifest.Check{}, fmt.Errorf("invalid contact ID %q in %s", v, AnnotationContacts)
+		}
+		check.ContactIDs = append(check.ContactIDs, id)
+	}
+	check.Tags = splitList(annotations[AnnotationTags])
+
+	if err := check.PingdomCheck("").Valid(); err != nil {
+		return manifest.Check{}, err
+	}
+
+	return check, nil
+}
+
+// objectHosts returns the hosts of the object, and those served over TLS.
+func objectHosts(resource kube.Resource, o kube.Object) ([]string, []string, error) {
+	var hosts, tlsHosts []string
+
+	switch resource.Kind {
+	case kube.Ingresses.Kind:
+		var spec struct {
+			Rules []struct {
+				Host string `json:"host"`
+			} `json:"rules"`
+			TLS []struct {
+				Hosts []string `json:"hosts"`
+			} `json:"tls"`
+		}
+		if err := unmarshal(o.Spec, &spec); err != nil {
+			return nil, nil, err
+		}
+		for _, rule := range spec.Rules {
+			// Wildcard hosts cannot be checked.
+			if rule.Host != "" && !strings.HasPrefix(rule.Host, "*") {
+				hosts = append(hosts, rule.Host)
+			}
+		}
+		for _, tls := range spec.TLS {
+			tlsHosts = append(tlsHosts, tls.Hosts...)
+		}
+	case kube.Services.Kind:
+		var status struct {
+			LoadBalancer struct {
+				Ingress []struct {
+					Hostname string `json:"hostname"`
+					IP       string `json:"ip"`
+				} `json:"ingress"`
+			} `json:"loadBalancer"`
+		}
+		if err := unmarshal(o.Status, &status); err != nil {
+			return nil, nil, err
+		}
+		for _, ingress := range status.LoadBalancer.Ingress {
+			if ingress.Hostname != "" {
+				hosts = append(hosts, ingress.Hostname)
+			} else if ingress.IP != "" {
+				hosts = append(hosts, ingress.IP)
+			}
+		}
+	case kube.HTTPRoutes.Kind:
+		var spec struct {
+			Hostnames []string `json:"hostnames"`
+		}
+		if err := unmarshal(o.Spec, &spec); err != nil {
+			return nil, nil, err
+		}
+		hosts = spec.Hostnames
+	}
+
+	return hosts, tlsHosts, nil
+}
+
+func unmarshal(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+// splitList splits a comma separated list, ignoring spaces and empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+
+	return false
+}
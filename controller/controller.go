@@ -0,0 +1,381 @@
+// Package controller creates, updates and deletes Pingdom HTTP checks for
+// Kubernetes objects, from their annotations.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/kube"
+	"github.com/giantswarm/prometheus-pingdom-exporter/manifest"
+)
+
+// DefaultManagedTag is the tag identifying the checks owned by the
+// controller.
+const DefaultManagedTag = "pingdom-controller"
+
+// Config represents the configuration used to create a Controller.
+type Config struct {
+	Kubernetes kube.Interface
+	Pingdom    manifest.PingdomAPI
+	Logger     log.Logger
+
+	// Resources are the kinds of objects to watch.
+	Resources []kube.Resource
+	// Namespace restricts the watched objects to a namespace. All namespaces
+	// are watched if it is empty.
+	Namespace string
+	// ManagedTag identifies the checks owned by the controller. Owned checks
+	// without an object are deleted.
+	ManagedTag string
+	// ResyncInterval is the time between reconciliations when no object
+	// changes, which undo changes made to the checks in Pingdom.
+	ResyncInterval time.Duration
+	// RetryInterval is the time to wait before listing objects again after
+	// an error.
+	RetryInterval time.Duration
+	// MinReconcileInterval is the minimum time between reconciliations.
+	// Object changes in between are reconciled together.
+	MinReconcileInterval time.Duration
+	// DryRun logs the changes without applying them.
+	DryRun bool
+}
+
+// DefaultConfig provides a default configuration to create a Controller.
+func DefaultConfig() Config {
+	return Config{
+		Kubernetes: nil,
+		Pingdom:    nil,
+		Logger:     log.NewNopLogger(),
+
+		Resources:            []kube.Resource{kube.Ingresses},
+		Namespace:            "",
+		ManagedTag:           DefaultManagedTag,
+		ResyncInterval:       5 * time.Minute,
+		RetryInterval:        10 * time.Second,
+		MinReconcileInterval: 30 * time.Second,
+		DryRun:               false,
+	}
+}
+
+// Controller reconciles the checks owned by the controller with the
+// annotations of the watched objects.
+type Controller struct {
+	kubernetes kube.Interface
+	reconciler *manifest.Reconciler
+	logger     log.Logger
+
+	resources            []kube.Resource
+	namespace            string
+	resyncInterval       time.Duration
+	retryInterval        time.Duration
+	minReconcileInterval time.Duration
+	dryRun               bool
+
+	mu sync.Mutex
+	// objects are the watched objects, by resource kind and key.
+	objects map[string]map[string]kube.Object
+	// synced records the resources listed at least once. Checks are only
+	// reconciled once all resources are synced, so that the checks of
+	// objects not listed yet are not deleted.
+	synced map[string]bool
+}
+
+// New creates a new configured Controller.
+func New(config Config) (*Controller, error) {
+	if config.Kubernetes == nil {
+		return nil, fmt.Errorf("config.Kubernetes must not be empty")
+	}
+	if config.Pingdom == nil {
+		return nil, fmt.Errorf("config.Pingdom must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, fmt.Errorf("config.Logger must not be empty")
+	}
+	if len(config.Resources) == 0 {
+		return nil, fmt.Errorf("config.Resources must not be empty")
+	}
+	if config.ResyncInterval <= 0 {
+		return nil, fmt.Errorf("config.ResyncInterval must be positive")
+	}
+	if config.RetryInterval <= 0 {
+		return nil, fmt.Errorf("config.RetryInterval must be positive")
+	}
+	if config.MinReconcileInterval < 0 {
+		return nil, fmt.Errorf("config.MinReconcileInterval must not be negative")
+	}
+
+	rc := manifest.DefaultConfig()
+	rc.API = config.Pingdom
+	rc.Logger = config.Logger
+	rc.ManagedTag = config.ManagedTag
+	rc.Prune = true
+	// Checks created by hand are never taken over because an object has
+	// their name.
+	rc.Adopt = false
+	// Object changes are frequent, the details of checks are only read again
+	// when their listed settings change, or on resync.
+	rc.SkipUnchanged = true
+	reconciler, err := manifest.New(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Controller{
+		kubernetes: config.Kubernetes,
+		reconciler: reconciler,
+		logger:     config.Logger,
+
+		resources:            config.Resources,
+		namespace:            config.Namespace,
+		resyncInterval:       config.ResyncInterval,
+		retryInterval:        config.RetryInterval,
+		minReconcileInterval: config.MinReconcileInterval,
+		dryRun:               config.DryRun,
+
+		objects: map[string]map[string]kube.Object{},
+		synced:  map[string]bool{},
+	}
+
+	return c, nil
+}
+
+// Run watches the objects and reconciles the checks when objects change, at
+// most once per minimum reconcile interval, and every resync interval, until
+// ctx is done.
+func (c *Controller) Run(ctx context.Context) {
+	changed := make(chan struct{}, 1)
+
+	var watchers sync.WaitGroup
+	for _, resource := range c.resources {
+		watchers.Add(1)
+		go func(resource kube.Resource) {
+			defer watchers.Done()
+			c.watch(ctx, resource, changed)
+		}(resource)
+	}
+	defer watchers.Wait()
+
+	ticker := time.NewTicker(c.resyncInterval)
+	defer ticker.Stop()
+
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-ticker.C:
+			c.reconciler.Forget()
+		}
+
+		if !c.hasSynced() {
+			continue
+		}
+
+		// Changes made while waiting are reconciled at once, as changed
+		// holds a single signal.
+		if wait := c.minReconcileInterval - time.Since(last); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			select {
+			case <-changed:
+			default:
+			}
+		}
+		last = time.Now()
+
+		if err := c.Reconcile(ctx); err != nil && ctx.Err() == nil {
+			level.Error(c.logger).Log("msg", "failed to reconcile checks", "err", err)
+		}
+	}
+}
+
+// watch lists and watches the objects of the resource, keeping the objects
+// up to date, and signals changes on changed.
+func (c *Controller) watch(ctx context.Context, resource kube.Resource, changed chan<- struct{}) {
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+
+	for {
+		err := c.listAndWatch(ctx, resource, notify)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			level.Error(c.logger).Log("msg", "failed to watch objects", "resource", resource.Plural, "err", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.retryInterval):
+			}
+		}
+	}
+}
+
+func (c *Controller) listAndWatch(ctx context.Context, resource kube.Resource, notify func()) error {
+	resourceVersion, err := c.list(ctx, resource)
+	if err != nil {
+		return err
+	}
+	notify()
+
+	events, err := c.kubernetes.Watch(ctx, resource, c.namespace, resourceVersion)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var event kube.Event
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok = <-events:
+		}
+		if !ok {
+			return nil
+		}
+
+		c.mu.Lock()
+		switch event.Type {
+		case kube.Added, kube.Modified:
+			c.objects[resource.Kind][event.Object.Key()] = event.Object
+		case kube.Deleted:
+			delete(c.objects[resource.Kind], event.Object.Key())
+		case kube.Error:
+			// The resource version is too old, the objects are listed again.
+			c.mu.Unlock()
+			return nil
+		}
+		c.mu.Unlock()
+
+		if event.Type != kube.Bookmark {
+			notify()
+		}
+	}
+}
+
+// list replaces the objects of the resource with the listed objects, and
+// returns the resource version to watch from.
+func (c *Controller) list(ctx context.Context, resource kube.Resource) (string, error) {
+	list, err := c.kubernetes.List(ctx, resource, c.namespace)
+	if err != nil {
+		return "", err
+	}
+
+	objects := map[string]kube.Object{}
+	for _, o := range list.Items {
+		objects[o.Key()] = o
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.objects[resource.Kind] = objects
+	c.synced[resource.Kind] = true
+
+	return list.Metadata.ResourceVersion, nil
+}
+
+func (c *Controller) hasSynced() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, resource := range c.resources {
+		if !c.synced[resource.Kind] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Reconcile creates, updates and deletes the owned checks to match the
+// annotations of the objects. The checks of objects with invalid annotations
+// are left unchanged.
+func (c *Controller) Reconcile(ctx context.Context) error {
+	f := &manifest.File{}
+	// keep are the names of checks which must not be deleted, because the
+	// annotations of their object are invalid.
+	keep := map[string]bool{}
+	owners := map[string]string{}
+
+	for _, resource := range c.resources {
+		for _, o := range c.snapshot(resource) {
+			if !enabled(o) {
+				continue
+			}
+
+			name := checkName(resource, o)
+			owner := resource.Kind + " " + o.Key()
+			if other, ok := owners[name]; ok {
+				level.Warn(c.logger).Log("msg", "check name already used by another object, ignoring the object", "check", name, "object", owner, "owner", other)
+				continue
+			}
+			owners[name] = owner
+
+			check, err := checkFor(resource, o)
+			if err != nil {
+				level.Warn(c.logger).Log("msg", "invalid check annotations, leaving the check unchanged", "object", owner, "err", err)
+				keep[name] = true
+				continue
+			}
+			f.Checks = append(f.Checks, check)
+		}
+	}
+
+	plan, err := c.reconciler.Plan(ctx, f)
+	if err != nil {
+		return err
+	}
+	for _, name := range plan.Unadopted {
+		level.Warn(c.logger).Log("msg", "check name already used by a check not owned by the controller, ignoring the object", "check", name, "object", owners[name])
+	}
+
+	changes := plan.Changes[:0]
+	for _, change := range plan.Changes {
+		if change.Action == manifest.Delete && keep[change.Name] {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	plan.Changes = changes
+
+	if c.dryRun {
+		for _, change := range plan.Changes {
+			level.Info(c.logger).Log("msg", "would apply change", "action", change.Action, "check", change.Name)
+		}
+		return nil
+	}
+
+	return c.reconciler.Apply(ctx, plan)
+}
+
+// snapshot returns the objects of the resource, sorted by key.
+func (c *Controller) snapshot(resource kube.Resource) []kube.Object {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var objects []kube.Object
+	for _, o := range c.objects[resource.Kind] {
+		objects = append(objects, o)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key() < objects[j].Key() })
+
+	return objects
+}
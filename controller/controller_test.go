@@ -0,0 +1,368 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom/fakepingdomtest"
+	"github.com/giantswarm/prometheus-pingdom-exporter/kube"
+)
+
+func ingress(namespace, name string, annotations map[string]string, hosts ...string) kube.Object {
+	spec := map[string]interface{}{}
+	var rules []interface{}
+	for _, host := range hosts {
+		rules = append(rules, map[string]interface{}{"host": host})
+	}
+	spec["rules"] = rules
+	spec["tls"] = []interface{}{map[string]interface{}{"hosts": hosts[:1]}}
+	raw, _ := json.Marshal(spec)
+
+	return kube.Object{
+		Metadata: kube.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations},
+		Spec:     raw,
+	}
+}
+
+func newTestController(t *testing.T, k kube.Interface) (*Controller, *fakepingdomtest.Server) {
+	fake := fakepingdomtest.NewServer(t, fakepingdom.DefaultFixtures())
+
+	c := DefaultConfig()
+	c.Kubernetes = k
+	c.Pingdom = fake.Client
+	c.ResyncInterval = time.Hour
+	c.RetryInterval = 10 * time.Millisecond
+	c.MinReconcileInterval = 0
+	ctrl, err := New(c)
+	if err != nil {
+		fake.Close()
+		t.Fatal(err)
+	}
+
+	return ctrl, fake
+}
+
+// reads returns the number of checks read by ID by the fake API so far.
+func reads(fake *fakepingdomtest.Server) int {
+	n := 0
+	for _, r := range fake.Requests() {
+		if r.Method == "GET" && strings.HasPrefix(r.Path, "/api/2.0/checks/") {
+			n++
+		}
+	}
+
+	return n
+}
+
+// listAll lists the objects of every resource, like Run does before watching
+// them.
+func listAll(t *testing.T, ctrl *Controller) {
+	for _, resource := range ctrl.resources {
+		if _, err := ctrl.list(context.Background(), resource); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// ownedChecks returns the checks owned by the controller, by name.
+func ownedChecks(t *testing.T, api *exporter.Client) map[string]*pingdom.CheckResponse {
+	checks, err := api.ListChecks(context.Background(), map[string]string{"tags": DefaultManagedTag})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	owned := map[string]*pingdom.CheckResponse{}
+	for _, check := range checks {
+		details, err := api.ReadCheck(context.Background(), check.ID)
+		if err != nil {
+			// The check was deleted since it was listed.
+			continue
+		}
+		owned[check.Name] = details
+	}
+
+	return owned
+}
+
+func TestReconcile(t *testing.T) {
+	k := kube.NewFake()
+	ctrl, fake := newTestController(t, k)
+	defer fake.Close()
+	api := fake.Client
+
+	k.Apply(kube.Ingresses, ingress("shop", "web", map[string]string{
+		AnnotationEnabled:    "true",
+		AnnotationPath:       "/healthz",
+		AnnotationResolution: "1",
+		AnnotationContacts:   "2001, 2002",
+		AnnotationTags:       "customer,shop",
+	}, "shop.example.com", "www.shop.example.com"))
+	k.Apply(kube.Ingresses, ingress("shop", "admin", nil, "admin.example.com"))
+
+	ctx := context.Background()
+	listAll(t, ctrl)
+	if err := ctrl.Reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	owned := ownedChecks(t, api)
+	if len(owned) != 1 {
+		t.Fatalf("expected 1 owned check, got %d", len(owned))
+	}
+	check := owned["ingress/shop/web"]
+	if check == nil {
+		t.Fatalf("expected a check named ingress/shop/web, got %v", owned)
+	}
+	if check.Hostname != "shop.example.com" || check.Resolution != 1 {
+		t.Errorf("unexpected check %+v", check)
+	}
+	if check.Type.HTTP == nil || check.Type.HTTP.Url != "/healthz" || !check.Type.HTTP.Encryption {
+		t.Errorf("unexpected HTTP settings %+v", check.Type.HTTP)
+	}
+	if !reflect.DeepEqual(check.ContactIds, []int{2001, 2002}) {
+		t.Errorf("unexpected contacts %v", check.ContactIds)
+	}
+
+	// Invalid annotations leave the check unchanged.
+	k.Apply(kube.Ingresses, ingress("shop", "web", map[string]string{
+		AnnotationEnabled:    "true",
+		AnnotationResolution: "2",
+	}, "shop.example.com"))
+	listAll(t, ctrl)
+	if err := ctrl.Reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if check := ownedChecks(t, api)["ingress/shop/web"]; check == nil || check.Resolution != 1 {
+		t.Errorf("expected the check to be unchanged, got %+v", check)
+	}
+
+	// Deleting the ingress deletes the check, but not the checks not owned.
+	k.Delete(kube.Ingresses, "shop", "web")
+	listAll(t, ctrl)
+	if err := ctrl.Reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if owned := ownedChecks(t, api); len(owned) != 0 {
+		t.Errorf("expected no owned checks, got %v", owned)
+	}
+	checks, _ := api.ListChecks(ctx, nil)
+	if len(checks) != len(fakepingdom.DefaultFixtures().Checks) {
+		t.Errorf("expected the other checks to be kept, got %d checks", len(checks))
+	}
+}
+
+func TestReconcileDoesNotAdopt(t *testing.T) {
+	k := kube.NewFake()
+	ctrl, fake := newTestController(t, k)
+	defer fake.Close()
+	api := fake.Client
+
+	// Website is a check created by hand.
+	k.Apply(kube.Ingresses, ingress("shop", "web", map[string]string{AnnotationEnabled: "true", AnnotationName: "Website"}, "shop.example.com"))
+	k.Apply(kube.Ingresses, ingress("shop", "admin", map[string]string{AnnotationEnabled: "true"}, "admin.example.com"))
+
+	ctx := context.Background()
+	listAll(t, ctrl)
+	for i := 0; i < 2; i++ {
+		if err := ctrl.Reconcile(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	owned := ownedChecks(t, api)
+	if len(owned) != 1 || owned["ingress/shop/admin"] == nil {
+		t.Errorf("expected only the admin check to be owned, got %v", owned)
+	}
+	check, err := api.ReadCheck(ctx, 1001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Hostname != "www.example.com" || !reflect.DeepEqual(exporter.WithoutPauseTags(*check), []string{"production", "team-web"}) {
+		t.Errorf("expected Website to be left alone, got %+v", check)
+	}
+	for _, r := range fake.Requests() {
+		if r.Method != "GET" && r.Method != "POST" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Path)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	k := kube.NewFake()
+	ctrl, fake := newTestController(t, k)
+	defer fake.Close()
+	api := fake.Client
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		ctrl.Run(ctx)
+		close(stopped)
+	}()
+
+	waitFor := func(condition func(map[string]*pingdom.CheckResponse) bool) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !condition(ownedChecks(t, api)) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for the checks, got %v", ownedChecks(t, api))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	k.Apply(kube.Ingresses, ingress("default", "web", map[string]string{AnnotationEnabled: "true"}, "web.example.com"))
+	waitFor(func(owned map[string]*pingdom.CheckResponse) bool { return owned["ingress/default/web"] != nil })
+
+	// Changes are picked up after the watch ends.
+	k.CloseWatches()
+	k.Apply(kube.Ingresses, ingress("default", "web", map[string]string{AnnotationEnabled: "true", AnnotationName: "Web"}, "web.example.com"))
+	waitFor(func(owned map[string]*pingdom.CheckResponse) bool {
+		return owned["Web"] != nil && owned["ingress/default/web"] == nil
+	})
+
+	k.Apply(kube.Ingresses, ingress("default", "web", map[string]string{AnnotationEnabled: "false"}, "web.example.com"))
+	waitFor(func(owned map[string]*pingdom.CheckResponse) bool { return len(owned) == 0 })
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("controller did not stop")
+	}
+}
+
+func TestReconcileSkipsUnchanged(t *testing.T) {
+	k := kube.NewFake()
+	ctrl, fake := newTestController(t, k)
+	defer fake.Close()
+
+	k.Apply(kube.Ingresses, ingress("default", "web", map[string]string{AnnotationEnabled: "true"}, "web.example.com"))
+	listAll(t, ctrl)
+
+	ctx := context.Background()
+	reconcile := func() {
+		if err := ctrl.Reconcile(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The created check is read once to find it up to date, then not again.
+	reconcile()
+	reconcile()
+	before := reads(fake)
+	reconcile()
+	if n := reads(fake) - before; n != 0 {
+		t.Errorf("expected no check read when nothing changed, got %d", n)
+	}
+
+	// A change of the listed settings is read and undone.
+	owned := ownedChecks(t, fake.Client)
+	id := owned["ingress/default/web"].ID
+	fake.SetCheckStatus(id, "paused")
+	before = reads(fake)
+	reconcile()
+	if n := reads(fake) - before; n != 1 {
+		t.Errorf("expected the changed check to be read, got %d reads", n)
+	}
+	if check := ownedChecks(t, fake.Client)["ingress/default/web"]; check == nil || exporter.IsPaused(*check) {
+		t.Errorf("expected the check to be resumed, got %+v", check)
+	}
+
+	// A change of the annotations is read and applied.
+	reconcile()
+	k.Apply(kube.Ingresses, ingress("default", "web", map[string]string{AnnotationEnabled: "true", AnnotationPath: "/healthz"}, "web.example.com"))
+	listAll(t, ctrl)
+	before = reads(fake)
+	reconcile()
+	if n := reads(fake) - before; n != 1 {
+		t.Errorf("expected the check to be read, got %d reads", n)
+	}
+	if check := ownedChecks(t, fake.Client)["ingress/default/web"]; check == nil || check.Type.HTTP.Url != "/healthz" {
+		t.Errorf("expected the path to be updated, got %+v", check)
+	}
+
+	// Forget makes the next reconciliation read the checks again.
+	reconcile()
+	ctrl.reconciler.Forget()
+	before = reads(fake)
+	reconcile()
+	if n := reads(fake) - before; n != 1 {
+		t.Errorf("expected the check to be read after Forget, got %d reads", n)
+	}
+}
+
+func TestRunRateLimit(t *testing.T) {
+	k := kube.NewFake()
+	ctrl, fake := newTestController(t, k)
+	defer fake.Close()
+	ctrl.minReconcileInterval = time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ctrl.Run(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	k.Apply(kube.Ingresses, ingress("default", "web", map[string]string{AnnotationEnabled: "true"}, "web.example.com"))
+	for ownedChecks(t, fake.Client)["ingress/default/web"] == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the check")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	reconciled := time.Now()
+
+	k.Apply(kube.Ingresses, ingress("default", "web", map[string]string{AnnotationEnabled: "true", AnnotationName: "Web"}, "web.example.com"))
+	for ownedChecks(t, fake.Client)["Web"] == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the check to be renamed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The check was created by the previous reconciliation, before it was
+	// seen.
+	if elapsed := time.Since(reconciled); elapsed < 900*time.Millisecond {
+		t.Errorf("expected the change to wait for the minimum interval, reconciled after %v", elapsed)
+	}
+}
+
+func TestCheckFor(t *testing.T) {
+	route := kube.Object{
+		Metadata: kube.ObjectMeta{Namespace: "default", Name: "api", Annotations: map[string]string{
+			AnnotationEncryption:    "true",
+			AnnotationShouldContain: "OK",
+		}},
+		Spec: json.RawMessage(`{"hostnames": ["api.example.com"]}`),
+	}
+	check, err := checkFor(kube.HTTPRoutes, route)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Name != "httproute/default/api" || check.Hostname != "api.example.com" || !check.HTTP.Encryption || check.HTTP.ShouldContain != "OK" {
+		t.Errorf("unexpected check %+v", check)
+	}
+
+	service := kube.Object{
+		Metadata: kube.ObjectMeta{Namespace: "default", Name: "lb"},
+		Status:   json.RawMessage(`{"loadBalancer": {"ingress": [{"ip": "192.0.2.1"}]}}`),
+	}
+	check, err = checkFor(kube.Services, service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Hostname != "192.0.2.1" || check.HTTP.Encryption {
+		t.Errorf("unexpected check %+v", check)
+	}
+
+	service.Status = nil
+	if _, err := checkFor(kube.Services, service); err == nil {
+		t.Errorf("expected an error for a service without host")
+	}
+}
@@ -0,0 +1,305 @@
+// Package dashboard generates a Grafana dashboard for the metrics of the
+// exporter.
+package dashboard
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+// Dashboard is a Grafana dashboard, in the JSON model of Grafana 5.
+type Dashboard struct {
+	UID           string     `json:"uid"`
+	Title         string     `json:"title"`
+	Tags          []string   `json:"tags"`
+	Timezone      string     `json:"timezone"`
+	Editable      bool       `json:"editable"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Refresh       string     `json:"refresh"`
+	Time          TimeRange  `json:"time"`
+	Templating    Templating `json:"templating"`
+	Panels        []*Panel   `json:"panels"`
+}
+
+// TimeRange is the default time range of the dashboard.
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Templating holds the variables of the dashboard.
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+// Variable is a dashboard variable.
+type Variable struct {
+	Name       string `json:"name"`
+	Label      string `json:"label"`
+	Type       string `json:"type"`
+	Query      string `json:"query"`
+	Datasource string `json:"datasource,omitempty"`
+	Refresh    int    `json:"refresh"`
+	Multi      bool   `json:"multi"`
+	IncludeAll bool   `json:"includeAll"`
+	Sort       int    `json:"sort"`
+}
+
+// Panel is a panel, or a row of panels.
+type Panel struct {
+	ID         int      `json:"id"`
+	Type       string   `json:"type"`
+	Title      string   `json:"title"`
+	GridPos    GridPos  `json:"gridPos"`
+	Datasource string   `json:"datasource,omitempty"`
+	Targets    []Target `json:"targets,omitempty"`
+
+	// Rows.
+	Collapsed bool     `json:"collapsed,omitempty"`
+	Repeat    string   `json:"repeat,omitempty"`
+	Panels    []*Panel `json:"panels,omitempty"`
+
+	// Graphs.
+	Lines       bool   `json:"lines,omitempty"`
+	Bars        bool   `json:"bars,omitempty"`
+	Stack       bool   `json:"stack,omitempty"`
+	Fill        int    `json:"fill,omitempty"`
+	SteppedLine bool   `json:"steppedLine,omitempty"`
+	YAxes       []Axis `json:"yaxes,omitempty"`
+
+	// Single stats.
+	ValueName  string   `json:"valueName,omitempty"`
+	Format     string   `json:"format,omitempty"`
+	Colors     []string `json:"colors,omitempty"`
+	Thresholds string   `json:"thresholds,omitempty"`
+	ColorValue bool     `json:"colorValue,omitempty"`
+
+	// Tables.
+	Transform string `json:"transform,omitempty"`
+}
+
+// GridPos is the position of a panel, in a 24 columns wide grid.
+type GridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// Target is a Prometheus query of a panel.
+type Target struct {
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+	Format       string `json:"format,omitempty"`
+	Instant      bool   `json:"instant,omitempty"`
+	RefID        string `json:"refId"`
+}
+
+// Axis is a y axis of a graph.
+type Axis struct {
+	Format  string `json:"format"`
+	Show    bool   `json:"show"`
+	Min     *int   `json:"min,omitempty"`
+	LogBase int    `json:"logBase"`
+}
+
+// Config represents the configuration used to generate a dashboard.
+type Config struct {
+	Title string
+	UID   string
+	// Checks are grouped into a row per tag if set. Checks without tags are
+	// left out of these rows.
+	Checks []pingdom.CheckResponse
+}
+
+// DefaultConfig provides a default configuration to generate a dashboard.
+func DefaultConfig() Config {
+	return Config{
+		Title:  "Pingdom",
+		UID:    "pingdom",
+		Checks: nil,
+	}
+}
+
+// layout places panels in rows of the grid.
+type layout struct {
+	panels []*Panel
+	nextID int
+	x, y   int
+	rowH   int
+}
+
+func (l *layout) row(title string) *Panel {
+	l.newLine()
+
+	row := &Panel{Type: "row", Title: title, GridPos: GridPos{X: 0, Y: l.y, W: 24, H: 1}}
+	l.add(row)
+	l.y++
+
+	return row
+}
+
+func (l *layout) panel(p *Panel, w, h int) *Panel {
+	if l.x+w > 24 {
+		l.newLine()
+	}
+
+	p.GridPos = GridPos{X: l.x, Y: l.y, W: w, H: h}
+	if p.Type != "row" {
+		p.Datasource = "$datasource"
+	}
+	for i := range p.Targets {
+		p.Targets[i].RefID = string('A' + rune(i))
+	}
+
+	l.add(p)
+	l.x += w
+	if h > l.rowH {
+		l.rowH = h
+	}
+
+	return p
+}
+
+func (l *layout) add(p *Panel) {
+	l.nextID++
+	p.ID = l.nextID
+	l.panels = append(l.panels, p)
+}
+
+func (l *layout) newLine() {
+	if l.x > 0 {
+		l.y += l.rowH
+	}
+	l.x, l.rowH = 0, 0
+}
+
+var zero = 0
+
+func graph(title, format string, stepped bool, targets ...Target) *Panel {
+	return &Panel{
+		Type:        "graph",
+		Title:       title,
+		Targets:     targets,
+		Lines:       true,
+		Fill:        1,
+		SteppedLine: stepped,
+		YAxes: []Axis{
+			{Format: format, Show: true, Min: &zero, LogBase: 1},
+			{Format: "short", Show: false, LogBase: 1},
+		},
+	}
+}
+
+func singleStat(title, expr, thresholds string, colors ...string) *Panel {
+	return &Panel{
+		Type:       "singlestat",
+		Title:      title,
+		Targets:    []Target{{Expr: expr, Instant: true}},
+		ValueName:  "current",
+		Format:     "none",
+		Thresholds: thresholds,
+		Colors:     colors,
+		ColorValue: thresholds != "",
+	}
+}
+
+func table(title string, targets ...Target) *Panel {
+	for i := range targets {
+		targets[i].Format = "table"
+		targets[i].Instant = true
+	}
+
+	return &Panel{
+		Type:      "table",
+		Title:     title,
+		Targets:   targets,
+		Transform: "table",
+	}
+}
+
+const (
+	green  = "#299c46"
+	orange = "rgba(237, 129, 40, 0.89)"
+	red    = "#d44a3a"
+)
+
+// Generate returns the dashboard: an overview of all checks by status, a
+// row per check with its response time, status and uptime, and the health
+// of the exporter.
+func Generate(config Config) *Dashboard {
+	l := &layout{}
+
+	l.row("Overview")
+	l.panel(singleStat("Up", `count(pingdom_check_state{state="up"} == 1) or vector(0)`, ""), 6, 4)
+	l.panel(singleStat("Down", `count(pingdom_check_state{state=~"down|unconfirmed_down"} == 1) or vector(0)`, "1,1", green, orange, red), 6, 4)
+	l.panel(singleStat("Paused", `count(pingdom_check_state{state="paused"} == 1) or vector(0)`, ""), 6, 4)
+	l.panel(singleStat("Unknown", `count(pingdom_check_state{state="unknown"} == 1) or vector(0)`, "1,1", green, orange, orange), 6, 4)
+	l.panel(table("Checks by status", Target{Expr: `pingdom_check_state == 1`}), 24, 10)
+	l.panel(graph("Checks by status", "short", true, Target{Expr: `count by (state) (pingdom_check_state == 1)`, LegendFormat: "{{state}}"}), 24, 8)
+
+	for _, tag := range tags(config.Checks) {
+		// strconv.Quote escapes the backslashes of the regular expression for
+		// the PromQL string.
+		tagSelector := "tags=~" + strconv.Quote("(.*,)?"+regexp.QuoteMeta(tag)+"(,.*)?")
+
+		l.row("Tag " + tag)
+		l.panel(graph("Status", "short", true, Target{Expr: fmt.Sprintf(`pingdom_check_status{%s}`, tagSelector), LegendFormat: "{{name}}"}), 12, 8)
+		l.panel(graph("Response time", "ms", false, Target{Expr: fmt.Sprintf(`pingdom_check_response_time{%s}`, tagSelector), LegendFormat: "{{name}}"}), 12, 8)
+	}
+
+	row := l.row("Check $check")
+	row.Repeat = "check"
+	l.panel(graph("Response time", "ms", false, Target{Expr: `pingdom_check_response_time{name=~"$check"}`, LegendFormat: "{{name}}"}), 12, 8)
+	l.panel(graph("Status", "short", true, Target{Expr: `pingdom_check_state{name=~"$check"} == 1`, LegendFormat: "{{state}}"}), 12, 8)
+	l.panel(singleStat("Uptime (last day)", `pingdom_check_performance_uptime_seconds{name=~"$check",resolution="day"} / (pingdom_check_performance_uptime_seconds{name=~"$check",resolution="day"} + pingdom_check_performance_downtime_seconds{name=~"$check",resolution="day"})`, "0.99,0.999", red, orange, green), 6, 6).Format = "percentunit"
+	l.panel(singleStat("Uptime (last week)", `pingdom_check_performance_uptime_seconds{name=~"$check",resolution="week"} / (pingdom_check_performance_uptime_seconds{name=~"$check",resolution="week"} + pingdom_check_performance_downtime_seconds{name=~"$check",resolution="week"})`, "0.99,0.999", red, orange, green), 6, 6).Format = "percentunit"
+	outages := l.panel(graph("Outages", "short", true, Target{Expr: `pingdom_check_state{name=~"$check",state=~"down|unconfirmed_down"}`, LegendFormat: "{{state}}"}), 12, 6)
+	outages.Bars, outages.Lines = true, false
+
+	l.row("Exporter")
+	l.panel(graph("Pingdom API polls", "short", true, Target{Expr: `pingdom_up`, LegendFormat: "{{instance}}"}), 12, 6)
+	l.panel(graph("Unknown statuses", "short", false, Target{Expr: `sum by (status) (rate(pingdom_check_unknown_status_total[5m]))`, LegendFormat: "{{status}}"}), 12, 6)
+
+	return &Dashboard{
+		UID:           config.UID,
+		Title:         config.Title,
+		Tags:          []string{"pingdom"},
+		Timezone:      "browser",
+		Editable:      true,
+		SchemaVersion: 16,
+		Refresh:       "1m",
+		Time:          TimeRange{From: "now-24h", To: "now"},
+		Templating: Templating{
+			List: []Variable{
+				{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+				{Name: "check", Label: "Check", Type: "query", Query: "label_values(pingdom_check_status, name)", Datasource: "$datasource", Refresh: 2, Multi: true, IncludeAll: true, Sort: 1},
+			},
+		},
+		Panels: l.panels,
+	}
+}
+
+// tags returns the tags of the checks, without the pause tags, sorted.
+func tags(checks []pingdom.CheckResponse) []string {
+	seen := map[string]bool{}
+	var tags []string
+
+	for _, check := range checks {
+		for _, tag := range exporter.WithoutPauseTags(check) {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+
+	return tags
+}
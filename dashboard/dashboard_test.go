@@ -0,0 +1,75 @@
+package dashboard
+
+import (
+	"testing"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+func TestGenerate(t *testing.T) {
+	c := DefaultConfig()
+	c.Checks = []pingdom.CheckResponse{
+		{ID: 1, Tags: []pingdom.CheckResponseTag{{Name: "team-web"}, {Name: "production"}}},
+		{ID: 2, Tags: []pingdom.CheckResponseTag{{Name: "production"}, {Name: "v1.0"}}},
+	}
+
+	d := Generate(c)
+
+	ids := map[int]bool{}
+	var rows []string
+	var exprs []string
+	for _, p := range d.Panels {
+		if ids[p.ID] {
+			t.Errorf("panel ID %d is used twice", p.ID)
+		}
+		ids[p.ID] = true
+
+		if p.GridPos.X+p.GridPos.W > 24 {
+			t.Errorf("panel %q overflows the grid: %+v", p.Title, p.GridPos)
+		}
+		if p.Type == "row" {
+			rows = append(rows, p.Title)
+		} else if p.Datasource != "$datasource" {
+			t.Errorf("panel %q does not use the datasource variable", p.Title)
+		}
+		for _, target := range p.Targets {
+			exprs = append(exprs, target.Expr)
+		}
+	}
+
+	// Regular expression escapes are escaped again in PromQL strings.
+	if want := `pingdom_check_status{tags=~"(.*,)?v1\\.0(,.*)?"}`; !containsString(exprs, want) {
+		t.Errorf("no panel queries %s, got %v", want, exprs)
+	}
+
+	want := []string{"Overview", "Tag production", "Tag team-web", "Tag v1.0", "Check $check", "Exporter"}
+	if len(rows) != len(want) {
+		t.Fatalf("got rows %v, want %v", rows, want)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("got rows %v, want %v", rows, want)
+			break
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestTagsWithoutPauseTags(t *testing.T) {
+	checks := []pingdom.CheckResponse{
+		{ID: 1, Paused: true, Tags: []pingdom.CheckResponseTag{{Name: "production"}, {Name: "paused-until-201805041330"}, {Name: "paused-by-jane"}}},
+	}
+
+	if got := tags(checks); len(got) != 1 || got[0] != "production" {
+		t.Errorf("expected only the production tag, got %v", got)
+	}
+}
@@ -0,0 +1,384 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// PingdomAPI is the part of the Pingdom API used by the exporter. Client
+// implements it on top of a pingdom.Client.
+type PingdomAPI interface {
+	// ListChecks returns the checks of the account, like
+	// pingdom.CheckService.List.
+	ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error)
+}
+
+// ClientConfig represents the configuration used to create a Client.
+type ClientConfig struct {
+	// Pingdom provides the base URL and credentials of the API.
+	Pingdom *pingdom.Client
+	// HTTPClient sends the requests, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	Logger     log.Logger
+}
+
+// DefaultClientConfig provides a default configuration to create a Client.
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		Pingdom:    nil,
+		HTTPClient: http.DefaultClient,
+		Logger:     log.NewNopLogger(),
+	}
+}
+
+// Client implements PingdomAPI. Unlike pingdom.Client, its requests can be
+// cancelled, are sent with a configurable HTTP client, and are logged.
+type Client struct {
+	pingdom    *pingdom.Client
+	httpClient *http.Client
+	logger     log.Logger
+}
+
+// NewClient creates a new configured Client.
+func NewClient(config ClientConfig) (*Client, error) {
+	if config.Pingdom == nil {
+		return nil, fmt.Errorf("config.Pingdom must not be empty")
+	}
+	if config.HTTPClient == nil {
+		return nil, fmt.Errorf("config.HTTPClient must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, fmt.Errorf("config.Logger must not be empty")
+	}
+
+	c := &Client{
+		pingdom:    config.Pingdom,
+		httpClient: config.HTTPClient,
+		logger:     log.With(config.Logger, "account", AccountName(config.Pingdom)),
+	}
+
+	return c, nil
+}
+
+// listChecksResponse is the body of a /api/2.0/checks response.
+type listChecksResponse struct {
+	Checks []pingdom.CheckResponse `json:"checks"`
+}
+
+// errorResponse is the body of a Pingdom API error response.
+type errorResponse struct {
+	Error *pingdom.PingdomError `json:"error"`
+}
+
+// ListChecks returns the checks of the account.
+func (c *Client) ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error) {
+	m := &listChecksResponse{}
+	if err := c.Get(ctx, "/api/2.0/checks", params, m); err != nil {
+		return nil, err
+	}
+
+	return m.Checks, nil
+}
+
+// listContactsResponse is the body of a /api/2.0/notification_contacts
+// response.
+type listContactsResponse struct {
+	Contacts []pingdom.ContactResponse `json:"contacts"`
+}
+
+// ListContacts returns the notification contacts of the account.
+func (c *Client) ListContacts(ctx context.Context) ([]pingdom.ContactResponse, error) {
+	m := &listContactsResponse{}
+	if err := c.Get(ctx, "/api/2.0/notification_contacts", nil, m); err != nil {
+		return nil, err
+	}
+
+	return m.Contacts, nil
+}
+
+// contactResponse is the body of a /api/2.0/notification_contacts/{id}
+// response.
+type contactResponse struct {
+	Contact *pingdom.ContactResponse `json:"contact"`
+}
+
+// CreateContact creates a notification contact, like ContactService.Create,
+// and returns the new contact.
+func (c *Client) CreateContact(ctx context.Context, contact pingdom.Contact) (*pingdom.ContactResponse, error) {
+	if err := contact.Valid(); err != nil {
+		return nil, err
+	}
+
+	// The contact params do not include whether the contact is paused.
+	params := contact.PostParams()
+	if contact.Paused {
+		params["paused"] = "true"
+	}
+
+	m := &contactResponse{}
+	if err := c.request(ctx, "POST", "/api/2.0/notification_contacts", params, m); err != nil {
+		return nil, err
+	}
+	if m.Contact == nil {
+		return nil, fmt.Errorf("no contact in response")
+	}
+
+	return m.Contact, nil
+}
+
+// UpdateContact replaces the settings of a notification contact, like
+// ContactService.Update.
+func (c *Client) UpdateContact(ctx context.Context, id int, contact pingdom.Contact) error {
+	if err := contact.Valid(); err != nil {
+		return err
+	}
+
+	params := contact.PutParams()
+	params["paused"] = strconv.FormatBool(contact.Paused)
+
+	return c.request(ctx, "PUT", fmt.Sprintf("/api/2.0/notification_contacts/%d", id), params, &pingdom.PingdomResponse{})
+}
+
+// DeleteContact deletes a notification contact, like ContactService.Delete.
+func (c *Client) DeleteContact(ctx context.Context, id int) error {
+	return c.request(ctx, "DELETE", fmt.Sprintf("/api/2.0/notification_contacts/%d", id), nil, &pingdom.PingdomResponse{})
+}
+
+// checkResponse is the body of a /api/2.0/checks/{checkid} response.
+type checkResponse struct {
+	Check *pingdom.CheckResponse `json:"check"`
+}
+
+// ReadCheck returns the details of a check, including the settings of its
+// type.
+func (c *Client) ReadCheck(ctx context.Context, id int) (*pingdom.CheckResponse, error) {
+	m := &checkResponse{}
+	if err := c.Get(ctx, fmt.Sprintf("/api/2.0/checks/%d", id), nil, m); err != nil {
+		return nil, err
+	}
+	if m.Check == nil {
+		return nil, fmt.Errorf("no check in response")
+	}
+
+	return m.Check, nil
+}
+
+// CreateCheck creates a check, like CheckService.Create, and returns the ID
+// and name of the new check.
+func (c *Client) CreateCheck(ctx context.Context, check pingdom.Check) (*pingdom.CheckResponse, error) {
+	if err := check.Valid(); err != nil {
+		return nil, err
+	}
+
+	m := &checkResponse{}
+	if err := c.request(ctx, "POST", "/api/2.0/checks", check.PostParams(), m); err != nil {
+		return nil, err
+	}
+	if m.Check == nil {
+		return nil, fmt.Errorf("no check in response")
+	}
+
+	return m.Check, nil
+}
+
+// UpdateCheck replaces the settings of a check, like CheckService.Update.
+func (c *Client) UpdateCheck(ctx context.Context, id int, check pingdom.Check) error {
+	if err := check.Valid(); err != nil {
+		return err
+	}
+
+	return c.request(ctx, "PUT", fmt.Sprintf("/api/2.0/checks/%d", id), check.PutParams(), &pingdom.PingdomResponse{})
+}
+
+// SetCheckContacts replaces the notification contacts of a check, leaving its
+// other settings unchanged.
+func (c *Client) SetCheckContacts(ctx context.Context, id int, contactIDs []int) error {
+	ids := make([]string, len(contactIDs))
+	for i, contactID := range contactIDs {
+		ids[i] = strconv.Itoa(contactID)
+	}
+
+	return c.request(ctx, "PUT", fmt.Sprintf("/api/2.0/checks/%d", id), map[string]string{"contactids": strings.Join(ids, ",")}, &pingdom.PingdomResponse{})
+}
+
+// DeleteCheck deletes a check, like CheckService.Delete.
+func (c *Client) DeleteCheck(ctx context.Context, id int) error {
+	return c.request(ctx, "DELETE", fmt.Sprintf("/api/2.0/checks/%d", id), nil, &pingdom.PingdomResponse{})
+}
+
+// Maintenance is a maintenance window, during which Pingdom does not alert
+// on the checks of the window.
+type Maintenance struct {
+	ID          int    `json:"id"`
+	Description string `json:"description"`
+	// From and To are the Unix times of the start and end of the window.
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+	// RecurrenceType is none, day, week or month.
+	RecurrenceType string            `json:"recurrencetype"`
+	Checks         MaintenanceChecks `json:"checks"`
+}
+
+// MaintenanceChecks are the checks of a maintenance window.
+type MaintenanceChecks struct {
+	Uptime []int `json:"uptime"`
+	TMS    []int `json:"tms"`
+}
+
+// params returns the parameters of a create or modify request of the
+// window.
+func (m Maintenance) params() map[string]string {
+	ids := make([]string, len(m.Checks.Uptime))
+	for i, id := range m.Checks.Uptime {
+		ids[i] = strconv.Itoa(id)
+	}
+
+	recurrenceType := m.RecurrenceType
+	if recurrenceType == "" {
+		recurrenceType = "none"
+	}
+
+	return map[string]string{
+		"description":    m.Description,
+		"from":           strconv.FormatInt(m.From, 10),
+		"to":             strconv.FormatInt(m.To, 10),
+		"recurrencetype": recurrenceType,
+		"uptimeids":      strings.Join(ids, ","),
+	}
+}
+
+// listMaintenanceResponse is the body of a /api/2.0/maintenance response.
+type listMaintenanceResponse struct {
+	Maintenance []Maintenance `json:"maintenance"`
+}
+
+// maintenanceResponse is the body of a /api/2.0/maintenance/{id} response.
+type maintenanceResponse struct {
+	Maintenance *Maintenance `json:"maintenance"`
+}
+
+// ListMaintenance returns the maintenance windows of the account.
+func (c *Client) ListMaintenance(ctx context.Context) ([]Maintenance, error) {
+	m := &listMaintenanceResponse{}
+	if err := c.Get(ctx, "/api/2.0/maintenance", nil, m); err != nil {
+		return nil, err
+	}
+
+	return m.Maintenance, nil
+}
+
+// CreateMaintenance creates a maintenance window, and returns its ID.
+func (c *Client) CreateMaintenance(ctx context.Context, window Maintenance) (int, error) {
+	m := &maintenanceResponse{}
+	if err := c.request(ctx, "POST", "/api/2.0/maintenance", window.params(), m); err != nil {
+		return 0, err
+	}
+	if m.Maintenance == nil {
+		return 0, fmt.Errorf("no maintenance window in response")
+	}
+
+	return m.Maintenance.ID, nil
+}
+
+// UpdateMaintenance replaces the settings of the maintenance window with the
+// ID of window.
+func (c *Client) UpdateMaintenance(ctx context.Context, window Maintenance) error {
+	return c.request(ctx, "PUT", fmt.Sprintf("/api/2.0/maintenance/%d", window.ID), window.params(), &pingdom.PingdomResponse{})
+}
+
+// DeleteMaintenance deletes a maintenance window.
+func (c *Client) DeleteMaintenance(ctx context.Context, id int) error {
+	return c.request(ctx, "DELETE", fmt.Sprintf("/api/2.0/maintenance/%d", id), nil, &pingdom.PingdomResponse{})
+}
+
+// Get requests a resource of the Pingdom API, and decodes the response into
+// v.
+func (c *Client) Get(ctx context.Context, rsc string, params map[string]string, v interface{}) error {
+	return c.request(ctx, "GET", rsc, params, v)
+}
+
+// request sends a request to the Pingdom API, and decodes the response into
+// v.
+func (c *Client) request(ctx context.Context, method, rsc string, params map[string]string, v interface{}) error {
+	req, err := c.pingdom.NewRequest(method, rsc, params)
+	if err != nil {
+		return err
+	}
+
+	return c.do(ctx, req, v)
+}
+
+// do sends the request and decodes the response into v, logging the call at
+// debug level. Only the method and path are logged, never the headers
+// carrying the credentials.
+func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) error {
+	start := time.Now()
+	code, err := c.send(req.WithContext(ctx), v)
+
+	keyvals := []interface{}{
+		"msg", "called Pingdom API",
+		"method", req.Method,
+		"endpoint", req.URL.Path,
+		"duration", time.Since(start),
+	}
+	if code != 0 {
+		keyvals = append(keyvals, "code", code)
+	}
+	if err != nil {
+		keyvals = append(keyvals, "err", err)
+	}
+	level.Debug(c.logger).Log(keyvals...)
+
+	return err
+}
+
+// send sends the request, and returns the response status code. Error
+// responses are returned as *pingdom.PingdomError, like the Pingdom client
+// does.
+func (c *Client) send(req *http.Request, v interface{}) (int, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		m := &errorResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(m); err != nil || m.Error == nil {
+			return resp.StatusCode, fmt.Errorf("unexpected response %s", resp.Status)
+		}
+
+		return resp.StatusCode, m.Error
+	}
+
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// AccountName identifies the Pingdom account used by the client in logs.
+func AccountName(client *pingdom.Client) string {
+	if client.AccountEmail != "" {
+		return client.AccountEmail
+	}
+
+	return client.User
+}
+
+// IsAuthError returns true if the error is Pingdom rejecting the credentials
+// or API key used by the client.
+func IsAuthError(err error) bool {
+	pingdomErr, ok := err.(*pingdom.PingdomError)
+	if !ok {
+		return false
+	}
+
+	return pingdomErr.StatusCode == http.StatusUnauthorized || pingdomErr.StatusCode == http.StatusForbidden
+}
@@ -0,0 +1,382 @@
+// Package exporter exports the checks of a Pingdom account as Prometheus
+// metrics.
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// checkLabels are the labels of the per check metrics.
+var checkLabels = []string{"id", "name", "hostname", "resolution", "paused", "tags"}
+
+var (
+	upDesc = prometheus.NewDesc(
+		"pingdom_up",
+		"Whether the last pingdom scrape was successfull (1: up, 0: down)",
+		nil, nil,
+	)
+
+	checkStatusDesc = prometheus.NewDesc(
+		"pingdom_check_status",
+		"The current status of the check, as set with --status-value (default 0: up, 1: unconfirmed_down, 2: down, -1: paused, -2: unknown)",
+		checkLabels, nil,
+	)
+
+	checkStateDesc = prometheus.NewDesc(
+		"pingdom_check_state",
+		"Whether the check is in the given state (1: current state, 0: otherwise)",
+		append(append([]string{}, checkLabels...), "state"), nil,
+	)
+
+	checkResponseTimeDesc = prometheus.NewDesc(
+		"pingdom_check_response_time",
+		"The response time of last test in milliseconds",
+		checkLabels, nil,
+	)
+
+	checkPausedUntilDesc = prometheus.NewDesc(
+		"pingdom_check_paused_until_timestamp_seconds",
+		"The time until which a check paused with checks pause is paused, and who paused it",
+		append(append([]string{}, checkLabels...), "paused_by"), nil,
+	)
+)
+
+// Config represents the configuration used to create an Exporter.
+type Config struct {
+	API    PingdomAPI
+	Logger log.Logger
+
+	// StatusValues maps check statuses to the values of
+	// pingdom_check_status, see ParseStatusValues.
+	StatusValues map[string]float64
+}
+
+// DefaultConfig provides a default configuration to create an Exporter.
+func DefaultConfig() Config {
+	return Config{
+		API:          nil,
+		Logger:       log.NewNopLogger(),
+		StatusValues: DefaultStatusValues,
+	}
+}
+
+// Exporter polls the checks from Pingdom, and exports the results of the
+// last poll as a prometheus.Collector.
+type Exporter struct {
+	api          PingdomAPI
+	logger       log.Logger
+	statusValues map[string]float64
+
+	unknownStatus *prometheus.CounterVec
+	stateChanges  *prometheus.CounterVec
+
+	mu     sync.RWMutex
+	status Status
+	checks []pingdom.CheckResponse
+	// overrides are the statuses set by webhooks, by check ID.
+	overrides map[int]webhookOverride
+}
+
+// Status is the outcome of the last poll.
+type Status struct {
+	// Ready is true once a poll succeeded, and false again while Pingdom
+	// rejects the credentials.
+	Ready bool
+
+	Time     time.Time
+	Duration time.Duration
+	Checks   int
+	Err      error
+}
+
+// New creates a new configured Exporter.
+func New(config Config) (*Exporter, error) {
+	if config.API == nil {
+		return nil, fmt.Errorf("config.API must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, fmt.Errorf("config.Logger must not be empty")
+	}
+	if len(config.StatusValues) == 0 {
+		return nil, fmt.Errorf("config.StatusValues must not be empty")
+	}
+
+	e := &Exporter{
+		api:          config.API,
+		logger:       config.Logger,
+		statusValues: config.StatusValues,
+
+		unknownStatus: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pingdom_check_unknown_status_total",
+			Help: "The number of times a check reported a status without a configured value",
+		}, []string{"status"}),
+		stateChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pingdom_check_state_changes_total",
+			Help: "The number of times the status of a check changed, from polls or webhooks",
+		}, []string{"id", "name", "from", "to"}),
+
+		overrides: map[int]webhookOverride{},
+	}
+
+	return e, nil
+}
+
+// Run polls Pingdom every interval until the context is cancelled.
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	for {
+		e.Poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Poll fetches the checks from Pingdom once, and updates the exported
+// metrics. Polls cancelled through the context leave the metrics unchanged.
+func (e *Exporter) Poll(ctx context.Context) error {
+	params := map[string]string{
+		"include_tags": "true",
+	}
+
+	start := time.Now()
+	checks, err := e.api.ListChecks(ctx, params)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	duration := time.Since(start)
+
+	if err != nil {
+		level.Error(e.logger).Log("msg", "failed to get checks", "endpoint", "/api/2.0/checks", "duration", duration, "err", err)
+	} else {
+		level.Debug(e.logger).Log("msg", "updated checks", "checks", len(checks), "duration", duration)
+	}
+
+	for _, check := range checks {
+		if _, ok := e.statusValues[check.Status]; !ok {
+			level.Warn(e.logger).Log("msg", "check has a status without a configured value", "check_id", check.ID, "status", check.Status)
+			e.unknownStatus.WithLabelValues(check.Status).Inc()
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.status.Time = start
+	e.status.Duration = duration
+	e.status.Checks = len(checks)
+	e.status.Err = err
+
+	if err == nil {
+		e.status.Ready = true
+		e.updateChecks(checks)
+	} else if IsAuthError(err) {
+		e.status.Ready = false
+	}
+
+	return err
+}
+
+// updateChecks replaces the checks with the polled checks, keeping the
+// statuses set by webhooks after the last test of the checks, and counts the
+// state changes.
+func (e *Exporter) updateChecks(checks []pingdom.CheckResponse) {
+	previous := map[int]string{}
+	for _, check := range e.checks {
+		previous[check.ID] = check.Status
+	}
+
+	// The checks are copied, as their statuses may be changed.
+	checks = append([]pingdom.CheckResponse(nil), checks...)
+	overrides := map[int]webhookOverride{}
+	for i := range checks {
+		check := &checks[i]
+
+		if o, ok := e.overrides[check.ID]; ok && time.Unix(check.LastTestTime, 0).Before(o.time) {
+			check.Status = o.status
+			overrides[check.ID] = o
+		}
+		if status, ok := previous[check.ID]; ok {
+			e.countStateChange(pingdom.CheckResponse{ID: check.ID, Name: check.Name, Status: status}, check.Status)
+		}
+	}
+
+	e.checks = checks
+	e.overrides = overrides
+}
+
+// countStateChange counts the change of the status of the check, if it
+// differs.
+func (e *Exporter) countStateChange(check pingdom.CheckResponse, status string) {
+	if check.Status == status {
+		return
+	}
+
+	e.stateChanges.WithLabelValues(strconv.Itoa(check.ID), check.Name, check.Status, status).Inc()
+}
+
+// Status returns the outcome of the last poll.
+func (e *Exporter) Status() Status {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.status
+}
+
+// Checks returns the checks of the last successful poll, with the statuses
+// set by webhooks.
+func (e *Exporter) Checks() []pingdom.CheckResponse {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return append([]pingdom.CheckResponse(nil), e.checks...)
+}
+
+// Describe implements prometheus.Collector.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	ch <- checkStatusDesc
+	ch <- checkStateDesc
+	ch <- checkResponseTimeDesc
+	ch <- checkPausedUntilDesc
+	e.unknownStatus.Describe(ch)
+	e.stateChanges.Describe(ch)
+}
+
+// Collect implements prometheus.Collector. The checks of the last
+// successful poll are exported, so that a failed poll only affects
+// pingdom_up.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.collect(ch, false)
+}
+
+// WithTestTimestamps returns a prometheus.Collector exporting the same
+// metrics as the exporter, with the time of the last test of the check as
+// the timestamp of the check metrics, or the time of the state change while
+// a webhook sets the status. Prometheus ignores timestamps on scrapes in most
+// setups, so this is meant for pushing the metrics, e.g. through remote
+// write.
+func (e *Exporter) WithTestTimestamps() prometheus.Collector {
+	return &testTimestampCollector{exporter: e}
+}
+
+func (e *Exporter) collect(ch chan<- prometheus.Metric, testTimestamps bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	up := 0.0
+	if !e.status.Time.IsZero() && e.status.Err == nil {
+		up = 1
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up)
+
+	states := make([]string, 0, len(e.statusValues))
+	for state := range e.statusValues {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+
+	for _, check := range e.checks {
+		labels := CheckLabelValues(check)
+
+		send := func(m prometheus.Metric) { ch <- m }
+		if testTimestamps && check.LastTestTime != 0 {
+			timestamp := time.Unix(check.LastTestTime, 0)
+			// The status set by a webhook is newer than the last test.
+			if o, ok := e.overrides[check.ID]; ok && o.time.After(timestamp) {
+				timestamp = o.time
+			}
+			send = func(m prometheus.Metric) { ch <- &timestampedMetric{Metric: m, timestamp: timestamp} }
+		}
+
+		if status, ok := e.statusValues[check.Status]; ok {
+			send(prometheus.MustNewConstMetric(checkStatusDesc, prometheus.GaugeValue, status, labels...))
+		}
+
+		for _, state := range states {
+			var value float64
+			if state == check.Status {
+				value = 1
+			}
+			send(prometheus.MustNewConstMetric(checkStateDesc, prometheus.GaugeValue, value, append(labels, state)...))
+		}
+
+		send(prometheus.MustNewConstMetric(checkResponseTimeDesc, prometheus.GaugeValue, float64(check.LastResponseTime), labels...))
+
+		if pause, ok := CheckPause(check); ok && IsPaused(check) {
+			send(prometheus.MustNewConstMetric(checkPausedUntilDesc, prometheus.GaugeValue, float64(pause.Until.Unix()), append(labels, pause.By)...))
+		}
+	}
+
+	e.unknownStatus.Collect(ch)
+	e.stateChanges.Collect(ch)
+}
+
+// testTimestampCollector is the collector returned by
+// Exporter.WithTestTimestamps.
+type testTimestampCollector struct {
+	exporter *Exporter
+}
+
+func (c *testTimestampCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.exporter.Describe(ch)
+}
+
+func (c *testTimestampCollector) Collect(ch chan<- prometheus.Metric) {
+	c.exporter.collect(ch, true)
+}
+
+// timestampedMetric is a metric with an explicit timestamp.
+type timestampedMetric struct {
+	prometheus.Metric
+	timestamp time.Time
+}
+
+func (m *timestampedMetric) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+
+	out.TimestampMs = proto.Int64(m.timestamp.UnixNano() / int64(time.Millisecond))
+
+	return nil
+}
+
+// IsPaused returns true if the check is paused.
+func IsPaused(check pingdom.CheckResponse) bool {
+	// Pingdom library doesn't report paused correctly,
+	// so calculate it off the status.
+	return check.Paused || check.Status == "paused"
+}
+
+// CheckLabelValues returns the values of the id, name, hostname, resolution,
+// paused and tags labels of the check metrics.
+func CheckLabelValues(check pingdom.CheckResponse) []string {
+	// The tags of a pause change with every pause, and are exported as the
+	// paused until metric instead.
+	tags := WithoutPauseTags(check)
+
+	return []string{
+		strconv.Itoa(check.ID),
+		check.Name,
+		check.Hostname,
+		strconv.Itoa(check.Resolution),
+		strconv.FormatBool(IsPaused(check)),
+		strings.Join(tags, ","),
+	}
+}
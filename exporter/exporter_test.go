@@ -0,0 +1,227 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// mockAPI is a PingdomAPI returning fixed checks or an error.
+type mockAPI struct {
+	checks []pingdom.CheckResponse
+	err    error
+}
+
+func (m *mockAPI) ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.checks, nil
+}
+
+func newTestExporter(t *testing.T, api PingdomAPI) *Exporter {
+	c := DefaultConfig()
+	c.API = api
+
+	e, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+// gather returns the values of the metrics exported by the collector, keyed
+// by metric name and label values in label name order.
+func gather(t *testing.T, c prometheus.Collector) map[string]float64 {
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(c); err != nil {
+		t.Fatal(err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			var labels []string
+			for _, pair := range m.GetLabel() {
+				labels = append(labels, pair.GetName()+"="+pair.GetValue())
+			}
+			key := family.GetName() + "{" + strings.Join(labels, ",") + "}"
+
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				values[key] = m.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				values[key] = m.GetCounter().GetValue()
+			}
+		}
+	}
+
+	return values
+}
+
+func TestCheckLabelValues(t *testing.T) {
+	tests := []struct {
+		check pingdom.CheckResponse
+		want  []string
+	}{
+		{
+			check: pingdom.CheckResponse{
+				ID:         1,
+				Name:       "Website",
+				Hostname:   "www.example.com",
+				Resolution: 5,
+				Status:     "up",
+				Tags:       []pingdom.CheckResponseTag{{Name: "a"}, {Name: "b"}},
+			},
+			want: []string{"1", "Website", "www.example.com", "5", "false", "a,b"},
+		},
+		{
+			// The Pingdom API does not set paused in check lists.
+			check: pingdom.CheckResponse{
+				ID:     2,
+				Status: "paused",
+			},
+			want: []string{"2", "", "", "0", "true", ""},
+		},
+	}
+
+	for _, tc := range tests {
+		if got := CheckLabelValues(tc.check); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("CheckLabelValues(%+v) = %v, want %v", tc.check, got, tc.want)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	api := &mockAPI{
+		checks: []pingdom.CheckResponse{
+			{ID: 1, Name: "a", Status: "down", Resolution: 1, LastResponseTime: 42},
+			{ID: 2, Name: "b", Status: "maintenance", Resolution: 1},
+		},
+	}
+	e := newTestExporter(t, api)
+
+	if err := e.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	values := gather(t, e)
+
+	want := map[string]float64{
+		"pingdom_up{}": 1,
+		"pingdom_check_status{hostname=,id=1,name=a,paused=false,resolution=1,tags=}":                       2,
+		"pingdom_check_state{hostname=,id=1,name=a,paused=false,resolution=1,state=down,tags=}":             1,
+		"pingdom_check_state{hostname=,id=1,name=a,paused=false,resolution=1,state=up,tags=}":               0,
+		"pingdom_check_state{hostname=,id=2,name=b,paused=false,resolution=1,state=down,tags=}":             0,
+		"pingdom_check_response_time{hostname=,id=1,name=a,paused=false,resolution=1,tags=}":                42,
+		"pingdom_check_unknown_status_total{status=maintenance}":                                            1,
+		"pingdom_check_state{hostname=,id=1,name=a,paused=false,resolution=1,state=unconfirmed_down,tags=}": 0,
+	}
+	for key, value := range want {
+		got, ok := values[key]
+		if !ok {
+			t.Errorf("missing %s", key)
+			continue
+		}
+		if got != value {
+			t.Errorf("%s = %v, want %v", key, got, value)
+		}
+	}
+
+	if _, ok := values["pingdom_check_status{hostname=,id=2,name=b,paused=false,resolution=1,tags=}"]; ok {
+		t.Errorf("check with an unknown status is exported in pingdom_check_status")
+	}
+}
+
+func TestCustomStatusValues(t *testing.T) {
+	values, err := ParseStatusValues([]string{"down=1", "maintenance=3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := DefaultConfig()
+	c.API = &mockAPI{
+		checks: []pingdom.CheckResponse{
+			{ID: 1, Status: "down"},
+			{ID: 2, Status: "maintenance"},
+		},
+	}
+	c.StatusValues = values
+	e, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := gather(t, e)
+	if got := metrics["pingdom_check_status{hostname=,id=1,name=,paused=false,resolution=0,tags=}"]; got != 1 {
+		t.Errorf("down = %v, want 1", got)
+	}
+	if got := metrics["pingdom_check_status{hostname=,id=2,name=,paused=false,resolution=0,tags=}"]; got != 3 {
+		t.Errorf("maintenance = %v, want 3", got)
+	}
+
+	for _, invalid := range []string{"down", "=1", "down=x"} {
+		if _, err := ParseStatusValues([]string{invalid}); err == nil {
+			t.Errorf("ParseStatusValues(%q) did not fail", invalid)
+		}
+	}
+}
+
+func TestPollError(t *testing.T) {
+	api := &mockAPI{
+		checks: []pingdom.CheckResponse{
+			{ID: 1, Status: "up"},
+		},
+	}
+	e := newTestExporter(t, api)
+
+	if e.Status().Ready {
+		t.Errorf("exporter is ready before the first poll")
+	}
+
+	if err := e.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !e.Status().Ready {
+		t.Errorf("exporter is not ready after a successful poll")
+	}
+
+	// Errors other than rejected credentials keep the exporter ready, and
+	// the checks of the last successful poll exported.
+	api.err = &pingdom.PingdomError{StatusCode: http.StatusInternalServerError}
+	e.Poll(context.Background())
+
+	values := gather(t, e)
+	if values["pingdom_up{}"] != 0 {
+		t.Errorf("pingdom_up = %v, want 0", values["pingdom_up{}"])
+	}
+	if _, ok := values["pingdom_check_status{hostname=,id=1,name=,paused=false,resolution=0,tags=}"]; !ok {
+		t.Errorf("checks of the last successful poll are not exported")
+	}
+	if !e.Status().Ready {
+		t.Errorf("exporter is not ready after a server error")
+	}
+
+	api.err = &pingdom.PingdomError{StatusCode: http.StatusUnauthorized}
+	e.Poll(context.Background())
+	if e.Status().Ready {
+		t.Errorf("exporter is ready while credentials are rejected")
+	}
+}
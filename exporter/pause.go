@@ -0,0 +1,91 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+const (
+	// PausedUntilTagPrefix starts the tag recording until when a check was
+	// paused by checks pause, followed by the UTC time as PausedUntilLayout.
+	PausedUntilTagPrefix = "paused-until-"
+	// PausedByTagPrefix starts the tag recording who paused a check with
+	// checks pause.
+	PausedByTagPrefix = "paused-by-"
+	// PausedUntilLayout is the time layout of the paused until tag. Tags have
+	// no separators, and minutes are precise enough for pauses.
+	PausedUntilLayout = "200601021504"
+)
+
+// invalidTagChars are the characters replaced in the tag of who paused a
+// check.
+var invalidTagChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// Pause is who paused a check, and until when, as recorded in its tags.
+type Pause struct {
+	Until time.Time
+	By    string
+}
+
+// Tags returns the tags recording the pause.
+func (p Pause) Tags() []string {
+	tags := []string{PausedUntilTagPrefix + p.Until.UTC().Format(PausedUntilLayout)}
+	if by := strings.Trim(invalidTagChars.ReplaceAllString(strings.ToLower(p.By), "-"), "-"); by != "" {
+		tags = append(tags, PausedByTagPrefix+by)
+	}
+
+	return tags
+}
+
+// CheckPause returns the pause recorded in the tags of the check, if any.
+func CheckPause(check pingdom.CheckResponse) (Pause, bool) {
+	var p Pause
+	found := false
+
+	for _, tag := range check.Tags {
+		switch {
+		case strings.HasPrefix(tag.Name, PausedUntilTagPrefix):
+			until, err := time.Parse(PausedUntilLayout, strings.TrimPrefix(tag.Name, PausedUntilTagPrefix))
+			if err != nil {
+				continue
+			}
+			p.Until = until
+			found = true
+		case strings.HasPrefix(tag.Name, PausedByTagPrefix):
+			p.By = strings.TrimPrefix(tag.Name, PausedByTagPrefix)
+		}
+	}
+
+	return p, found
+}
+
+// WithoutPauseTags returns the names of the tags of the check, without the
+// tags recording a pause.
+func WithoutPauseTags(check pingdom.CheckResponse) []string {
+	var tags []string
+	for _, tag := range check.Tags {
+		if strings.HasPrefix(tag.Name, PausedUntilTagPrefix) || strings.HasPrefix(tag.Name, PausedByTagPrefix) {
+			continue
+		}
+		tags = append(tags, tag.Name)
+	}
+
+	return tags
+}
+
+// SetCheckPaused pauses or resumes a check, and replaces its tags, leaving
+// its other settings unchanged.
+func (c *Client) SetCheckPaused(ctx context.Context, id int, paused bool, tags []string) error {
+	params := map[string]string{
+		"paused": strconv.FormatBool(paused),
+		"tags":   strings.Join(tags, ","),
+	}
+
+	return c.request(ctx, "PUT", fmt.Sprintf("/api/2.0/checks/%d", id), params, &pingdom.PingdomResponse{})
+}
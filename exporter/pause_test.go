@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+func TestCheckPause(t *testing.T) {
+	until := time.Date(2018, 5, 4, 13, 30, 0, 0, time.UTC)
+	pause := Pause{Until: until, By: "Jane Doe"}
+
+	tags := pause.Tags()
+	if want := []string{"paused-until-201805041330", "paused-by-jane-doe"}; !reflect.DeepEqual(tags, want) {
+		t.Fatalf("Tags() = %v, want %v", tags, want)
+	}
+
+	check := pingdom.CheckResponse{Tags: []pingdom.CheckResponseTag{{Name: "web"}}}
+	if _, ok := CheckPause(check); ok {
+		t.Errorf("found a pause in a check without pause tags")
+	}
+
+	for _, tag := range tags {
+		check.Tags = append(check.Tags, pingdom.CheckResponseTag{Name: tag})
+	}
+	got, ok := CheckPause(check)
+	if !ok || !got.Until.Equal(until) || got.By != "jane-doe" {
+		t.Errorf("CheckPause() = %+v, %t, want until %s by jane-doe", got, ok, until)
+	}
+	if tags := WithoutPauseTags(check); !reflect.DeepEqual(tags, []string{"web"}) {
+		t.Errorf("WithoutPauseTags() = %v, want [web]", tags)
+	}
+}
+
+func TestPausedUntilMetric(t *testing.T) {
+	api := &mockAPI{
+		checks: []pingdom.CheckResponse{
+			{ID: 1, Name: "a", Status: "paused", Resolution: 1, Tags: []pingdom.CheckResponseTag{
+				{Name: "team-web"}, {Name: "paused-until-201805041330"}, {Name: "paused-by-jane"},
+			}},
+			{ID: 2, Name: "b", Status: "up", Resolution: 1, Tags: []pingdom.CheckResponseTag{
+				{Name: "paused-until-201805041330"},
+			}},
+		},
+	}
+	e := newTestExporter(t, api)
+	if err := e.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	values := gather(t, e)
+	key := "pingdom_check_paused_until_timestamp_seconds{hostname=,id=1,name=a,paused=true,paused_by=jane,resolution=1,tags=team-web}"
+	if want := float64(time.Date(2018, 5, 4, 13, 30, 0, 0, time.UTC).Unix()); values[key] != want {
+		t.Errorf("%s = %v, want %v", key, values[key], want)
+	}
+	// The tags of the pause are left out of the tags label.
+	if status := "pingdom_check_status{hostname=,id=1,name=a,paused=true,resolution=1,tags=team-web}"; values[status] != -1 {
+		t.Errorf("%s = %v, want -1", status, values[status])
+	}
+	for k := range values {
+		if k != key && strings.HasPrefix(k, "pingdom_check_paused_until_timestamp_seconds") {
+			t.Errorf("unexpected metric %s for a check not paused", k)
+		}
+	}
+}
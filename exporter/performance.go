@@ -0,0 +1,283 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// performanceLabels are the labels of the performance metrics. The
+// resolution label is the period of the summary, which is why the check
+// resolution label is left out.
+var performanceLabels = []string{"id", "name", "resolution"}
+
+var (
+	performanceUpDesc = prometheus.NewDesc(
+		"pingdom_performance_up",
+		"Whether the last refresh of the performance summaries was successful (1: up, 0: down)",
+		nil, nil,
+	)
+
+	performanceStartTimeDesc = prometheus.NewDesc(
+		"pingdom_check_performance_start_time_seconds",
+		"The start of the last complete period summarized by Pingdom, as a Unix timestamp",
+		performanceLabels, nil,
+	)
+
+	performanceAvgResponseTimeDesc = prometheus.NewDesc(
+		"pingdom_check_performance_avg_response_time",
+		"The average response time of the check in milliseconds over the last complete period",
+		performanceLabels, nil,
+	)
+
+	performanceUptimeDesc = prometheus.NewDesc(
+		"pingdom_check_performance_uptime_seconds",
+		"The time the check was up during the last complete period",
+		performanceLabels, nil,
+	)
+
+	performanceDowntimeDesc = prometheus.NewDesc(
+		"pingdom_check_performance_downtime_seconds",
+		"The time the check was down during the last complete period",
+		performanceLabels, nil,
+	)
+
+	performanceUnmonitoredDesc = prometheus.NewDesc(
+		"pingdom_check_performance_unmonitored_seconds",
+		"The time the check was not monitored during the last complete period",
+		performanceLabels, nil,
+	)
+)
+
+// resolutionPeriods are the lengths of the periods of the
+// summary.performance resolutions.
+var resolutionPeriods = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+// PerformanceAPI is the part of the Pingdom API used by the
+// PerformanceCollector. Client implements it.
+type PerformanceAPI interface {
+	ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error)
+	Performance(ctx context.Context, checkID int, resolution string, params map[string]string) ([]PerformanceSummary, error)
+}
+
+// PerformanceConfig represents the configuration used to create a
+// PerformanceCollector.
+type PerformanceConfig struct {
+	API    PerformanceAPI
+	Logger log.Logger
+
+	// Resolutions are the summary.performance resolutions to export, out of
+	// hour, day and week.
+	Resolutions []string
+}
+
+// DefaultPerformanceConfig provides a default configuration to create a
+// PerformanceCollector.
+func DefaultPerformanceConfig() PerformanceConfig {
+	return PerformanceConfig{
+		API:         nil,
+		Logger:      log.NewNopLogger(),
+		Resolutions: []string{"hour", "day", "week"},
+	}
+}
+
+// PerformanceCollector exports the last complete hourly, daily and weekly
+// performance summaries of the checks, as computed by Pingdom. Summaries
+// change at most once an hour and cost a request per check and resolution,
+// so they are refreshed separately from the check list.
+type PerformanceCollector struct {
+	api         PerformanceAPI
+	logger      log.Logger
+	resolutions []string
+
+	mu        sync.RWMutex
+	err       error
+	refreshed bool
+	summaries []performanceSample
+}
+
+// performanceSample is the last complete summary of a check at a resolution.
+type performanceSample struct {
+	check      pingdom.CheckResponse
+	resolution string
+	summary    PerformanceSummary
+}
+
+// NewPerformanceCollector creates a new configured PerformanceCollector.
+func NewPerformanceCollector(config PerformanceConfig) (*PerformanceCollector, error) {
+	if config.API == nil {
+		return nil, fmt.Errorf("config.API must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, fmt.Errorf("config.Logger must not be empty")
+	}
+	if len(config.Resolutions) == 0 {
+		return nil, fmt.Errorf("config.Resolutions must not be empty")
+	}
+	for _, resolution := range config.Resolutions {
+		if _, ok := resolutionPeriods[resolution]; !ok {
+			return nil, fmt.Errorf("config.Resolutions must be hour, day or week, got %q", resolution)
+		}
+	}
+
+	c := &PerformanceCollector{
+		api:         config.API,
+		logger:      config.Logger,
+		resolutions: config.Resolutions,
+	}
+
+	return c, nil
+}
+
+// Run refreshes the summaries every interval until the context is
+// cancelled.
+func (c *PerformanceCollector) Run(ctx context.Context, interval time.Duration) {
+	for {
+		c.Refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Refresh fetches the summaries of all checks once. Summaries that fail to
+// be fetched keep their previous values.
+func (c *PerformanceCollector) Refresh(ctx context.Context) error {
+	checks, err := c.api.ListChecks(ctx, nil)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		level.Error(c.logger).Log("msg", "failed to get checks", "endpoint", "/api/2.0/checks", "err", err)
+
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+
+		return err
+	}
+
+	previous := map[string]performanceSample{}
+	c.mu.RLock()
+	for _, s := range c.summaries {
+		previous[s.key()] = s
+	}
+	c.mu.RUnlock()
+
+	now := time.Now()
+	var summaries []performanceSample
+	var lastErr error
+
+	for _, check := range checks {
+		for _, resolution := range c.resolutions {
+			s := performanceSample{check: check, resolution: resolution}
+
+			summary, ok, err := c.lastComplete(ctx, check.ID, resolution, now)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				level.Error(c.logger).Log("msg", "failed to get performance summary", "check_id", check.ID, "resolution", resolution, "err", err)
+				lastErr = err
+
+				if p, found := previous[s.key()]; found {
+					summaries = append(summaries, p)
+				}
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			s.summary = summary
+			summaries = append(summaries, s)
+		}
+	}
+
+	level.Debug(c.logger).Log("msg", "updated performance summaries", "checks", len(checks), "duration", time.Since(now))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.refreshed = true
+	c.err = lastErr
+	c.summaries = summaries
+
+	return lastErr
+}
+
+// lastComplete returns the summary of the last complete period of the
+// check, and false if there is none.
+func (c *PerformanceCollector) lastComplete(ctx context.Context, checkID int, resolution string, now time.Time) (PerformanceSummary, bool, error) {
+	period := resolutionPeriods[resolution]
+
+	summaries, err := c.api.Performance(ctx, checkID, resolution, map[string]string{
+		"from": strconv.FormatInt(now.Add(-2*period).Unix(), 10),
+		"to":   strconv.FormatInt(now.Unix(), 10),
+	})
+	if err != nil {
+		return PerformanceSummary{}, false, err
+	}
+
+	var last PerformanceSummary
+	found := false
+	for _, summary := range summaries {
+		end := time.Unix(summary.StartTime, 0).Add(period)
+		if end.After(now) || summary.StartTime < last.StartTime {
+			continue
+		}
+		last, found = summary, true
+	}
+
+	return last, found, nil
+}
+
+func (s performanceSample) key() string {
+	return strconv.Itoa(s.check.ID) + "/" + s.resolution
+}
+
+// Describe implements prometheus.Collector.
+func (c *PerformanceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- performanceUpDesc
+	ch <- performanceStartTimeDesc
+	ch <- performanceAvgResponseTimeDesc
+	ch <- performanceUptimeDesc
+	ch <- performanceDowntimeDesc
+	ch <- performanceUnmonitoredDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *PerformanceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	up := 0.0
+	if c.refreshed && c.err == nil {
+		up = 1
+	}
+	ch <- prometheus.MustNewConstMetric(performanceUpDesc, prometheus.GaugeValue, up)
+
+	for _, s := range c.summaries {
+		labels := []string{strconv.Itoa(s.check.ID), s.check.Name, s.resolution}
+
+		ch <- prometheus.MustNewConstMetric(performanceStartTimeDesc, prometheus.GaugeValue, float64(s.summary.StartTime), labels...)
+		ch <- prometheus.MustNewConstMetric(performanceAvgResponseTimeDesc, prometheus.GaugeValue, float64(s.summary.AvgResponse), labels...)
+		ch <- prometheus.MustNewConstMetric(performanceUptimeDesc, prometheus.GaugeValue, float64(s.summary.Uptime), labels...)
+		ch <- prometheus.MustNewConstMetric(performanceDowntimeDesc, prometheus.GaugeValue, float64(s.summary.Downtime), labels...)
+		ch <- prometheus.MustNewConstMetric(performanceUnmonitoredDesc, prometheus.GaugeValue, float64(s.summary.Unmonitored), labels...)
+	}
+}
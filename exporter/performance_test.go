@@ -0,0 +1,85 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// mockPerformanceAPI returns the summaries of every resolution ending at
+// the time of the request, the last of which is incomplete.
+type mockPerformanceAPI struct {
+	mockAPI
+	err error
+}
+
+func (m *mockPerformanceAPI) Performance(ctx context.Context, checkID int, resolution string, params map[string]string) ([]PerformanceSummary, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	period := int64(resolutionPeriods[resolution] / time.Second)
+	now := time.Now().Unix()
+	current := now - now%period
+
+	return []PerformanceSummary{
+		{StartTime: current - 2*period, AvgResponse: 100, Uptime: int(period)},
+		{StartTime: current - period, AvgResponse: 200, Uptime: int(period) - 60, Downtime: 60},
+		{StartTime: current, AvgResponse: 300, Uptime: 1},
+	}, nil
+}
+
+func TestPerformanceCollector(t *testing.T) {
+	api := &mockPerformanceAPI{
+		mockAPI: mockAPI{
+			checks: []pingdom.CheckResponse{{ID: 1, Name: "a"}},
+		},
+	}
+
+	c := DefaultPerformanceConfig()
+	c.API = api
+	c.Resolutions = []string{"hour", "day"}
+	p, err := NewPerformanceCollector(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	values := gather(t, p)
+	want := map[string]float64{
+		"pingdom_performance_up{}": 1,
+		"pingdom_check_performance_avg_response_time{id=1,name=a,resolution=hour}": 200,
+		"pingdom_check_performance_downtime_seconds{id=1,name=a,resolution=hour}":  60,
+		"pingdom_check_performance_uptime_seconds{id=1,name=a,resolution=day}":     24*3600 - 60,
+	}
+	for key, value := range want {
+		if got, ok := values[key]; !ok || got != value {
+			t.Errorf("%s = %v, want %v", key, got, value)
+		}
+	}
+
+	// Failed refreshes keep the previous summaries.
+	api.err = &pingdom.PingdomError{StatusCode: http.StatusInternalServerError}
+	if err := p.Refresh(context.Background()); err == nil {
+		t.Fatal("refresh did not fail")
+	}
+
+	values = gather(t, p)
+	if values["pingdom_performance_up{}"] != 0 {
+		t.Errorf("pingdom_performance_up = %v, want 0", values["pingdom_performance_up{}"])
+	}
+	if values["pingdom_check_performance_avg_response_time{id=1,name=a,resolution=hour}"] != 200 {
+		t.Errorf("summaries of the previous refresh are not exported")
+	}
+
+	c.Resolutions = []string{"month"}
+	if _, err := NewPerformanceCollector(c); err == nil {
+		t.Errorf("invalid resolution was accepted")
+	}
+}
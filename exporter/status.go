@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultStatusValues maps the check statuses reported by Pingdom to the
+// values of the pingdom_check_status metric.
+var DefaultStatusValues = map[string]float64{
+	"unknown":          -2,
+	"paused":           -1,
+	"up":               0,
+	"unconfirmed_down": 1,
+	"down":             2,
+}
+
+// ParseStatusValues returns the default status values with the overrides
+// applied. Each override has the form "status=value", and may also add a
+// status that Pingdom introduced after this exporter was released.
+func ParseStatusValues(overrides []string) (map[string]float64, error) {
+	values := map[string]float64{}
+	for status, value := range DefaultStatusValues {
+		values[status] = value
+	}
+
+	for _, override := range overrides {
+		parts := strings.SplitN(override, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid status value %q, must be of the form status=value", override)
+		}
+
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status value %q: %v", override, err)
+		}
+
+		values[parts[0]] = value
+	}
+
+	return values, nil
+}
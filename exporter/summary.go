@@ -0,0 +1,110 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+)
+
+// Result is a single test result of a check, as returned by
+// results/{checkid}.
+type Result struct {
+	ProbeID        int    `json:"probeid"`
+	Time           int64  `json:"time"`
+	Status         string `json:"status"`
+	ResponseTime   int    `json:"responsetime"`
+	StatusDesc     string `json:"statusdesc"`
+	StatusDescLong string `json:"statusdesclong"`
+}
+
+// PerformanceSummary is the performance of a check over an hour, day or
+// week, as returned by summary.performance/{checkid}.
+type PerformanceSummary struct {
+	StartTime   int64 `json:"starttime"`
+	AvgResponse int   `json:"avgresponse"`
+	Uptime      int   `json:"uptime"`
+	Downtime    int   `json:"downtime"`
+	Unmonitored int   `json:"unmonitored"`
+}
+
+// OutageState is a period during which a check had the same status, as
+// returned by summary.outage/{checkid}. Status is up, down or unknown.
+type OutageState struct {
+	Status   string `json:"status"`
+	TimeFrom int64  `json:"timefrom"`
+	TimeTo   int64  `json:"timeto"`
+}
+
+// resultsResponse is the body of a results/{checkid} response.
+type resultsResponse struct {
+	Results []Result `json:"results"`
+}
+
+// performanceResponse is the body of a summary.performance/{checkid}
+// response. Only the list matching the requested resolution is set.
+type performanceResponse struct {
+	Summary struct {
+		Hours []PerformanceSummary `json:"hours"`
+		Days  []PerformanceSummary `json:"days"`
+		Weeks []PerformanceSummary `json:"weeks"`
+	} `json:"summary"`
+}
+
+// outageResponse is the body of a summary.outage/{checkid} response.
+type outageResponse struct {
+	Summary struct {
+		States []OutageState `json:"states"`
+	} `json:"summary"`
+}
+
+// Results returns the raw test results of a check, newest first. Pingdom
+// returns at most 1000 results per request, see the limit and offset
+// parameters.
+func (c *Client) Results(ctx context.Context, checkID int, params map[string]string) ([]Result, error) {
+	m := &resultsResponse{}
+	if err := c.Get(ctx, fmt.Sprintf("/api/2.0/results/%d", checkID), params, m); err != nil {
+		return nil, err
+	}
+
+	return m.Results, nil
+}
+
+// Performance returns the performance summaries of a check at the given
+// resolution, which is hour, day or week.
+func (c *Client) Performance(ctx context.Context, checkID int, resolution string, params map[string]string) ([]PerformanceSummary, error) {
+	if resolution != "hour" && resolution != "day" && resolution != "week" {
+		return nil, fmt.Errorf("invalid resolution %q, expected hour, day or week", resolution)
+	}
+
+	p := map[string]string{
+		"resolution":    resolution,
+		"includeuptime": "true",
+	}
+	for k, v := range params {
+		p[k] = v
+	}
+
+	m := &performanceResponse{}
+	if err := c.Get(ctx, fmt.Sprintf("/api/2.0/summary.performance/%d", checkID), p, m); err != nil {
+		return nil, err
+	}
+
+	switch resolution {
+	case "hour":
+		return m.Summary.Hours, nil
+	case "day":
+		return m.Summary.Days, nil
+	default:
+		return m.Summary.Weeks, nil
+	}
+}
+
+// Outages returns the periods during which the check was up, down or
+// unknown, oldest first.
+func (c *Client) Outages(ctx context.Context, checkID int, params map[string]string) ([]OutageState, error) {
+	m := &outageResponse{}
+	if err := c.Get(ctx, fmt.Sprintf("/api/2.0/summary.outage/%d", checkID), params, m); err != nil {
+		return nil, err
+	}
+
+	return m.Summary.States, nil
+}
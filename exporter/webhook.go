@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// webhookStatuses maps the states of Pingdom webhooks to check statuses,
+// where they differ. Transaction checks report SUCCESS and FAILING.
+var webhookStatuses = map[string]string{
+	"success": "up",
+	"failing": "down",
+}
+
+// StateChange is the body of a state change webhook sent by Pingdom.
+type StateChange struct {
+	CheckID       int    `json:"check_id"`
+	CheckName     string `json:"check_name"`
+	PreviousState string `json:"previous_state"`
+	CurrentState  string `json:"current_state"`
+	// StateChangedTimestamp is the Unix time of the state change.
+	StateChangedTimestamp int64 `json:"state_changed_timestamp"`
+}
+
+// Status returns the check status of the current state, e.g. down for DOWN.
+func (c StateChange) Status() string {
+	status := strings.ToLower(c.CurrentState)
+	if s, ok := webhookStatuses[status]; ok {
+		return s
+	}
+
+	return status
+}
+
+// UnknownCheckError is returned for state changes of checks the exporter
+// has not polled yet.
+type UnknownCheckError struct {
+	CheckID int
+}
+
+func (e *UnknownCheckError) Error() string {
+	return fmt.Sprintf("unknown check %d", e.CheckID)
+}
+
+// webhookOverride is the status of a check set by a webhook, which takes
+// precedence over polls with older tests.
+type webhookOverride struct {
+	status string
+	time   time.Time
+}
+
+// ApplyStateChange sets the status of the check from a webhook, without
+// waiting for the next poll. Polls keep the status of the webhook until they
+// return a test of the check made after the state change.
+func (e *Exporter) ApplyStateChange(change StateChange) error {
+	status := change.Status()
+	if status == "" {
+		return fmt.Errorf("no current state")
+	}
+	_, configured := e.statusValues[status]
+	_, known := DefaultStatusValues[status]
+	if !configured && !known {
+		return fmt.Errorf("unknown current state %q", change.CurrentState)
+	}
+
+	changed := time.Unix(change.StateChangedTimestamp, 0)
+	if change.StateChangedTimestamp == 0 {
+		changed = time.Now()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := range e.checks {
+		check := &e.checks[i]
+		if check.ID != change.CheckID {
+			continue
+		}
+
+		if _, ok := e.statusValues[status]; !ok {
+			level.Warn(e.logger).Log("msg", "check has a status without a configured value", "check_id", check.ID, "status", status)
+			e.unknownStatus.WithLabelValues(status).Inc()
+		}
+
+		level.Debug(e.logger).Log("msg", "applied state change", "check_id", check.ID, "from", check.Status, "to", status)
+		e.countStateChange(*check, status)
+		check.Status = status
+		e.overrides[check.ID] = webhookOverride{status: status, time: changed}
+
+		return nil
+	}
+
+	return &UnknownCheckError{CheckID: change.CheckID}
+}
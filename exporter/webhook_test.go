@@ -0,0 +1,148 @@
+package exporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+func TestApplyStateChange(t *testing.T) {
+	api := &mockAPI{
+		checks: []pingdom.CheckResponse{
+			{ID: 1, Name: "a", Status: "up", Resolution: 1, LastTestTime: 1000},
+		},
+	}
+	e := newTestExporter(t, api)
+
+	if err := e.ApplyStateChange(StateChange{CheckID: 1, CurrentState: "DOWN"}); err == nil {
+		t.Errorf("expected an error for a check not polled yet")
+	}
+
+	if err := e.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, state := range []string{"", "BROKEN"} {
+		if err := e.ApplyStateChange(StateChange{CheckID: 1, CurrentState: state}); err == nil {
+			t.Errorf("expected an error for the state %q", state)
+		}
+	}
+
+	if err := e.ApplyStateChange(StateChange{CheckID: 1, PreviousState: "UP", CurrentState: "DOWN", StateChangedTimestamp: 1030}); err != nil {
+		t.Fatal(err)
+	}
+
+	statusKey := "pingdom_check_status{hostname=,id=1,name=a,paused=false,resolution=1,tags=}"
+	changesKey := "pingdom_check_state_changes_total{from=up,id=1,name=a,to=down}"
+	values := gather(t, e)
+	if values[statusKey] != 2 {
+		t.Errorf("%s = %v, want 2", statusKey, values[statusKey])
+	}
+	if values[changesKey] != 1 {
+		t.Errorf("%s = %v, want 1", changesKey, values[changesKey])
+	}
+
+	// Polls with tests older than the state change keep the status of the
+	// webhook.
+	if err := e.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	values = gather(t, e)
+	if values[statusKey] != 2 {
+		t.Errorf("%s = %v after a poll with an older test, want 2", statusKey, values[statusKey])
+	}
+
+	// Newer tests replace it, and the change is counted.
+	api.checks = []pingdom.CheckResponse{
+		{ID: 1, Name: "a", Status: "up", Resolution: 1, LastTestTime: 1060},
+	}
+	if err := e.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	values = gather(t, e)
+	if values[statusKey] != 0 {
+		t.Errorf("%s = %v after a poll with a newer test, want 0", statusKey, values[statusKey])
+	}
+	if v := values["pingdom_check_state_changes_total{from=down,id=1,name=a,to=up}"]; v != 1 {
+		t.Errorf("change back to up counted %v times, want 1", v)
+	}
+	if values[changesKey] != 1 {
+		t.Errorf("%s = %v, want 1", changesKey, values[changesKey])
+	}
+}
+
+func TestApplyStateChangeTimestamps(t *testing.T) {
+	api := &mockAPI{
+		checks: []pingdom.CheckResponse{
+			{ID: 1, Name: "a", Status: "up", Resolution: 1, LastTestTime: 1000},
+		},
+	}
+	e := newTestExporter(t, api)
+	if err := e.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// statusTimestamp returns the timestamp of pingdom_check_status, in
+	// seconds.
+	statusTimestamp := func() int64 {
+		registry := prometheus.NewPedanticRegistry()
+		registry.MustRegister(e.WithTestTimestamps())
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, family := range families {
+			if family.GetName() == "pingdom_check_status" {
+				return family.GetMetric()[0].GetTimestampMs() / 1000
+			}
+		}
+		t.Fatal("no pingdom_check_status")
+		return 0
+	}
+
+	if ts := statusTimestamp(); ts != 1000 {
+		t.Errorf("status timestamp = %d, want the last test 1000", ts)
+	}
+
+	if err := e.ApplyStateChange(StateChange{CheckID: 1, PreviousState: "UP", CurrentState: "DOWN", StateChangedTimestamp: 1030}); err != nil {
+		t.Fatal(err)
+	}
+	if ts := statusTimestamp(); ts != 1030 {
+		t.Errorf("status timestamp = %d, want the state change 1030", ts)
+	}
+
+	// Polls with older tests keep the time of the state change.
+	if err := e.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ts := statusTimestamp(); ts != 1030 {
+		t.Errorf("status timestamp = %d after a poll with an older test, want 1030", ts)
+	}
+
+	api.checks = []pingdom.CheckResponse{
+		{ID: 1, Name: "a", Status: "up", Resolution: 1, LastTestTime: 1060},
+	}
+	if err := e.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ts := statusTimestamp(); ts != 1060 {
+		t.Errorf("status timestamp = %d after a poll with a newer test, want 1060", ts)
+	}
+}
+
+func TestStateChangeStatus(t *testing.T) {
+	tests := map[string]string{
+		"UP":      "up",
+		"DOWN":    "down",
+		"SUCCESS": "up",
+		"FAILING": "down",
+	}
+
+	for state, want := range tests {
+		if got := (StateChange{CurrentState: state}).Status(); got != want {
+			t.Errorf("status of %s = %q, want %q", state, got, want)
+		}
+	}
+}
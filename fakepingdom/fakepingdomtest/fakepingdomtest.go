@@ -0,0 +1,60 @@
+// Package fakepingdomtest serves the fake Pingdom API over HTTP for tests,
+// with a client using it.
+package fakepingdomtest
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+)
+
+// Server is a fake Pingdom API listening on a local port.
+type Server struct {
+	*fakepingdom.Server
+
+	// URL is the base URL of the fake API, e.g. for the --pingdom-url flag.
+	URL string
+	// Pingdom and Client are clients of the fake API.
+	Pingdom *pingdom.Client
+	Client  *exporter.Client
+
+	httpServer *httptest.Server
+}
+
+// NewServer starts a fake Pingdom API serving the fixtures. The caller
+// should call Close when finished, to shut it down.
+func NewServer(t *testing.T, fixtures *fakepingdom.Fixtures) *Server {
+	fake := fakepingdom.New(fixtures)
+	httpServer := httptest.NewServer(fake)
+
+	client := pingdom.NewClient("user", "password", "key")
+	client.BaseURL, _ = url.Parse(httpServer.URL)
+
+	c := exporter.DefaultClientConfig()
+	c.Pingdom = client
+	api, err := exporter.NewClient(c)
+	if err != nil {
+		httpServer.Close()
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		Server:     fake,
+		URL:        httpServer.URL,
+		Pingdom:    client,
+		Client:     api,
+		httpServer: httpServer,
+	}
+
+	return s
+}
+
+// Close shuts down the fake API.
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
@@ -0,0 +1,201 @@
+package fakepingdom
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// Fixtures is the data served by the fake Pingdom API. Fixtures can be
+// loaded from a JSON file with LoadFixtures, using the same field names as
+// the Pingdom API responses.
+type Fixtures struct {
+	Checks   []pingdom.CheckResponse   `json:"checks"`
+	Contacts []pingdom.ContactResponse `json:"contacts"`
+	Credits  Credits                   `json:"credits"`
+
+	// Results, Performance and Outages are keyed by check ID. Average
+	// summaries are computed from the results and outages.
+	Results     map[int][]Result         `json:"results"`
+	Performance map[int][]PerformanceBin `json:"performance"`
+	Outages     map[int][]OutageState    `json:"outages"`
+
+	Maintenance []Maintenance `json:"maintenance"`
+
+	// Errors are returned instead of the fixtures for matching requests.
+	Errors []ErrorFixture `json:"errors"`
+
+	// RateLimit, if set, limits the number of requests served.
+	RateLimit *RateLimit `json:"ratelimit"`
+}
+
+// Result is a single test result of a check, as returned by results/{checkid}.
+type Result struct {
+	ProbeID        int    `json:"probeid"`
+	Time           int64  `json:"time"`
+	Status         string `json:"status"`
+	ResponseTime   int    `json:"responsetime"`
+	StatusDesc     string `json:"statusdesc"`
+	StatusDescLong string `json:"statusdesclong"`
+}
+
+// PerformanceBin is an hourly performance summary of a check, as returned by
+// summary.performance/{checkid}. Daily and weekly summaries are computed from
+// the hourly ones.
+type PerformanceBin struct {
+	StartTime   int64 `json:"starttime"`
+	AvgResponse int   `json:"avgresponse"`
+	Uptime      int   `json:"uptime"`
+	Downtime    int   `json:"downtime"`
+	Unmonitored int   `json:"unmonitored"`
+}
+
+// OutageState is a period during which a check had the same status, as
+// returned by summary.outage/{checkid}.
+type OutageState struct {
+	Status   string `json:"status"`
+	TimeFrom int64  `json:"timefrom"`
+	TimeTo   int64  `json:"timeto"`
+}
+
+// Maintenance is a maintenance window, as returned by maintenance.
+type Maintenance struct {
+	ID             int               `json:"id"`
+	Description    string            `json:"description"`
+	From           int64             `json:"from"`
+	To             int64             `json:"to"`
+	RecurrenceType string            `json:"recurrencetype"`
+	Checks         MaintenanceChecks `json:"checks"`
+}
+
+// MaintenanceChecks are the checks of a maintenance window.
+type MaintenanceChecks struct {
+	Uptime []int `json:"uptime"`
+	TMS    []int `json:"tms"`
+}
+
+// Credits are the account credits, as returned by credits.
+type Credits struct {
+	CheckLimit           int  `json:"checklimit"`
+	AvailableChecks      int  `json:"availablechecks"`
+	UsedDefault          int  `json:"useddefault"`
+	AvailableDefault     int  `json:"availabledefault"`
+	UsedTransaction      int  `json:"usedtransaction"`
+	AvailableTransaction int  `json:"availabletransaction"`
+	AvailableSMS         int  `json:"availablesms"`
+	AvailableSMSTests    int  `json:"availablesmstests"`
+	AutoFillSMS          bool `json:"autofillsms"`
+	AvailableRUMSites    int  `json:"availablerumsites"`
+}
+
+// ErrorFixture makes the fake API return an error for requests with the
+// given method and path. An empty method matches all methods.
+type ErrorFixture struct {
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	StatusCode int    `json:"statuscode"`
+	Message    string `json:"errormessage"`
+}
+
+// RateLimit is the number of requests the fake API serves before answering
+// every further request with 429 Too Many Requests.
+type RateLimit struct {
+	Requests int `json:"requests"`
+}
+
+// LoadFixtures reads fixtures from a JSON file.
+func LoadFixtures(path string) (*Fixtures, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fixtures{}
+	if err := json.Unmarshal(content, f); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// DefaultFixtures returns a small account with a check in each status.
+func DefaultFixtures() *Fixtures {
+	// Fixed times keep the fixtures, and metrics derived from them, stable.
+	const now = 1500000000
+	const hour = 3600
+
+	checks := []pingdom.CheckResponse{
+		{ID: 1001, Name: "Website", Hostname: "www.example.com", Status: "up", Resolution: 1, LastResponseTime: 123, LastTestTime: now - 60, Tags: []pingdom.CheckResponseTag{{Name: "production", Type: "u"}, {Name: "team-web", Type: "u"}}},
+		{ID: 1002, Name: "API", Hostname: "api.example.com", Status: "down", Resolution: 1, LastResponseTime: 0, LastTestTime: now - 60, LastErrorTime: now - 60, Tags: []pingdom.CheckResponseTag{{Name: "production", Type: "u"}}},
+		{ID: 1003, Name: "Staging", Hostname: "staging.example.com", Status: "paused", Resolution: 5, Paused: true, LastTestTime: now - 7*24*hour},
+		{ID: 1004, Name: "Status page", Hostname: "status.example.com", Status: "unconfirmed_down", Resolution: 1, LastResponseTime: 2500, LastTestTime: now - 60},
+		{ID: 1005, Name: "Mail", Hostname: "mail.example.com", Status: "unknown", Resolution: 15},
+	}
+	for i := range checks {
+		checks[i].Created = now - 365*24*hour
+		checks[i].ContactIds = []int{2001}
+		checks[i].Type = pingdom.CheckResponseType{
+			Name: "http",
+			HTTP: &pingdom.CheckResponseHTTPDetails{
+				Url:           "/",
+				Encryption:    true,
+				Port:          443,
+				ShouldContain: "OK",
+			},
+		}
+	}
+	checks[4].Type = pingdom.CheckResponseType{Name: "ping"}
+
+	f := &Fixtures{
+		Checks: checks,
+		Contacts: []pingdom.ContactResponse{
+			{ID: 2001, Name: "On-call", Email: "oncall@example.com", Type: "user"},
+			{ID: 2002, Name: "Backup", Email: "backup@example.com", Cellphone: "4915100000000", CountryISO: "DE", Paused: true, Type: "user"},
+		},
+		Credits: Credits{
+			CheckLimit:        50,
+			AvailableChecks:   45,
+			AvailableSMS:      100,
+			AvailableSMSTests: 10,
+		},
+		Results:     map[int][]Result{},
+		Performance: map[int][]PerformanceBin{},
+		Outages:     map[int][]OutageState{},
+	}
+
+	for _, check := range checks[:2] {
+		for i := 0; i < 24; i++ {
+			start := int64(now - (24-i)*hour)
+
+			status, responseTime, downtime := "up", 100+i, 0
+			if check.Status == "down" && i >= 20 {
+				status, responseTime, downtime = "down", 0, hour
+			}
+
+			f.Results[check.ID] = append(f.Results[check.ID], Result{
+				ProbeID:      33,
+				Time:         start,
+				Status:       status,
+				ResponseTime: responseTime,
+				StatusDesc:   "OK",
+			})
+			f.Performance[check.ID] = append(f.Performance[check.ID], PerformanceBin{
+				StartTime:   start,
+				AvgResponse: responseTime,
+				Uptime:      hour - downtime,
+				Downtime:    downtime,
+			})
+		}
+	}
+
+	f.Outages[1001] = []OutageState{
+		{Status: "up", TimeFrom: now - 24*hour, TimeTo: now},
+	}
+	f.Outages[1002] = []OutageState{
+		{Status: "up", TimeFrom: now - 24*hour, TimeTo: now - 4*hour},
+		{Status: "down", TimeFrom: now - 4*hour, TimeTo: now},
+	}
+
+	return f
+}
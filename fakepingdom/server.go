@@ -0,0 +1,826 @@
+// Package fakepingdom implements a fake Pingdom API serving fixtures, for
+// tests and for running the exporter without Pingdom credentials.
+package fakepingdom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+const apiPrefix = "/api/2.0/"
+
+// Request is a request received by the fake API.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+}
+
+// Server is a fake Pingdom API. It implements http.Handler, so it can be
+// served with httptest.NewServer or http.ListenAndServe.
+type Server struct {
+	// User, Password and AppKey are the credentials the fake API accepts.
+	// If User is empty, any credentials are accepted.
+	User     string
+	Password string
+	AppKey   string
+
+	mu       sync.Mutex
+	fixtures *Fixtures
+	requests []Request
+}
+
+// New returns a fake Pingdom API serving the fixtures.
+func New(fixtures *Fixtures) *Server {
+	return &Server{
+		fixtures: fixtures,
+	}
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// SetError makes the fake API return an error for requests with the given
+// method and path, in addition to the errors in the fixtures. An empty
+// method matches all methods.
+func (s *Server) SetError(method, path string, statusCode int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fixtures.Errors = append(s.fixtures.Errors, ErrorFixture{
+		Method:     method,
+		Path:       path,
+		StatusCode: statusCode,
+		Message:    message,
+	})
+}
+
+// ClearErrors removes all errors, including those from the fixtures.
+func (s *Server) ClearErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fixtures.Errors = nil
+}
+
+// SetCheckStatus changes the status of a check.
+func (s *Server) SetCheckStatus(id int, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.fixtures.Checks {
+		if s.fixtures.Checks[i].ID == id {
+			s.fixtures.Checks[i].Status = status
+		}
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+	})
+
+	if limit := s.fixtures.RateLimit; limit != nil {
+		remaining := limit.Requests - len(s.requests)
+		if remaining < 0 {
+			writeError(w, http.StatusTooManyRequests, "Request limit exceeded")
+			return
+		}
+		w.Header().Set("Req-Limit-Short", fmt.Sprintf("Remaining: %d Time until reset: 3600", remaining))
+		w.Header().Set("Req-Limit-Long", fmt.Sprintf("Remaining: %d Time until reset: 2592000", remaining))
+	}
+
+	if s.User != "" {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != s.User || password != s.Password {
+			writeError(w, http.StatusUnauthorized, "Invalid email and/or password")
+			return
+		}
+		if r.Header.Get("App-Key") != s.AppKey {
+			writeError(w, http.StatusForbidden, "Invalid application key")
+			return
+		}
+	}
+
+	for _, e := range s.fixtures.Errors {
+		if (e.Method == "" || e.Method == r.Method) && e.Path == r.URL.Path {
+			writeError(w, e.StatusCode, e.Message)
+			return
+		}
+	}
+
+	if !strings.HasPrefix(r.URL.Path, apiPrefix) {
+		writeError(w, http.StatusNotFound, "Unknown resource")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, apiPrefix), "/")
+
+	switch {
+	case r.Method == "GET" && len(parts) == 1 && parts[0] == "checks":
+		s.listChecks(w, r)
+	case r.Method == "POST" && len(parts) == 1 && parts[0] == "checks":
+		s.createCheck(w, r)
+	case r.Method == "GET" && len(parts) == 2 && parts[0] == "checks":
+		s.withCheck(w, parts[1], s.readCheck)
+	case r.Method == "PUT" && len(parts) == 2 && parts[0] == "checks":
+		s.withCheck(w, parts[1], func(w http.ResponseWriter, check pingdom.CheckResponse) {
+			s.updateCheck(w, r, check)
+		})
+	case r.Method == "DELETE" && len(parts) == 2 && parts[0] == "checks":
+		s.withCheck(w, parts[1], s.deleteCheck)
+	case r.Method == "GET" && len(parts) == 2 && parts[0] == "results":
+		s.withCheck(w, parts[1], func(w http.ResponseWriter, check pingdom.CheckResponse) {
+			s.results(w, r, check)
+		})
+	case r.Method == "GET" && len(parts) == 2 && parts[0] == "summary.average":
+		s.withCheck(w, parts[1], func(w http.ResponseWriter, check pingdom.CheckResponse) {
+			s.summaryAverage(w, r, check)
+		})
+	case r.Method == "GET" && len(parts) == 2 && parts[0] == "summary.performance":
+		s.withCheck(w, parts[1], func(w http.ResponseWriter, check pingdom.CheckResponse) {
+			s.summaryPerformance(w, r, check)
+		})
+	case r.Method == "GET" && len(parts) == 2 && parts[0] == "summary.outage":
+		s.withCheck(w, parts[1], func(w http.ResponseWriter, check pingdom.CheckResponse) {
+			s.summaryOutage(w, r, check)
+		})
+	case r.Method == "GET" && len(parts) == 1 && parts[0] == "notification_contacts":
+		writeJSON(w, map[string]interface{}{"contacts": s.fixtures.Contacts})
+	case r.Method == "POST" && len(parts) == 1 && parts[0] == "notification_contacts":
+		s.createContact(w, r)
+	case r.Method == "PUT" && len(parts) == 2 && parts[0] == "notification_contacts":
+		s.withContact(w, parts[1], func(w http.ResponseWriter, i int) {
+			s.updateContact(w, r, i)
+		})
+	case r.Method == "DELETE" && len(parts) == 2 && parts[0] == "notification_contacts":
+		s.withContact(w, parts[1], s.deleteContact)
+	case r.Method == "GET" && len(parts) == 1 && parts[0] == "maintenance":
+		writeJSON(w, map[string]interface{}{"maintenance": s.maintenance()})
+	case r.Method == "POST" && len(parts) == 1 && parts[0] == "maintenance":
+		s.createMaintenance(w, r)
+	case r.Method == "PUT" && len(parts) == 2 && parts[0] == "maintenance":
+		s.withMaintenance(w, parts[1], func(w http.ResponseWriter, i int) {
+			s.updateMaintenance(w, r, i)
+		})
+	case r.Method == "DELETE" && len(parts) == 2 && parts[0] == "maintenance":
+		s.withMaintenance(w, parts[1], s.deleteMaintenance)
+	case r.Method == "GET" && len(parts) == 1 && parts[0] == "credits":
+		writeJSON(w, map[string]interface{}{"credits": s.fixtures.Credits})
+	default:
+		writeError(w, http.StatusNotFound, "Unknown resource")
+	}
+}
+
+func (s *Server) withCheck(w http.ResponseWriter, id string, f func(http.ResponseWriter, pingdom.CheckResponse)) {
+	checkID, err := strconv.Atoi(id)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid check id")
+		return
+	}
+
+	for _, check := range s.fixtures.Checks {
+		if check.ID == checkID {
+			f(w, check)
+			return
+		}
+	}
+
+	writeError(w, http.StatusForbidden, "Check not found")
+}
+
+func (s *Server) listChecks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var tags []string
+	if query.Get("tags") != "" {
+		tags = strings.Split(query.Get("tags"), ",")
+	}
+
+	checks := []interface{}{}
+	for _, check := range s.fixtures.Checks {
+		if len(tags) > 0 && !hasAnyTag(check, tags) {
+			continue
+		}
+		if query.Get("include_tags") != "true" {
+			check.Tags = nil
+		}
+		checks = append(checks, checkJSON(check, false))
+	}
+
+	writeJSON(w, map[string]interface{}{"checks": checks})
+}
+
+func (s *Server) readCheck(w http.ResponseWriter, check pingdom.CheckResponse) {
+	writeJSON(w, map[string]interface{}{"check": checkJSON(check, true)})
+}
+
+func (s *Server) createCheck(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	check := pingdom.CheckResponse{
+		Status: "unknown",
+	}
+	switch r.Form.Get("type") {
+	case "http":
+		check.Type.HTTP = &pingdom.CheckResponseHTTPDetails{}
+	case "ping":
+	default:
+		writeError(w, http.StatusBadRequest, "Invalid check type")
+		return
+	}
+	check.Type.Name = r.Form.Get("type")
+
+	if err := setCheckParams(&check, r.Form); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if check.Name == "" || check.Hostname == "" {
+		writeError(w, http.StatusBadRequest, "Missing name or host")
+		return
+	}
+
+	for _, c := range s.fixtures.Checks {
+		if c.ID >= check.ID {
+			check.ID = c.ID + 1
+		}
+	}
+	s.fixtures.Checks = append(s.fixtures.Checks, check)
+
+	writeJSON(w, map[string]interface{}{
+		"check": map[string]interface{}{"id": check.ID, "name": check.Name},
+	})
+}
+
+func (s *Server) updateCheck(w http.ResponseWriter, r *http.Request, check pingdom.CheckResponse) {
+	r.ParseForm()
+
+	if err := setCheckParams(&check, r.Form); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for i := range s.fixtures.Checks {
+		if s.fixtures.Checks[i].ID == check.ID {
+			s.fixtures.Checks[i] = check
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{"message": "Modification of check was successful!"})
+}
+
+func (s *Server) deleteCheck(w http.ResponseWriter, check pingdom.CheckResponse) {
+	checks := s.fixtures.Checks[:0]
+	for _, c := range s.fixtures.Checks {
+		if c.ID != check.ID {
+			checks = append(checks, c)
+		}
+	}
+	s.fixtures.Checks = checks
+
+	writeJSON(w, map[string]interface{}{"message": "Deletion of check was successful!"})
+}
+
+// setCheckParams sets the settings of a check from the parameters of a create
+// or modify request. Parameters that are not present are left unchanged.
+func setCheckParams(check *pingdom.CheckResponse, params url.Values) error {
+	var err error
+	setInt := func(name string, v *int) {
+		if _, ok := params[name]; ok && err == nil {
+			*v, err = strconv.Atoi(params.Get(name))
+		}
+	}
+	setBool := func(name string, v *bool) {
+		if _, ok := params[name]; ok && err == nil {
+			*v, err = strconv.ParseBool(params.Get(name))
+		}
+	}
+	setString := func(name string, v *string) {
+		if _, ok := params[name]; ok {
+			*v = params.Get(name)
+		}
+	}
+	setInts := func(name string, v *[]int) {
+		if _, ok := params[name]; !ok {
+			return
+		}
+		*v = nil
+		for _, s := range strings.Split(params.Get(name), ",") {
+			if s == "" || err != nil {
+				continue
+			}
+			var i int
+			i, err = strconv.Atoi(s)
+			*v = append(*v, i)
+		}
+	}
+
+	setString("name", &check.Name)
+	setString("host", &check.Hostname)
+	setInt("resolution", &check.Resolution)
+	setInt("sendnotificationwhendown", &check.SendNotificationWhenDown)
+	setInt("notifyagainevery", &check.NotifyAgainEvery)
+	setBool("notifywhenbackup", &check.NotifyWhenBackup)
+	setInts("contactids", &check.ContactIds)
+	setInts("integrationids", &check.IntegrationIds)
+	if _, ok := params["tags"]; ok {
+		check.Tags = nil
+		for _, tag := range strings.Split(params.Get("tags"), ",") {
+			if tag != "" {
+				check.Tags = append(check.Tags, pingdom.CheckResponseTag{Name: tag, Type: "u"})
+			}
+		}
+	}
+	if _, ok := params["paused"]; ok {
+		setBool("paused", &check.Paused)
+		if check.Paused {
+			check.Status = "paused"
+		} else if check.Status == "paused" {
+			check.Status = "unknown"
+		}
+	}
+
+	if details := check.Type.HTTP; details != nil {
+		setString("url", &details.Url)
+		setBool("encryption", &details.Encryption)
+		setInt("port", &details.Port)
+		// shouldcontain and shouldnotcontain are mutually exclusive, setting
+		// one clears the other.
+		if _, ok := params["shouldcontain"]; ok {
+			details.ShouldContain, details.ShouldNotContain = params.Get("shouldcontain"), ""
+		} else if _, ok := params["shouldnotcontain"]; ok {
+			details.ShouldContain, details.ShouldNotContain = "", params.Get("shouldnotcontain")
+		}
+		setString("postdata", &details.PostData)
+		if auth, ok := params["auth"]; ok {
+			parts := strings.SplitN(auth[0], ":", 2)
+			details.Username, details.Password = parts[0], ""
+			if len(parts) == 2 {
+				details.Password = parts[1]
+			}
+		}
+		for i := 0; ; i++ {
+			header := params.Get(fmt.Sprintf("requestheader%d", i))
+			if header == "" {
+				break
+			}
+			if i == 0 {
+				details.RequestHeaders = map[string]string{}
+			}
+			parts := strings.SplitN(header, ":", 2)
+			if len(parts) == 2 {
+				details.RequestHeaders[parts[0]] = parts[1]
+			}
+		}
+	}
+
+	return err
+}
+
+func (s *Server) withContact(w http.ResponseWriter, id string, f func(http.ResponseWriter, int)) {
+	contactID, err := strconv.Atoi(id)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid contact id")
+		return
+	}
+
+	for i, contact := range s.fixtures.Contacts {
+		if contact.ID == contactID {
+			f(w, i)
+			return
+		}
+	}
+
+	writeError(w, http.StatusForbidden, "Contact not found")
+}
+
+func (s *Server) createContact(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	contact := pingdom.ContactResponse{Type: "user"}
+	if err := setContactParams(&contact, r.Form); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if contact.Name == "" {
+		writeError(w, http.StatusBadRequest, "Missing name")
+		return
+	}
+
+	for _, c := range s.fixtures.Contacts {
+		if c.ID >= contact.ID {
+			contact.ID = c.ID + 1
+		}
+	}
+	s.fixtures.Contacts = append(s.fixtures.Contacts, contact)
+
+	writeJSON(w, map[string]interface{}{
+		"contact": map[string]interface{}{"id": contact.ID, "name": contact.Name},
+	})
+}
+
+func (s *Server) updateContact(w http.ResponseWriter, r *http.Request, i int) {
+	r.ParseForm()
+
+	contact := s.fixtures.Contacts[i]
+	if err := setContactParams(&contact, r.Form); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.fixtures.Contacts[i] = contact
+
+	writeJSON(w, map[string]interface{}{"message": "Modification of contact was successful!"})
+}
+
+func (s *Server) deleteContact(w http.ResponseWriter, i int) {
+	id := s.fixtures.Contacts[i].ID
+	s.fixtures.Contacts = append(s.fixtures.Contacts[:i], s.fixtures.Contacts[i+1:]...)
+
+	// Deleted contacts are removed from the checks.
+	for j := range s.fixtures.Checks {
+		check := &s.fixtures.Checks[j]
+		ids := []int{}
+		for _, contactID := range check.ContactIds {
+			if contactID != id {
+				ids = append(ids, contactID)
+			}
+		}
+		check.ContactIds = ids
+	}
+
+	writeJSON(w, map[string]interface{}{"message": "Deletion of contact was successful!"})
+}
+
+// setContactParams sets the settings of a contact from the parameters of a
+// create or modify request. Parameters that are not present are left
+// unchanged.
+func setContactParams(contact *pingdom.ContactResponse, params url.Values) error {
+	var err error
+	setBool := func(name string, v *bool) {
+		if _, ok := params[name]; ok && err == nil {
+			*v, err = strconv.ParseBool(params.Get(name))
+		}
+	}
+	setString := func(name string, v *string) {
+		if _, ok := params[name]; ok {
+			*v = params.Get(name)
+		}
+	}
+
+	setString("name", &contact.Name)
+	setString("email", &contact.Email)
+	setString("cellphone", &contact.Cellphone)
+	setString("countryiso", &contact.CountryISO)
+	setString("defaultsmsprovider", &contact.DefaultSMSProvider)
+	setString("twitteruser", &contact.TwitterUser)
+	setBool("directtwitter", &contact.DirectTwitter)
+	setBool("paused", &contact.Paused)
+
+	return err
+}
+
+// Maintenance returns the maintenance windows.
+func (s *Server) Maintenance() []Maintenance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.maintenance()
+}
+
+func (s *Server) maintenance() []Maintenance {
+	windows := []Maintenance{}
+	for _, m := range s.fixtures.Maintenance {
+		m.Checks.Uptime = append([]int(nil), m.Checks.Uptime...)
+		windows = append(windows, m)
+	}
+
+	return windows
+}
+
+func (s *Server) withMaintenance(w http.ResponseWriter, id string, f func(http.ResponseWriter, int)) {
+	windowID, err := strconv.Atoi(id)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid maintenance id")
+		return
+	}
+
+	for i, m := range s.fixtures.Maintenance {
+		if m.ID == windowID {
+			f(w, i)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "Maintenance window not found")
+}
+
+func (s *Server) createMaintenance(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	m := Maintenance{}
+	if err := setMaintenanceParams(&m, r.Form); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if m.Description == "" || m.To <= m.From {
+		writeError(w, http.StatusBadRequest, "Missing description or invalid interval")
+		return
+	}
+
+	m.ID = 1
+	for _, other := range s.fixtures.Maintenance {
+		if other.ID >= m.ID {
+			m.ID = other.ID + 1
+		}
+	}
+	s.fixtures.Maintenance = append(s.fixtures.Maintenance, m)
+
+	writeJSON(w, map[string]interface{}{"maintenance": map[string]interface{}{"id": m.ID}})
+}
+
+func (s *Server) updateMaintenance(w http.ResponseWriter, r *http.Request, i int) {
+	r.ParseForm()
+
+	m := s.fixtures.Maintenance[i]
+	if err := setMaintenanceParams(&m, r.Form); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if m.To <= m.From {
+		writeError(w, http.StatusBadRequest, "Invalid interval")
+		return
+	}
+	s.fixtures.Maintenance[i] = m
+
+	writeJSON(w, map[string]interface{}{"message": "Maintenance window successfully modified!"})
+}
+
+func (s *Server) deleteMaintenance(w http.ResponseWriter, i int) {
+	s.fixtures.Maintenance = append(s.fixtures.Maintenance[:i], s.fixtures.Maintenance[i+1:]...)
+
+	writeJSON(w, map[string]interface{}{"message": "Maintenance window successfully deleted!"})
+}
+
+// setMaintenanceParams sets the settings of a maintenance window from the
+// parameters of a create or modify request. Parameters that are not present
+// are left unchanged.
+func setMaintenanceParams(m *Maintenance, params url.Values) error {
+	var err error
+	setInt64 := func(name string, v *int64) {
+		if _, ok := params[name]; ok && err == nil {
+			*v, err = strconv.ParseInt(params.Get(name), 10, 64)
+		}
+	}
+	setInt64("from", &m.From)
+	setInt64("to", &m.To)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := params["description"]; ok {
+		m.Description = params.Get("description")
+	}
+	if _, ok := params["recurrencetype"]; ok {
+		m.RecurrenceType = params.Get("recurrencetype")
+	}
+	if _, ok := params["uptimeids"]; ok {
+		m.Checks.Uptime = nil
+		for _, id := range strings.Split(params.Get("uptimeids"), ",") {
+			if id == "" {
+				continue
+			}
+			checkID, err := strconv.Atoi(id)
+			if err != nil {
+				return fmt.Errorf("invalid uptimeids")
+			}
+			m.Checks.Uptime = append(m.Checks.Uptime, checkID)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) results(w http.ResponseWriter, r *http.Request, check pingdom.CheckResponse) {
+	from, to := timeRange(r)
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+
+	results := []Result{}
+	for _, result := range s.fixtures.Results[check.ID] {
+		if result.Time >= from && result.Time <= to {
+			results = append(results, result)
+		}
+	}
+	// Pingdom returns the newest results first.
+	sort.Slice(results, func(i, j int) bool { return results[i].Time > results[j].Time })
+
+	if offset > len(results) {
+		offset = len(results)
+	}
+	results = results[offset:]
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"results":      results,
+		"activeprobes": []int{33},
+	})
+}
+
+func (s *Server) summaryAverage(w http.ResponseWriter, r *http.Request, check pingdom.CheckResponse) {
+	from, to := timeRange(r)
+
+	var total, count int
+	for _, result := range s.fixtures.Results[check.ID] {
+		if result.Time >= from && result.Time <= to && result.Status == "up" {
+			total += result.ResponseTime
+			count++
+		}
+	}
+	avgResponse := 0
+	if count > 0 {
+		avgResponse = total / count
+	}
+
+	totals := map[string]int64{}
+	for _, state := range clipStates(s.fixtures.Outages[check.ID], from, to) {
+		totals[state.Status] += state.TimeTo - state.TimeFrom
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"summary": map[string]interface{}{
+			"responsetime": map[string]interface{}{
+				"from":        from,
+				"to":          to,
+				"avgresponse": avgResponse,
+			},
+			"status": map[string]interface{}{
+				"totalup":      totals["up"],
+				"totaldown":    totals["down"],
+				"totalunknown": totals["unknown"],
+			},
+		},
+	})
+}
+
+func (s *Server) summaryPerformance(w http.ResponseWriter, r *http.Request, check pingdom.CheckResponse) {
+	from, to := timeRange(r)
+
+	resolution := r.URL.Query().Get("resolution")
+	var size int64
+	var key string
+	switch resolution {
+	case "", "hour":
+		size, key = 3600, "hours"
+	case "day":
+		size, key = 24*3600, "days"
+	case "week":
+		size, key = 7*24*3600, "weeks"
+	default:
+		writeError(w, http.StatusBadRequest, "Invalid resolution")
+		return
+	}
+
+	bins := []PerformanceBin{}
+	counts := []int{}
+	for _, hour := range s.fixtures.Performance[check.ID] {
+		if hour.StartTime < from || hour.StartTime >= to {
+			continue
+		}
+
+		start := hour.StartTime - hour.StartTime%size
+		if len(bins) == 0 || bins[len(bins)-1].StartTime != start {
+			bins = append(bins, PerformanceBin{StartTime: start})
+			counts = append(counts, 0)
+		}
+
+		bin := &bins[len(bins)-1]
+		bin.AvgResponse += hour.AvgResponse
+		bin.Uptime += hour.Uptime
+		bin.Downtime += hour.Downtime
+		bin.Unmonitored += hour.Unmonitored
+		counts[len(counts)-1]++
+	}
+	for i := range bins {
+		bins[i].AvgResponse /= counts[i]
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"summary": map[string]interface{}{key: bins},
+	})
+}
+
+func (s *Server) summaryOutage(w http.ResponseWriter, r *http.Request, check pingdom.CheckResponse) {
+	from, to := timeRange(r)
+
+	writeJSON(w, map[string]interface{}{
+		"summary": map[string]interface{}{
+			"states": clipStates(s.fixtures.Outages[check.ID], from, to),
+		},
+	})
+}
+
+// timeRange returns the from and to parameters of the request, defaulting to
+// all time.
+func timeRange(r *http.Request) (int64, int64) {
+	from, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		from = 0
+	}
+	to, err := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if err != nil {
+		to = 1<<63 - 1
+	}
+
+	return from, to
+}
+
+// clipStates returns the parts of the states within the time range.
+func clipStates(states []OutageState, from, to int64) []OutageState {
+	clipped := []OutageState{}
+	for _, state := range states {
+		if state.TimeTo <= from || state.TimeFrom >= to {
+			continue
+		}
+		if state.TimeFrom < from {
+			state.TimeFrom = from
+		}
+		if state.TimeTo > to {
+			state.TimeTo = to
+		}
+		clipped = append(clipped, state)
+	}
+
+	return clipped
+}
+
+func hasAnyTag(check pingdom.CheckResponse, tags []string) bool {
+	for _, tag := range check.Tags {
+		for _, t := range tags {
+			if tag.Name == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkJSON returns the check as the Pingdom API serialises it. The type is
+// a name in check lists, and an object holding the type details when a
+// single check is read.
+func checkJSON(check pingdom.CheckResponse, detailed bool) map[string]interface{} {
+	content, _ := json.Marshal(check)
+
+	m := map[string]interface{}{}
+	json.Unmarshal(content, &m)
+
+	if detailed {
+		details := map[string]interface{}{}
+		if check.Type.HTTP != nil {
+			details = m["type"].(map[string]interface{})["http"].(map[string]interface{})
+		}
+		m["type"] = map[string]interface{}{check.Type.Name: details}
+	} else {
+		m["type"] = check.Type.Name
+	}
+
+	return m
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": pingdom.PingdomError{
+			StatusCode: statusCode,
+			StatusDesc: http.StatusText(statusCode),
+			Message:    message,
+		},
+	})
+}
@@ -0,0 +1,200 @@
+package fakepingdom
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+func newTestClient(t *testing.T, s *Server) (*pingdom.Client, func()) {
+	ts := httptest.NewServer(s)
+
+	client := pingdom.NewClient("user@example.com", "secret", "key")
+	baseURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL = baseURL
+
+	return client, ts.Close
+}
+
+func TestListChecks(t *testing.T) {
+	client, done := newTestClient(t, New(DefaultFixtures()))
+	defer done()
+
+	checks, err := client.Checks.List(map[string]string{"include_tags": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(checks) != 5 {
+		t.Fatalf("got %d checks, want 5", len(checks))
+	}
+	if checks[0].Name != "Website" || checks[0].Status != "up" || checks[0].Type.Name != "http" {
+		t.Errorf("unexpected first check %+v", checks[0])
+	}
+	if len(checks[0].Tags) != 2 {
+		t.Errorf("got %d tags, want 2", len(checks[0].Tags))
+	}
+
+	checks, err = client.Checks.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks[0].Tags) != 0 {
+		t.Errorf("got tags without include_tags")
+	}
+
+	checks, err = client.Checks.List(map[string]string{"tags": "team-web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 1 {
+		t.Errorf("got %d checks tagged team-web, want 1", len(checks))
+	}
+}
+
+func TestReadCheck(t *testing.T) {
+	client, done := newTestClient(t, New(DefaultFixtures()))
+	defer done()
+
+	check, err := client.Checks.Read(1001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Type.Name != "http" || check.Type.HTTP == nil || check.Type.HTTP.ShouldContain != "OK" {
+		t.Errorf("unexpected check type %+v", check.Type)
+	}
+
+	if _, err := client.Checks.Read(42); err == nil {
+		t.Errorf("expected an error reading a missing check")
+	}
+}
+
+func TestListContacts(t *testing.T) {
+	client, done := newTestClient(t, New(DefaultFixtures()))
+	defer done()
+
+	contacts, err := client.Contacts.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contacts) != 2 {
+		t.Errorf("got %d contacts, want 2", len(contacts))
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	s := New(DefaultFixtures())
+	s.User = "user@example.com"
+	s.Password = "other"
+	s.AppKey = "key"
+
+	client, done := newTestClient(t, s)
+	defer done()
+
+	_, err := client.Checks.List()
+	pingdomErr, ok := err.(*pingdom.PingdomError)
+	if !ok || pingdomErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got error %v, want 401", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := New(DefaultFixtures())
+	s.SetError("GET", "/api/2.0/checks", http.StatusInternalServerError, "Internal error")
+
+	client, done := newTestClient(t, s)
+	defer done()
+
+	_, err := client.Checks.List()
+	pingdomErr, ok := err.(*pingdom.PingdomError)
+	if !ok || pingdomErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("got error %v, want 500", err)
+	}
+
+	s.ClearErrors()
+	if _, err := client.Checks.List(); err != nil {
+		t.Errorf("got error %v after clearing errors", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	f := DefaultFixtures()
+	f.RateLimit = &RateLimit{Requests: 2}
+
+	client, done := newTestClient(t, New(f))
+	defer done()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Checks.List(); err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+	}
+
+	_, err := client.Checks.List()
+	pingdomErr, ok := err.(*pingdom.PingdomError)
+	if !ok || pingdomErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("got error %v, want 429", err)
+	}
+}
+
+func TestSummaries(t *testing.T) {
+	ts := httptest.NewServer(New(DefaultFixtures()))
+	defer ts.Close()
+
+	get := func(path string, v interface{}) {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s returned %d", path, resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var average struct {
+		Summary struct {
+			Status struct {
+				TotalUp   int64 `json:"totalup"`
+				TotalDown int64 `json:"totaldown"`
+			} `json:"status"`
+		} `json:"summary"`
+	}
+	get("/api/2.0/summary.average/1002", &average)
+	if average.Summary.Status.TotalUp != 20*3600 || average.Summary.Status.TotalDown != 4*3600 {
+		t.Errorf("unexpected average summary %+v", average)
+	}
+
+	var performance struct {
+		Summary struct {
+			Days []PerformanceBin `json:"days"`
+		} `json:"summary"`
+	}
+	get("/api/2.0/summary.performance/1002?resolution=day", &performance)
+	var downtime int
+	for _, day := range performance.Summary.Days {
+		downtime += day.Downtime
+	}
+	if downtime != 4*3600 {
+		t.Errorf("got %d seconds of downtime, want %d", downtime, 4*3600)
+	}
+
+	var results struct {
+		Results []Result `json:"results"`
+	}
+	get("/api/2.0/results/1001?limit=5&offset=1", &results)
+	if len(results.Results) != 5 || results.Results[0].Time <= results.Results[1].Time {
+		t.Errorf("unexpected results %+v", results.Results)
+	}
+}
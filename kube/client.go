@@ -0,0 +1,230 @@
+package kube
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+	// watchTimeoutSeconds is how long the Kubernetes API keeps a watch open.
+	watchTimeoutSeconds = 300
+)
+
+// Config represents the configuration used to create a Client.
+type Config struct {
+	// URL is the URL of the Kubernetes API, e.g. https://kubernetes.default.svc
+	// or http://localhost:8001 for kubectl proxy.
+	URL string
+	// TokenFile is the file of the bearer token, read on every request so
+	// that rotated tokens are used. Optional.
+	TokenFile string
+	// CAFile is the file of the CA certificates verifying the Kubernetes API.
+	// The system certificates are used if it is empty.
+	CAFile string
+	// Timeout is the time limit of list requests. Watches are given the
+	// watch timeout of the Kubernetes API in addition.
+	Timeout time.Duration
+	Logger  log.Logger
+}
+
+// DefaultConfig provides a default configuration to create a Client.
+func DefaultConfig() Config {
+	return Config{
+		URL:       "",
+		TokenFile: "",
+		CAFile:    "",
+		Timeout:   time.Minute,
+		Logger:    log.NewNopLogger(),
+	}
+}
+
+// InClusterConfig returns the configuration of a Client running in a pod,
+// using its service account.
+func InClusterConfig() (Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return Config{}, fmt.Errorf("not running in a Kubernetes cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+
+	c := DefaultConfig()
+	c.URL = "https://" + net.JoinHostPort(host, port)
+	c.TokenFile = serviceAccountDir + "token"
+	c.CAFile = serviceAccountDir + "ca.crt"
+
+	return c, nil
+}
+
+// Client lists and watches objects with the Kubernetes API.
+type Client struct {
+	url        *url.URL
+	tokenFile  string
+	timeout    time.Duration
+	httpClient *http.Client
+	logger     log.Logger
+}
+
+// NewClient creates a new configured Client.
+func NewClient(config Config) (*Client, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("config.URL must not be empty")
+	}
+	if config.Timeout <= 0 {
+		return nil, fmt.Errorf("config.Timeout must be positive")
+	}
+	if config.Logger == nil {
+		return nil, fmt.Errorf("config.Logger must not be empty")
+	}
+
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %v", config.URL, err)
+	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	if config.CAFile != "" {
+		pem, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", config.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	c := &Client{
+		url:        u,
+		tokenFile:  config.TokenFile,
+		timeout:    config.Timeout,
+		httpClient: &http.Client{Transport: transport},
+		logger:     config.Logger,
+	}
+
+	return c, nil
+}
+
+// List implements Interface.
+func (c *Client) List(ctx context.Context, resource Resource, namespace string) (*ObjectList, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.get(ctx, resource, namespace, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	list := &ObjectList{}
+	if err := json.NewDecoder(resp.Body).Decode(list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Watch implements Interface.
+func (c *Client) Watch(ctx context.Context, resource Resource, namespace, resourceVersion string) (<-chan Event, error) {
+	params := url.Values{}
+	params.Set("watch", "true")
+	params.Set("resourceVersion", resourceVersion)
+	params.Set("timeoutSeconds", fmt.Sprint(watchTimeoutSeconds))
+
+	// The Kubernetes API ends the watch after the watch timeout, the
+	// deadline ends it if the API stops answering.
+	ctx, cancel := context.WithTimeout(ctx, watchTimeoutSeconds*time.Second+c.timeout)
+	resp, err := c.get(ctx, resource, namespace, params)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer cancel()
+		defer close(events)
+		defer resp.Body.Close()
+
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var event Event
+			if err := decoder.Decode(&event); err != nil {
+				if ctx.Err() == nil {
+					level.Debug(c.logger).Log("msg", "watch ended", "resource", resource.Plural, "err", err)
+				}
+				return
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// get sends a GET request for the objects of the resource, and returns the
+// response if it succeeded.
+func (c *Client) get(ctx context.Context, resource Resource, namespace string, params url.Values) (*http.Response, error) {
+	path := resource.Prefix
+	if namespace != "" {
+		path += "/namespaces/" + namespace
+	}
+	path += "/" + resource.Plural
+
+	u := *c.url
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.tokenFile != "" {
+		token, err := ioutil.ReadFile(c.tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		status := struct {
+			Message string `json:"message"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil || status.Message == "" {
+			return nil, fmt.Errorf("unexpected response %s", resp.Status)
+		}
+
+		return nil, fmt.Errorf("%s: %s", resp.Status, status.Message)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,120 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"kind": "Status", "message": "Unauthorized"}`)
+			return
+		}
+		if r.URL.Path != "/apis/networking.k8s.io/v1/namespaces/shop/ingresses" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"kind": "Status", "message": "not found"}`)
+			return
+		}
+
+		if r.URL.Query().Get("watch") != "true" {
+			fmt.Fprint(w, `{"metadata": {"resourceVersion": "10"}, "items": [
+				{"metadata": {"name": "web", "namespace": "shop", "annotations": {"a": "b"}}, "spec": {"rules": [{"host": "shop.example.com"}]}}
+			]}`)
+			return
+		}
+
+		if r.URL.Query().Get("resourceVersion") != "10" {
+			t.Errorf("unexpected resource version %q", r.URL.Query().Get("resourceVersion"))
+		}
+		fmt.Fprintln(w, `{"type": "MODIFIED", "object": {"metadata": {"name": "web", "namespace": "shop", "resourceVersion": "11"}}}`)
+		w.(http.Flusher).Flush()
+		fmt.Fprintln(w, `{"type": "DELETED", "object": {"metadata": {"name": "web", "namespace": "shop", "resourceVersion": "12"}}}`)
+	}))
+	defer server.Close()
+
+	tokenFile, err := ioutil.TempFile("", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tokenFile.Name())
+	tokenFile.WriteString("token\n")
+	tokenFile.Close()
+
+	c := DefaultConfig()
+	c.URL = server.URL
+	c.TokenFile = tokenFile.Name()
+	client, err := NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	list, err := client.List(ctx, Ingresses, "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Metadata.ResourceVersion != "10" || len(list.Items) != 1 || list.Items[0].Key() != "shop/web" {
+		t.Fatalf("unexpected list %+v", list)
+	}
+	if list.Items[0].Metadata.Annotations["a"] != "b" {
+		t.Errorf("unexpected annotations %v", list.Items[0].Metadata.Annotations)
+	}
+
+	events, err := client.Watch(ctx, Ingresses, "shop", list.Metadata.ResourceVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []EventType
+	for event := range events {
+		types = append(types, event.Type)
+	}
+	if len(types) != 2 || types[0] != Modified || types[1] != Deleted {
+		t.Errorf("unexpected events %v", types)
+	}
+
+	if _, err := client.List(ctx, Services, ""); err == nil || err.Error() != "404 Not Found: not found" {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	stop := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stop
+	}))
+	defer server.Close()
+	defer close(stop)
+
+	c := DefaultConfig()
+	c.URL = server.URL
+	c.Timeout = 100 * time.Millisecond
+	client, err := NewClient(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.List(context.Background(), Ingresses, "")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("expected an error for an API not answering")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("list did not time out")
+	}
+}
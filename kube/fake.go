@@ -0,0 +1,136 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Fake implements Interface in memory, for tests. Changes made with Apply and
+// Delete are sent to the open watches.
+type Fake struct {
+	mu              sync.Mutex
+	resourceVersion int
+	objects         map[string]map[string]Object
+	watchers        map[string][]*fakeWatcher
+}
+
+type fakeWatcher struct {
+	namespace string
+	events    chan Event
+	done      <-chan struct{}
+}
+
+// NewFake returns a Fake without objects.
+func NewFake() *Fake {
+	return &Fake{
+		objects:  map[string]map[string]Object{},
+		watchers: map[string][]*fakeWatcher{},
+	}
+}
+
+// Apply creates or replaces an object.
+func (f *Fake) Apply(resource Resource, o Object) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.objects[resource.Plural] == nil {
+		f.objects[resource.Plural] = map[string]Object{}
+	}
+
+	eventType := Added
+	if _, ok := f.objects[resource.Plural][o.Key()]; ok {
+		eventType = Modified
+	}
+
+	f.resourceVersion++
+	o.Metadata.ResourceVersion = strconv.Itoa(f.resourceVersion)
+	f.objects[resource.Plural][o.Key()] = o
+
+	f.notify(resource, Event{Type: eventType, Object: o})
+}
+
+// Delete deletes an object.
+func (f *Fake) Delete(resource Resource, namespace, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := Object{Metadata: ObjectMeta{Namespace: namespace, Name: name}}.Key()
+	o, ok := f.objects[resource.Plural][key]
+	if !ok {
+		return fmt.Errorf("%s %s not found", resource.Kind, key)
+	}
+	delete(f.objects[resource.Plural], key)
+
+	f.resourceVersion++
+	f.notify(resource, Event{Type: Deleted, Object: o})
+
+	return nil
+}
+
+// CloseWatches ends the open watches, like the Kubernetes API does when they
+// time out.
+func (f *Fake) CloseWatches() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, watchers := range f.watchers {
+		for _, w := range watchers {
+			close(w.events)
+		}
+	}
+	f.watchers = map[string][]*fakeWatcher{}
+}
+
+// List implements Interface.
+func (f *Fake) List(ctx context.Context, resource Resource, namespace string) (*ObjectList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	list := &ObjectList{}
+	list.Metadata.ResourceVersion = strconv.Itoa(f.resourceVersion)
+	for _, o := range f.objects[resource.Plural] {
+		if namespace == "" || o.Metadata.Namespace == namespace {
+			list.Items = append(list.Items, o)
+		}
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Key() < list.Items[j].Key() })
+
+	return list, nil
+}
+
+// Watch implements Interface. Changes are sent from the time of the call,
+// whatever the resource version.
+func (f *Fake) Watch(ctx context.Context, resource Resource, namespace, resourceVersion string) (<-chan Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := &fakeWatcher{
+		namespace: namespace,
+		events:    make(chan Event, 100),
+		done:      ctx.Done(),
+	}
+	f.watchers[resource.Plural] = append(f.watchers[resource.Plural], w)
+
+	return w.events, nil
+}
+
+func (f *Fake) notify(resource Resource, event Event) {
+	watchers := f.watchers[resource.Plural][:0]
+	for _, w := range f.watchers[resource.Plural] {
+		select {
+		case <-w.done:
+			close(w.events)
+			continue
+		default:
+		}
+
+		if w.namespace == "" || w.namespace == event.Object.Metadata.Namespace {
+			w.events <- event
+		}
+		watchers = append(watchers, w)
+	}
+	f.watchers[resource.Plural] = watchers
+}
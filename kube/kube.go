@@ -0,0 +1,91 @@
+// Package kube implements the small part of the Kubernetes API used by the
+// controller: listing and watching objects, without the client libraries.
+package kube
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Resource is a kind of Kubernetes object.
+type Resource struct {
+	Kind string
+	// Prefix is the path of the API group and version, e.g. /api/v1.
+	Prefix string
+	// Plural is the name of the resource in paths, e.g. services.
+	Plural string
+}
+
+// Resources the controller can watch.
+var (
+	Ingresses = Resource{Kind: "Ingress", Prefix: "/apis/networking.k8s.io/v1", Plural: "ingresses"}
+	Services  = Resource{Kind: "Service", Prefix: "/api/v1", Plural: "services"}
+	// HTTPRoutes are the routes of the Gateway API.
+	HTTPRoutes = Resource{Kind: "HTTPRoute", Prefix: "/apis/gateway.networking.k8s.io/v1", Plural: "httproutes"}
+)
+
+// Object is a Kubernetes object. The spec and status are decoded by the users
+// of the object, depending on its kind.
+type Object struct {
+	Metadata ObjectMeta      `json:"metadata"`
+	Spec     json.RawMessage `json:"spec,omitempty"`
+	Status   json.RawMessage `json:"status,omitempty"`
+}
+
+// ObjectMeta is the metadata of an object.
+type ObjectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	UID             string            `json:"uid,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+// Key identifies the object among the objects of its resource.
+func (o Object) Key() string {
+	if o.Metadata.Namespace == "" {
+		return o.Metadata.Name
+	}
+
+	return o.Metadata.Namespace + "/" + o.Metadata.Name
+}
+
+// ObjectList is the result of listing objects.
+type ObjectList struct {
+	Metadata struct {
+		// ResourceVersion is the version to watch changes from.
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []Object `json:"items"`
+}
+
+// EventType is the type of a watch event.
+type EventType string
+
+// Types of watch events.
+const (
+	Added    EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted  EventType = "DELETED"
+	Bookmark EventType = "BOOKMARK"
+	Error    EventType = "ERROR"
+)
+
+// Event is a change to an object.
+type Event struct {
+	Type   EventType `json:"type"`
+	Object Object    `json:"object"`
+}
+
+// Interface lists and watches Kubernetes objects. Client implements it with
+// the Kubernetes API, and Fake in memory.
+type Interface interface {
+	// List returns the objects of the resource in the namespace, or in all
+	// namespaces if it is empty.
+	List(ctx context.Context, resource Resource, namespace string) (*ObjectList, error)
+	// Watch returns the changes to the objects since the resource version.
+	// The channel is closed when the watch ends, e.g. when ctx is done or
+	// the Kubernetes API closes the watch, after which the objects must be
+	// listed again.
+	Watch(ctx context.Context, resource Resource, namespace, resourceVersion string) (<-chan Event, error)
+}
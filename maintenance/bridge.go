@@ -0,0 +1,534 @@
+// Package maintenance keeps Pingdom maintenance windows and Alertmanager
+// silences in sync, so that planned work silenced on one side does not page
+// on the other.
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/alertmanager"
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+	"github.com/giantswarm/prometheus-pingdom-exporter/rules"
+)
+
+// DefaultCreatedBy is the creator of the silences created by the bridge.
+const DefaultCreatedBy = "pingdom-exporter"
+
+const (
+	// silencePrefix starts the description of the maintenance windows
+	// created for silences, followed by the ID of the silence.
+	silencePrefix = "Alertmanager silence "
+	// windowPrefix starts the comment of the silences created for
+	// maintenance windows, followed by the ID of the window.
+	windowPrefix = "Pingdom maintenance window "
+)
+
+// checkLabelNames are the labels identifying checks. Silences only create
+// maintenance windows if they match one of them, so that a silence of all
+// warnings does not pause every check.
+var checkLabelNames = map[string]bool{
+	"id":       true,
+	"check_id": true,
+	"name":     true,
+	"hostname": true,
+	"tags":     true,
+}
+
+// PingdomAPI is the part of the Pingdom API used by the bridge.
+// exporter.Client implements it.
+type PingdomAPI interface {
+	ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error)
+	ListMaintenance(ctx context.Context) ([]exporter.Maintenance, error)
+	CreateMaintenance(ctx context.Context, window exporter.Maintenance) (int, error)
+	UpdateMaintenance(ctx context.Context, window exporter.Maintenance) error
+	DeleteMaintenance(ctx context.Context, id int) error
+}
+
+// AlertmanagerAPI is the part of the Alertmanager API used by the bridge.
+// alertmanager.Client implements it.
+type AlertmanagerAPI interface {
+	ListSilences(ctx context.Context) ([]alertmanager.Silence, error)
+	CreateSilence(ctx context.Context, silence alertmanager.Silence) (string, error)
+	ExpireSilence(ctx context.Context, id string) error
+}
+
+// Config represents the configuration used to create a Bridge.
+type Config struct {
+	Pingdom      PingdomAPI
+	Alertmanager AlertmanagerAPI
+	Logger       log.Logger
+
+	// Rules holds the label templates of the alerts, which silences are
+	// matched against, together with the labels of the check metrics.
+	Rules *rules.File
+	// CreatedBy is the creator of the silences created by the bridge.
+	CreatedBy string
+	// Silences creates maintenance windows for the silences matching checks.
+	Silences bool
+	// Windows creates silences for the maintenance windows.
+	Windows bool
+	// Interval is the time between syncs.
+	Interval time.Duration
+	// DryRun logs the changes without applying them.
+	DryRun bool
+}
+
+// DefaultConfig provides a default configuration to create a Bridge.
+func DefaultConfig() Config {
+	return Config{
+		Pingdom:      nil,
+		Alertmanager: nil,
+		Logger:       log.NewNopLogger(),
+
+		Rules:     rules.DefaultFile(),
+		CreatedBy: DefaultCreatedBy,
+		Silences:  true,
+		Windows:   true,
+		Interval:  time.Minute,
+		DryRun:    false,
+	}
+}
+
+// Bridge creates and ends Pingdom maintenance windows for Alertmanager
+// silences, and silences for maintenance windows. The windows and silences
+// it creates reference the silence or window they were created for, so the
+// bridge keeps no state.
+type Bridge struct {
+	pingdom      PingdomAPI
+	alertmanager AlertmanagerAPI
+	logger       log.Logger
+
+	rules     *rules.File
+	createdBy string
+	silences  bool
+	windows   bool
+	interval  time.Duration
+	dryRun    bool
+}
+
+// New creates a new configured Bridge.
+func New(config Config) (*Bridge, error) {
+	if config.Pingdom == nil {
+		return nil, fmt.Errorf("config.Pingdom must not be empty")
+	}
+	if config.Alertmanager == nil {
+		return nil, fmt.Errorf("config.Alertmanager must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, fmt.Errorf("config.Logger must not be empty")
+	}
+	if config.Rules == nil {
+		return nil, fmt.Errorf("config.Rules must not be empty")
+	}
+	if config.CreatedBy == "" {
+		return nil, fmt.Errorf("config.CreatedBy must not be empty")
+	}
+	if !config.Silences && !config.Windows {
+		return nil, fmt.Errorf("config.Silences or config.Windows must be true")
+	}
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("config.Interval must be positive")
+	}
+
+	b := &Bridge{
+		pingdom:      config.Pingdom,
+		alertmanager: config.Alertmanager,
+		logger:       config.Logger,
+
+		rules:     config.Rules,
+		createdBy: config.CreatedBy,
+		silences:  config.Silences,
+		windows:   config.Windows,
+		interval:  config.Interval,
+		dryRun:    config.DryRun,
+	}
+
+	return b, nil
+}
+
+// Run syncs every interval until ctx is done.
+func (b *Bridge) Run(ctx context.Context) {
+	for {
+		if err := b.Sync(ctx); err != nil && ctx.Err() == nil {
+			level.Error(b.logger).Log("msg", "failed to sync maintenance windows and silences", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.interval):
+		}
+	}
+}
+
+// Sync creates, updates and ends the maintenance windows and silences once.
+// It returns the last error, after trying every change.
+func (b *Bridge) Sync(ctx context.Context) error {
+	now := time.Now()
+
+	windows, err := b.pingdom.ListMaintenance(ctx)
+	if err != nil {
+		return err
+	}
+	silences, err := b.alertmanager.ListSilences(ctx)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	if b.silences {
+		checks, err := b.pingdom.ListChecks(ctx, map[string]string{"include_tags": "true"})
+		if err != nil {
+			return err
+		}
+		if err := b.syncSilences(ctx, now, checks, windows, silences); err != nil {
+			lastErr = err
+		}
+	}
+	if b.windows {
+		if err := b.syncWindows(ctx, now, windows, silences); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// syncSilences creates and updates a maintenance window for every silence
+// matching checks, and ends the windows of silences which expired.
+func (b *Bridge) syncSilences(ctx context.Context, now time.Time, checks []pingdom.CheckResponse, windows []exporter.Maintenance, silences []alertmanager.Silence) error {
+	var lastErr error
+
+	bridged := map[string]exporter.Maintenance{}
+	for _, w := range windows {
+		if id, ok := silenceID(w.Description); ok {
+			bridged[id] = w
+		}
+	}
+
+	active := map[string]bool{}
+	for _, s := range silences {
+		if s.CreatedBy == b.createdBy || s.State() == alertmanager.SilenceExpired || !s.EndsAt.After(now) {
+			continue
+		}
+		if !selectsChecks(s.Matchers) {
+			continue
+		}
+
+		ids, err := b.matchingChecks(s, checks)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to match silence with checks", "silence", s.ID, "err", err)
+			continue
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		active[s.ID] = true
+
+		desired := exporter.Maintenance{
+			Description: silencePrefix + s.ID,
+			From:        s.StartsAt.Unix(),
+			To:          s.EndsAt.Unix(),
+			Checks:      exporter.MaintenanceChecks{Uptime: ids},
+		}
+		if s.Comment != "" {
+			desired.Description += ": " + s.Comment
+		}
+		if s.StartsAt.Before(now) {
+			desired.From = now.Unix()
+		}
+
+		w, ok := bridged[s.ID]
+		if !ok {
+			if err := b.createWindow(ctx, s.ID, desired); err != nil {
+				lastErr = err
+			}
+			continue
+		}
+
+		desired.ID = w.ID
+		if w.From <= now.Unix() {
+			// The start of windows in progress is kept.
+			desired.From = w.From
+		}
+		if sameWindow(w, desired) {
+			continue
+		}
+		if err := b.updateWindow(ctx, "updated", desired); err != nil {
+			lastErr = err
+		}
+	}
+
+	for id, w := range bridged {
+		if active[id] || w.To <= now.Unix() {
+			continue
+		}
+
+		var err error
+		if w.From >= now.Unix() {
+			// Windows which did not start yet cannot end before they start.
+			err = b.deleteWindow(ctx, w)
+		} else {
+			w.To = now.Unix()
+			err = b.updateWindow(ctx, "ended", w)
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// syncWindows creates and updates a silence for every maintenance window
+// which is not over, and expires the silences of windows which were deleted
+// or ended early. Recurring windows are not bridged.
+func (b *Bridge) syncWindows(ctx context.Context, now time.Time, windows []exporter.Maintenance, silences []alertmanager.Silence) error {
+	var lastErr error
+
+	bridged := map[int]alertmanager.Silence{}
+	for _, s := range silences {
+		if s.CreatedBy != b.createdBy || s.State() == alertmanager.SilenceExpired {
+			continue
+		}
+		if id, ok := windowID(s.Comment); ok {
+			bridged[id] = s
+		}
+	}
+
+	current := map[int]bool{}
+	for _, w := range windows {
+		if _, ok := silenceID(w.Description); ok {
+			continue
+		}
+		if w.RecurrenceType != "" && w.RecurrenceType != "none" {
+			level.Debug(b.logger).Log("msg", "ignoring recurring maintenance window", "window", w.ID)
+			continue
+		}
+		if w.To <= now.Unix() || len(w.Checks.Uptime) == 0 {
+			continue
+		}
+		current[w.ID] = true
+
+		desired := alertmanager.Silence{
+			Matchers:  idMatchers(w.Checks.Uptime),
+			StartsAt:  time.Unix(w.From, 0),
+			EndsAt:    time.Unix(w.To, 0),
+			CreatedBy: b.createdBy,
+			Comment:   fmt.Sprintf("%s%d: %s", windowPrefix, w.ID, w.Description),
+		}
+
+		s, ok := bridged[w.ID]
+		if ok {
+			if sameSilence(s, desired, now) {
+				continue
+			}
+			desired.ID = s.ID
+		}
+		if err := b.createSilence(ctx, w.ID, desired); err != nil {
+			lastErr = err
+		}
+	}
+
+	for id, s := range bridged {
+		if current[id] {
+			continue
+		}
+		if err := b.expireSilence(ctx, id, s); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+func (b *Bridge) createWindow(ctx context.Context, silence string, w exporter.Maintenance) error {
+	if b.dryRun {
+		level.Info(b.logger).Log("msg", "would create maintenance window", "silence", silence, "checks", fmt.Sprint(w.Checks.Uptime))
+		return nil
+	}
+
+	id, err := b.pingdom.CreateMaintenance(ctx, w)
+	if err != nil {
+		level.Error(b.logger).Log("msg", "failed to create maintenance window", "silence", silence, "err", err)
+		return err
+	}
+	level.Info(b.logger).Log("msg", "created maintenance window", "silence", silence, "window", id, "checks", fmt.Sprint(w.Checks.Uptime))
+
+	return nil
+}
+
+func (b *Bridge) updateWindow(ctx context.Context, action string, w exporter.Maintenance) error {
+	if b.dryRun {
+		level.Info(b.logger).Log("msg", "would update maintenance window", "window", w.ID, "action", action)
+		return nil
+	}
+
+	if err := b.pingdom.UpdateMaintenance(ctx, w); err != nil {
+		level.Error(b.logger).Log("msg", "failed to update maintenance window", "window", w.ID, "err", err)
+		return err
+	}
+	level.Info(b.logger).Log("msg", action+" maintenance window", "window", w.ID, "checks", fmt.Sprint(w.Checks.Uptime))
+
+	return nil
+}
+
+func (b *Bridge) deleteWindow(ctx context.Context, w exporter.Maintenance) error {
+	if b.dryRun {
+		level.Info(b.logger).Log("msg", "would delete maintenance window", "window", w.ID)
+		return nil
+	}
+
+	if err := b.pingdom.DeleteMaintenance(ctx, w.ID); err != nil {
+		level.Error(b.logger).Log("msg", "failed to delete maintenance window", "window", w.ID, "err", err)
+		return err
+	}
+	level.Info(b.logger).Log("msg", "deleted maintenance window", "window", w.ID)
+
+	return nil
+}
+
+func (b *Bridge) createSilence(ctx context.Context, window int, s alertmanager.Silence) error {
+	if b.dryRun {
+		level.Info(b.logger).Log("msg", "would create silence", "window", window)
+		return nil
+	}
+
+	id, err := b.alertmanager.CreateSilence(ctx, s)
+	if err != nil {
+		level.Error(b.logger).Log("msg", "failed to create silence", "window", window, "err", err)
+		return err
+	}
+	level.Info(b.logger).Log("msg", "created silence", "window", window, "silence", id)
+
+	return nil
+}
+
+func (b *Bridge) expireSilence(ctx context.Context, window int, s alertmanager.Silence) error {
+	if b.dryRun {
+		level.Info(b.logger).Log("msg", "would expire silence", "window", window, "silence", s.ID)
+		return nil
+	}
+
+	if err := b.alertmanager.ExpireSilence(ctx, s.ID); err != nil {
+		level.Error(b.logger).Log("msg", "failed to expire silence", "window", window, "silence", s.ID, "err", err)
+		return err
+	}
+	level.Info(b.logger).Log("msg", "expired silence", "window", window, "silence", s.ID)
+
+	return nil
+}
+
+// matchingChecks returns the sorted IDs of the checks whose alert labels
+// match all the matchers of the silence.
+func (b *Bridge) matchingChecks(s alertmanager.Silence, checks []pingdom.CheckResponse) ([]int, error) {
+	var ids []int
+
+	for _, check := range checks {
+		labels, err := alertmanager.CheckLabels(b.rules, check)
+		if err != nil {
+			return nil, err
+		}
+
+		matches := true
+		for _, m := range s.Matchers {
+			ok, err := m.Matches(labels)
+			if err != nil {
+				return nil, err
+			}
+			matches = matches && ok
+		}
+		if matches {
+			ids = append(ids, check.ID)
+		}
+	}
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
+// selectsChecks returns true if a matcher selects checks by one of the
+// labels identifying them.
+func selectsChecks(matchers []alertmanager.Matcher) bool {
+	for _, m := range matchers {
+		if m.Equal() && checkLabelNames[m.Name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// idMatchers returns the matchers of the alerts of the checks.
+func idMatchers(ids []int) []alertmanager.Matcher {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
+	}
+	sort.Strings(values)
+
+	if len(values) == 1 {
+		return []alertmanager.Matcher{{Name: "id", Value: values[0]}}
+	}
+
+	return []alertmanager.Matcher{{Name: "id", Value: strings.Join(values, "|"), IsRegex: true}}
+}
+
+// silenceID returns the ID of the silence a maintenance window was created
+// for, from its description.
+func silenceID(description string) (string, bool) {
+	if !strings.HasPrefix(description, silencePrefix) {
+		return "", false
+	}
+	id := strings.SplitN(strings.TrimPrefix(description, silencePrefix), ":", 2)[0]
+
+	return id, id != ""
+}
+
+// windowID returns the ID of the maintenance window a silence was created
+// for, from its comment.
+func windowID(comment string) (int, bool) {
+	if !strings.HasPrefix(comment, windowPrefix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.SplitN(strings.TrimPrefix(comment, windowPrefix), ":", 2)[0])
+
+	return id, err == nil
+}
+
+func sameWindow(a, b exporter.Maintenance) bool {
+	uptime := append([]int(nil), a.Checks.Uptime...)
+	sort.Ints(uptime)
+
+	return a.Description == b.Description && a.From == b.From && a.To == b.To && reflect.DeepEqual(uptime, b.Checks.Uptime)
+}
+
+// sameSilence returns true if the silence matches the desired one. The start
+// of silences in progress is ignored, as Alertmanager sets it to their
+// creation time.
+func sameSilence(s, desired alertmanager.Silence, now time.Time) bool {
+	if desired.StartsAt.After(now) && !s.StartsAt.Equal(desired.StartsAt) {
+		return false
+	}
+	if len(s.Matchers) != len(desired.Matchers) {
+		return false
+	}
+	for i := range s.Matchers {
+		if s.Matchers[i].Name != desired.Matchers[i].Name || s.Matchers[i].Value != desired.Matchers[i].Value ||
+			s.Matchers[i].IsRegex != desired.Matchers[i].IsRegex || !s.Matchers[i].Equal() {
+			return false
+		}
+	}
+
+	return s.EndsAt.Equal(desired.EndsAt) && s.Comment == desired.Comment
+}
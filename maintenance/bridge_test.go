@@ -0,0 +1,191 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/alertmanager"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom/fakepingdomtest"
+)
+
+// fakeAlertmanager keeps silences in memory.
+type fakeAlertmanager struct {
+	silences []alertmanager.Silence
+	nextID   int
+}
+
+func (a *fakeAlertmanager) ListSilences(ctx context.Context) ([]alertmanager.Silence, error) {
+	return append([]alertmanager.Silence(nil), a.silences...), nil
+}
+
+func (a *fakeAlertmanager) CreateSilence(ctx context.Context, silence alertmanager.Silence) (string, error) {
+	if silence.ID != "" {
+		a.ExpireSilence(ctx, silence.ID)
+	}
+
+	a.nextID++
+	silence.ID = fmt.Sprintf("s%d", a.nextID)
+	silence.Status = &alertmanager.SilenceStatus{State: alertmanager.SilenceActive}
+	a.silences = append(a.silences, silence)
+
+	return silence.ID, nil
+}
+
+func (a *fakeAlertmanager) ExpireSilence(ctx context.Context, id string) error {
+	for i := range a.silences {
+		if a.silences[i].ID == id {
+			a.silences[i].Status = &alertmanager.SilenceStatus{State: alertmanager.SilenceExpired}
+		}
+	}
+
+	return nil
+}
+
+// active returns the silences which are not expired.
+func (a *fakeAlertmanager) active() []alertmanager.Silence {
+	var active []alertmanager.Silence
+	for _, s := range a.silences {
+		if s.State() != alertmanager.SilenceExpired {
+			active = append(active, s)
+		}
+	}
+
+	return active
+}
+
+func newTestBridge(t *testing.T, fixtures *fakepingdom.Fixtures) (*Bridge, *fakepingdom.Server, *fakeAlertmanager, func()) {
+	fake := fakepingdomtest.NewServer(t, fixtures)
+
+	am := &fakeAlertmanager{}
+	c := DefaultConfig()
+	c.Pingdom = fake.Client
+	c.Alertmanager = am
+	b, err := New(c)
+	if err != nil {
+		fake.Close()
+		t.Fatal(err)
+	}
+
+	return b, fake.Server, am, fake.Close
+}
+
+func TestSyncSilences(t *testing.T) {
+	b, fake, am, done := newTestBridge(t, fakepingdom.DefaultFixtures())
+	defer done()
+
+	ctx := context.Background()
+	now := time.Now()
+	isEqual := false
+	am.silences = []alertmanager.Silence{
+		{
+			ID:        "prod",
+			Matchers:  []alertmanager.Matcher{{Name: "tags", Value: ".*production.*", IsRegex: true}},
+			StartsAt:  now.Add(-time.Minute),
+			EndsAt:    now.Add(time.Hour),
+			CreatedBy: "jane",
+			Comment:   "Database upgrade",
+			Status:    &alertmanager.SilenceStatus{State: alertmanager.SilenceActive},
+		},
+		// Silences not selecting checks are ignored.
+		{
+			ID:       "warnings",
+			Matchers: []alertmanager.Matcher{{Name: "severity", Value: "warning"}, {Name: "id", Value: "1001", IsEqual: &isEqual}},
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now.Add(time.Hour),
+			Status:   &alertmanager.SilenceStatus{State: alertmanager.SilenceActive},
+		},
+	}
+
+	if err := b.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	windows := fake.Maintenance()
+	if len(windows) != 1 {
+		t.Fatalf("expected 1 maintenance window, got %+v", windows)
+	}
+	w := windows[0]
+	if w.Description != "Alertmanager silence prod: Database upgrade" || !reflect.DeepEqual(w.Checks.Uptime, []int{1001, 1002}) || w.To != now.Add(time.Hour).Unix() {
+		t.Errorf("unexpected maintenance window %+v", w)
+	}
+	// The silence does not come back as a silence of the window.
+	if active := am.active(); len(active) != 2 {
+		t.Errorf("expected no new silence, got %+v", active)
+	}
+
+	// Syncing again changes nothing.
+	if err := b.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if windows := fake.Maintenance(); !reflect.DeepEqual(windows, []fakepingdom.Maintenance{w}) {
+		t.Errorf("expected the window to be unchanged, got %+v", windows)
+	}
+
+	// Extending the silence extends the window.
+	am.silences[0].EndsAt = now.Add(2 * time.Hour)
+	if err := b.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if windows := fake.Maintenance(); windows[0].To != now.Add(2*time.Hour).Unix() || windows[0].From != w.From {
+		t.Errorf("expected the window to end later, got %+v", windows[0])
+	}
+
+	// Expiring the silence ends the window, or deletes it if it did not
+	// start yet.
+	am.ExpireSilence(ctx, "prod")
+	if err := b.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if windows := fake.Maintenance(); len(windows) == 1 && windows[0].To > time.Now().Unix() {
+		t.Errorf("expected the window to end, got %+v", windows[0])
+	}
+}
+
+func TestSyncWindows(t *testing.T) {
+	now := time.Now()
+	fixtures := fakepingdom.DefaultFixtures()
+	fixtures.Maintenance = []fakepingdom.Maintenance{
+		{ID: 1, Description: "Migration", From: now.Add(time.Hour).Unix(), To: now.Add(2 * time.Hour).Unix(), RecurrenceType: "none", Checks: fakepingdom.MaintenanceChecks{Uptime: []int{1002, 1001}}},
+		{ID: 2, Description: "Weekly backup", From: now.Add(-time.Hour).Unix(), To: now.Add(time.Hour).Unix(), RecurrenceType: "week", Checks: fakepingdom.MaintenanceChecks{Uptime: []int{1001}}},
+		{ID: 3, Description: "Past", From: now.Add(-2 * time.Hour).Unix(), To: now.Add(-time.Hour).Unix(), RecurrenceType: "none", Checks: fakepingdom.MaintenanceChecks{Uptime: []int{1001}}},
+	}
+	b, _, am, done := newTestBridge(t, fixtures)
+	defer done()
+
+	ctx := context.Background()
+	if err := b.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	active := am.active()
+	if len(active) != 1 {
+		t.Fatalf("expected 1 silence, got %+v", active)
+	}
+	s := active[0]
+	if !reflect.DeepEqual(s.Matchers, []alertmanager.Matcher{{Name: "id", Value: "1001|1002", IsRegex: true}}) {
+		t.Errorf("unexpected matchers %+v", s.Matchers)
+	}
+	if s.CreatedBy != DefaultCreatedBy || !strings.HasPrefix(s.Comment, "Pingdom maintenance window 1: ") || s.EndsAt.Unix() != now.Add(2*time.Hour).Unix() {
+		t.Errorf("unexpected silence %+v", s)
+	}
+
+	// Syncing again changes nothing.
+	if err := b.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(am.silences) != 1 {
+		t.Errorf("expected the silence to be unchanged, got %+v", am.silences)
+	}
+
+	// Deleting the window expires the silence.
+	fixtures.Maintenance = fixtures.Maintenance[1:]
+	if err := b.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if active := am.active(); len(active) != 0 {
+		t.Errorf("expected the silence to expire, got %+v", active)
+	}
+}
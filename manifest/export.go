@@ -0,0 +1,126 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+// ExportAPI is the part of the Pingdom API used to export checks and
+// contacts. exporter.Client implements it.
+type ExportAPI interface {
+	ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error)
+	ReadCheck(ctx context.Context, id int) (*pingdom.CheckResponse, error)
+	ListContacts(ctx context.Context) ([]pingdom.ContactResponse, error)
+}
+
+// ExportChecks reads the details of every check, and returns their
+// definitions without the managed tag. Checks are sorted by name, then
+// hostname, so that exports of the same account can be diffed. HTTP basic
+// authentication passwords are left out unless includeSecrets is set.
+func ExportChecks(ctx context.Context, api ExportAPI, managedTag string, includeSecrets bool) ([]Check, error) {
+	checks, err := api.ListChecks(ctx, map[string]string{"include_tags": "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []Check
+	for _, check := range checks {
+		details, err := api.ReadCheck(ctx, check.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read check %d: %v", check.ID, err)
+		}
+		if len(details.Tags) == 0 {
+			details.Tags = check.Tags
+		}
+
+		def := FromCheck(*details, managedTag)
+		if def.HTTP != nil && len(def.HTTP.RequestHeaders) == 0 {
+			def.HTTP.RequestHeaders = nil
+		}
+		if def.HTTP != nil && !includeSecrets {
+			def.HTTP.Password = ""
+		}
+		defs = append(defs, def)
+	}
+
+	sort.SliceStable(defs, func(i, j int) bool {
+		if defs[i].Name != defs[j].Name {
+			return defs[i].Name < defs[j].Name
+		}
+		return defs[i].Hostname < defs[j].Hostname
+	})
+
+	return defs, nil
+}
+
+// ExportContacts returns the definitions of the contacts, sorted by name.
+func ExportContacts(ctx context.Context, api ExportAPI) ([]Contact, error) {
+	contacts, err := api.ListContacts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []Contact
+	for _, contact := range contacts {
+		defs = append(defs, FromContact(contact))
+	}
+
+	sort.SliceStable(defs, func(i, j int) bool {
+		if defs[i].Name != defs[j].Name {
+			return defs[i].Name < defs[j].Name
+		}
+		return defs[i].Email < defs[j].Email
+	})
+
+	return defs, nil
+}
+
+// FromContact returns the definition of a contact read from Pingdom.
+func FromContact(contact pingdom.ContactResponse) Contact {
+	return Contact{
+		Name:               contact.Name,
+		Email:              contact.Email,
+		Cellphone:          contact.Cellphone,
+		CountryISO:         contact.CountryISO,
+		DefaultSMSProvider: contact.DefaultSMSProvider,
+		DirectTwitter:      contact.DirectTwitter,
+		TwitterUser:        contact.TwitterUser,
+		Paused:             contact.Paused,
+	}
+}
+
+// SupportedChecks splits the checks into those of the types definitions
+// support, http and ping, and the others, which LoadFile rejects.
+func SupportedChecks(checks []Check) (supported, unsupported []Check) {
+	for _, check := range checks {
+		if check.Type == TypeHTTP || check.Type == TypePing {
+			supported = append(supported, check)
+		} else {
+			unsupported = append(unsupported, check)
+		}
+	}
+
+	return supported, unsupported
+}
+
+// DuplicateNames returns the names shared by several checks, which apply
+// rejects.
+func DuplicateNames(checks []Check) []string {
+	counts := map[string]int{}
+	for _, check := range checks {
+		counts[check.Name]++
+	}
+
+	var names []string
+	for name, count := range counts {
+		if count > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,164 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom/fakepingdomtest"
+)
+
+func exportFixtures() *fakepingdom.Fixtures {
+	fixtures := fakepingdom.DefaultFixtures()
+	fixtures.Checks[0].Tags = append(fixtures.Checks[0].Tags, pingdom.CheckResponseTag{Name: DefaultManagedTag, Type: "u"})
+	fixtures.Checks[0].ContactIds = []int{2001, 2002}
+	fixtures.Checks[0].Type.HTTP.Username = "monitor"
+	fixtures.Checks[0].Type.HTTP.Password = "s3cr3t"
+
+	return fixtures
+}
+
+// export returns the export of the checks and contacts of the fixtures.
+func export(t *testing.T, fixtures *fakepingdom.Fixtures, includeSecrets bool) *File {
+	fake := fakepingdomtest.NewServer(t, fixtures)
+	defer fake.Close()
+
+	ctx := context.Background()
+	checks, err := ExportChecks(ctx, fake.Client, DefaultManagedTag, includeSecrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contacts, err := ExportContacts(ctx, fake.Client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &File{Checks: checks, Contacts: contacts}
+}
+
+func TestExport(t *testing.T) {
+	f := export(t, exportFixtures(), false)
+	checks, contacts := f.Checks, f.Contacts
+
+	var names []string
+	for _, check := range checks {
+		names = append(names, check.Name)
+	}
+	expected := []string{"API", "Mail", "Staging", "Status page", "Website"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected checks %v, got %v", expected, names)
+	}
+	if tags := checks[4].Tags; !reflect.DeepEqual(tags, []string{"production", "team-web"}) {
+		t.Errorf("expected the managed tag to be left out, got %v", tags)
+	}
+	if h := checks[4].HTTP; h.Username != "monitor" || h.Password != "" {
+		t.Errorf("expected the password to be left out, got %+v", h)
+	}
+	if !checks[2].Paused {
+		t.Errorf("expected Staging to be paused")
+	}
+	if len(contacts) != 2 || contacts[0].Name != "Backup" {
+		t.Errorf("expected contacts sorted by name, got %+v", contacts)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	tmp, err := ioutil.TempFile("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	tmp.Write(buf.Bytes())
+	tmp.Close()
+
+	loaded, err := LoadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, f) {
+		t.Errorf("expected the export to load unchanged, got %+v", loaded)
+	}
+
+	if f := export(t, exportFixtures(), true); f.Checks[4].HTTP.Password != "s3cr3t" {
+		t.Errorf("expected the password with includeSecrets, got %+v", f.Checks[4].HTTP)
+	}
+}
+
+func TestExportUnsupportedTypes(t *testing.T) {
+	fixtures := exportFixtures()
+	fixtures.Checks[1].Type = pingdom.CheckResponseType{Name: "dns"}
+	fixtures.Checks[3].Type = pingdom.CheckResponseType{Name: "httpcustom"}
+
+	checks, unsupported := SupportedChecks(export(t, fixtures, false).Checks)
+
+	var names []string
+	for _, check := range unsupported {
+		names = append(names, check.Name)
+	}
+	if expected := []string{"API", "Status page"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected the unsupported checks %v, got %v", expected, names)
+	}
+
+	f := &File{Checks: checks}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	tmp, err := ioutil.TempFile("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	tmp.Write(buf.Bytes())
+	tmp.Close()
+
+	loaded, err := LoadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, f) {
+		t.Errorf("expected the export to load unchanged, got %+v", loaded)
+	}
+}
+
+func TestExportStable(t *testing.T) {
+	// The same account, with checks, contacts, tags and contact IDs listed
+	// in the opposite order.
+	reversed := exportFixtures()
+	checks := reversed.Checks
+	for i, j := 0, len(checks)-1; i < j; i, j = i+1, j-1 {
+		checks[i], checks[j] = checks[j], checks[i]
+	}
+	for i := range checks {
+		tags, ids := checks[i].Tags, checks[i].ContactIds
+		for k, l := 0, len(tags)-1; k < l; k, l = k+1, l-1 {
+			tags[k], tags[l] = tags[l], tags[k]
+		}
+		for k, l := 0, len(ids)-1; k < l; k, l = k+1, l-1 {
+			ids[k], ids[l] = ids[l], ids[k]
+		}
+	}
+	contacts := reversed.Contacts
+	contacts[0], contacts[1] = contacts[1], contacts[0]
+
+	var first, second bytes.Buffer
+	if err := export(t, exportFixtures(), false).Write(&first); err != nil {
+		t.Fatal(err)
+	}
+	if err := export(t, reversed, false).Write(&second); err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("export depends on the order of the API responses\nfirst:\n%s\nsecond:\n%s", first.String(), second.String())
+	}
+}
@@ -0,0 +1,319 @@
+// Package manifest exports Pingdom checks and contacts as declarative
+// definitions, and reconciles the checks with such definitions.
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+	"gopkg.in/yaml.v2"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+const (
+	// DefaultManagedTag is the tag identifying the checks managed by apply.
+	DefaultManagedTag = "pingdom-exporter"
+	// defaultResolution is the resolution of checks declaring none, like in
+	// Pingdom.
+	defaultResolution = 5
+	// defaultURL is the URL of HTTP checks declaring none, like in Pingdom.
+	defaultURL = "/"
+)
+
+// Check types.
+const (
+	TypeHTTP = "http"
+	TypePing = "ping"
+)
+
+// File is a list of check and contact definitions. Contacts are exported
+// with the checks, but not applied.
+type File struct {
+	Checks   []Check   `yaml:"checks,omitempty"`
+	Contacts []Contact `yaml:"contacts,omitempty"`
+}
+
+// Check is the definition of a check. Checks are identified by their names,
+// which must be unique.
+type Check struct {
+	Name string `yaml:"name"`
+	// Type is http or ping.
+	Type     string `yaml:"type"`
+	Hostname string `yaml:"hostname"`
+	// Resolution is the interval of the tests in minutes, 1, 5, 15, 30 or
+	// 60. It defaults to 5.
+	Resolution int  `yaml:"resolution,omitempty"`
+	Paused     bool `yaml:"paused,omitempty"`
+
+	// Tags are the tags of the check, not including the managed tag, which
+	// is added to every check.
+	Tags           []string `yaml:"tags,omitempty"`
+	ContactIDs     []int    `yaml:"contact_ids,omitempty"`
+	IntegrationIDs []int    `yaml:"integration_ids,omitempty"`
+
+	// NotifyAfterFailures is the number of consecutive failed tests after
+	// which to notify.
+	NotifyAfterFailures int `yaml:"notify_after_failures,omitempty"`
+	// NotifyAgainEvery is the number of failed tests after which to notify
+	// again, 0 notifies once.
+	NotifyAgainEvery int  `yaml:"notify_again_every,omitempty"`
+	NotifyWhenBackUp bool `yaml:"notify_when_back_up,omitempty"`
+
+	// HTTP are the settings of http checks.
+	HTTP *HTTP `yaml:"http,omitempty"`
+}
+
+// HTTP are the settings of an http check.
+type HTTP struct {
+	// URL is the path and query of the URL, it defaults to /.
+	URL        string `yaml:"url,omitempty"`
+	Encryption bool   `yaml:"encryption,omitempty"`
+	// Port defaults to 80, or 443 with encryption.
+	Port             int               `yaml:"port,omitempty"`
+	Username         string            `yaml:"username,omitempty"`
+	Password         string            `yaml:"password,omitempty"`
+	ShouldContain    string            `yaml:"should_contain,omitempty"`
+	ShouldNotContain string            `yaml:"should_not_contain,omitempty"`
+	PostData         string            `yaml:"post_data,omitempty"`
+	RequestHeaders   map[string]string `yaml:"request_headers,omitempty"`
+}
+
+// Contact is the definition of a notification contact.
+type Contact struct {
+	Name               string `yaml:"name"`
+	Email              string `yaml:"email,omitempty"`
+	Cellphone          string `yaml:"cellphone,omitempty"`
+	CountryISO         string `yaml:"country_iso,omitempty"`
+	DefaultSMSProvider string `yaml:"default_sms_provider,omitempty"`
+	DirectTwitter      bool   `yaml:"direct_twitter,omitempty"`
+	TwitterUser        string `yaml:"twitter_user,omitempty"`
+	Paused             bool   `yaml:"paused,omitempty"`
+}
+
+// LoadFile reads a file of check definitions, and sets the defaults of unset
+// fields.
+func LoadFile(path string) (*File, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{}
+	if err := yaml.UnmarshalStrict(content, f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+
+	return f, nil
+}
+
+func (f *File) validate() error {
+	names := map[string]bool{}
+	for i := range f.Checks {
+		c := &f.Checks[i]
+
+		if c.Name == "" {
+			return fmt.Errorf("check %d: name must not be empty", i+1)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("check %q is declared more than once", c.Name)
+		}
+		names[c.Name] = true
+
+		if c.Resolution == 0 {
+			c.Resolution = defaultResolution
+		}
+		switch c.Type {
+		case TypeHTTP:
+			if c.HTTP == nil {
+				c.HTTP = &HTTP{}
+			}
+			if c.HTTP.URL == "" {
+				c.HTTP.URL = defaultURL
+			}
+		case TypePing:
+			if c.HTTP != nil {
+				return fmt.Errorf("check %q: http settings are only allowed for http checks", c.Name)
+			}
+		default:
+			return fmt.Errorf("check %q: type must be http or ping", c.Name)
+		}
+
+		if err := c.PingdomCheck("").Valid(); err != nil {
+			return fmt.Errorf("check %q: %v", c.Name, err)
+		}
+	}
+
+	names = map[string]bool{}
+	for i, c := range f.Contacts {
+		if c.Name == "" {
+			return fmt.Errorf("contact %d: name must not be empty", i+1)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("contact %q is declared more than once", c.Name)
+		}
+		names[c.Name] = true
+	}
+
+	return nil
+}
+
+// Write writes the file as YAML.
+func (f *File) Write(w io.Writer) error {
+	content, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(content)
+
+	return err
+}
+
+// PingdomCheck returns the check as a pingdom.HttpCheck or pingdom.PingCheck,
+// with the managed tag added to its tags.
+func (c *Check) PingdomCheck(managedTag string) pingdom.Check {
+	tags := c.Tags
+	if managedTag != "" {
+		tags = append([]string{managedTag}, tags...)
+	}
+
+	if c.Type == TypePing {
+		return &pingCheck{
+			PingCheck: pingdom.PingCheck{
+				Name:                     c.Name,
+				Hostname:                 c.Hostname,
+				Resolution:               c.Resolution,
+				Paused:                   c.Paused,
+				SendNotificationWhenDown: c.NotifyAfterFailures,
+				NotifyAgainEvery:         c.NotifyAgainEvery,
+				NotifyWhenBackup:         c.NotifyWhenBackUp,
+				ContactIds:               c.ContactIDs,
+				IntegrationIds:           c.IntegrationIDs,
+			},
+			tags: strings.Join(tags, ","),
+		}
+	}
+
+	check := &pingdom.HttpCheck{
+		Name:                     c.Name,
+		Hostname:                 c.Hostname,
+		Resolution:               c.Resolution,
+		Paused:                   c.Paused,
+		SendNotificationWhenDown: c.NotifyAfterFailures,
+		NotifyAgainEvery:         c.NotifyAgainEvery,
+		NotifyWhenBackup:         c.NotifyWhenBackUp,
+		ContactIds:               c.ContactIDs,
+		IntegrationIds:           c.IntegrationIDs,
+		Tags:                     strings.Join(tags, ","),
+	}
+	if c.HTTP != nil {
+		check.Url = c.HTTP.URL
+		check.Encryption = c.HTTP.Encryption
+		check.Port = c.HTTP.Port
+		check.Username = c.HTTP.Username
+		check.Password = c.HTTP.Password
+		check.ShouldContain = c.HTTP.ShouldContain
+		check.ShouldNotContain = c.HTTP.ShouldNotContain
+		check.PostData = c.HTTP.PostData
+		check.RequestHeaders = c.HTTP.RequestHeaders
+	}
+
+	return check
+}
+
+// pingCheck is a pingdom.PingCheck with tags, which the Pingdom API accepts
+// for all check types.
+type pingCheck struct {
+	pingdom.PingCheck
+	tags string
+}
+
+func (c *pingCheck) PutParams() map[string]string {
+	params := c.PingCheck.PutParams()
+	params["tags"] = c.tags
+
+	return params
+}
+
+func (c *pingCheck) PostParams() map[string]string {
+	params := c.PingCheck.PostParams()
+	if c.tags != "" {
+		params["tags"] = c.tags
+	}
+
+	return params
+}
+
+// FromCheck returns the definition of a check read from Pingdom, without the
+// managed tag. Pauses made with checks pause are left out: the check is not
+// paused, and has no pause tags.
+func FromCheck(check pingdom.CheckResponse, managedTag string) Check {
+	_, paused := exporter.CheckPause(check)
+
+	c := Check{
+		Name:                check.Name,
+		Type:                check.Type.Name,
+		Hostname:            check.Hostname,
+		Resolution:          check.Resolution,
+		Paused:              exporter.IsPaused(check) && !paused,
+		ContactIDs:          sortedInts(check.ContactIds),
+		IntegrationIDs:      sortedInts(check.IntegrationIds),
+		NotifyAfterFailures: check.SendNotificationWhenDown,
+		NotifyAgainEvery:    check.NotifyAgainEvery,
+		NotifyWhenBackUp:    check.NotifyWhenBackup,
+	}
+
+	for _, tag := range exporter.WithoutPauseTags(check) {
+		if tag != managedTag {
+			c.Tags = append(c.Tags, tag)
+		}
+	}
+	sort.Strings(c.Tags)
+
+	if details := check.Type.HTTP; details != nil {
+		c.HTTP = &HTTP{
+			URL:              details.Url,
+			Encryption:       details.Encryption,
+			Port:             details.Port,
+			Username:         details.Username,
+			Password:         details.Password,
+			ShouldContain:    details.ShouldContain,
+			ShouldNotContain: details.ShouldNotContain,
+			PostData:         details.PostData,
+			RequestHeaders:   details.RequestHeaders,
+		}
+	}
+
+	return c
+}
+
+// HasTag returns true if the check has the tag.
+func HasTag(check pingdom.CheckResponse, tag string) bool {
+	for _, t := range check.Tags {
+		if t.Name == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedInts(ints []int) []int {
+	if len(ints) == 0 {
+		return nil
+	}
+
+	sorted := append([]int(nil), ints...)
+	sort.Ints(sorted)
+
+	return sorted
+}
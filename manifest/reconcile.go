@@ -0,0 +1,447 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+// PingdomAPI is the part of the Pingdom API used to reconcile checks.
+// exporter.Client implements it.
+type PingdomAPI interface {
+	ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error)
+	ReadCheck(ctx context.Context, id int) (*pingdom.CheckResponse, error)
+	CreateCheck(ctx context.Context, check pingdom.Check) (*pingdom.CheckResponse, error)
+	UpdateCheck(ctx context.Context, id int, check pingdom.Check) error
+	DeleteCheck(ctx context.Context, id int) error
+}
+
+// Action is what applying a change does to a check.
+type Action string
+
+// Actions of changes.
+const (
+	// Create creates a declared check.
+	Create Action = "create"
+	// Update changes the settings of a managed check.
+	Update Action = "update"
+	// Adopt updates a check that is declared but not managed yet, adding the
+	// managed tag.
+	Adopt Action = "adopt"
+	// Replace deletes and recreates a check whose type changed, as Pingdom
+	// does not allow changing the type of a check.
+	Replace Action = "replace"
+	// Delete deletes a managed check that is not declared anymore.
+	Delete Action = "delete"
+)
+
+// Change is a change to a check.
+type Change struct {
+	Action Action
+	// ID is the ID of the existing check, 0 for Create.
+	ID   int
+	Name string
+	// Check is the declared check, nil for Delete.
+	Check *Check
+	// Diff lists the settings that change, for Update, Adopt and Replace.
+	Diff []string
+}
+
+// Plan is the list of changes reconciling Pingdom with the declared checks.
+type Plan struct {
+	Changes []Change
+	// Unchanged is the number of declared checks that are up to date.
+	Unchanged int
+	// Undeclared lists the managed checks which are not declared anymore,
+	// but are kept because pruning is disabled.
+	Undeclared []string
+	// Unadopted lists the declared checks which are left alone because
+	// checks without the managed tag have their name, and adoption is
+	// disabled.
+	Unadopted []string
+}
+
+// Config represents the configuration used to create a Reconciler.
+type Config struct {
+	API    PingdomAPI
+	Logger log.Logger
+
+	// ManagedTag identifies the checks managed by the reconciler. Checks
+	// without it are never changed, unless they have the name of a declared
+	// check and Adopt is set, in which case they are adopted.
+	ManagedTag string
+	// Adopt adopts the checks without the managed tag which have the name of
+	// a declared check. Otherwise the declared check is left out of the plan.
+	Adopt bool
+	// Prune deletes the managed checks which are not declared.
+	Prune bool
+	// SkipUnchanged saves reading the details of managed checks that an
+	// earlier Plan found up to date, as long as their definition and listed
+	// settings stay the same. Forget makes the next Plan read them again.
+	SkipUnchanged bool
+}
+
+// DefaultConfig provides a default configuration to create a Reconciler.
+func DefaultConfig() Config {
+	return Config{
+		API:    nil,
+		Logger: log.NewNopLogger(),
+
+		ManagedTag:    DefaultManagedTag,
+		Adopt:         true,
+		Prune:         false,
+		SkipUnchanged: false,
+	}
+}
+
+// Reconciler plans and applies the changes making the Pingdom checks match
+// the declared checks. It must not be used concurrently.
+type Reconciler struct {
+	api    PingdomAPI
+	logger log.Logger
+
+	managedTag    string
+	adopt         bool
+	prune         bool
+	skipUnchanged bool
+
+	// unchanged holds the fingerprints of the checks found up to date, by
+	// ID, see SkipUnchanged.
+	unchanged map[int]string
+}
+
+// New creates a new configured Reconciler.
+func New(config Config) (*Reconciler, error) {
+	if config.API == nil {
+		return nil, fmt.Errorf("config.API must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, fmt.Errorf("config.Logger must not be empty")
+	}
+	if config.ManagedTag == "" {
+		return nil, fmt.Errorf("config.ManagedTag must not be empty")
+	}
+
+	r := &Reconciler{
+		api:    config.API,
+		logger: config.Logger,
+
+		managedTag:    config.ManagedTag,
+		adopt:         config.Adopt,
+		prune:         config.Prune,
+		skipUnchanged: config.SkipUnchanged,
+
+		unchanged: map[int]string{},
+	}
+
+	return r, nil
+}
+
+// Forget makes the next Plan read the details of every check, see
+// Config.SkipUnchanged.
+func (r *Reconciler) Forget() {
+	r.unchanged = map[int]string{}
+}
+
+// fingerprint identifies the definition of a check and its settings listed
+// by Pingdom.
+func (r *Reconciler) fingerprint(desired *Check, listed pingdom.CheckResponse) string {
+	var values []string
+	for _, f := range fields(*desired) {
+		values = append(values, f.name+"="+f.value)
+	}
+	for _, f := range fields(FromCheck(listed, r.managedTag)) {
+		values = append(values, "listed."+f.name+"="+f.value)
+	}
+
+	return strings.Join(values, "\n")
+}
+
+// Plan compares the declared checks with the checks in Pingdom, and returns
+// the changes to apply. Changes to declared checks are in the order of the
+// declarations, followed by deletions in the order of the names.
+func (r *Reconciler) Plan(ctx context.Context, f *File) (*Plan, error) {
+	checks, err := r.api.ListChecks(ctx, map[string]string{"include_tags": "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	managed := map[string]pingdom.CheckResponse{}
+	unmanaged := map[string][]pingdom.CheckResponse{}
+	for _, check := range checks {
+		if !HasTag(check, r.managedTag) {
+			unmanaged[check.Name] = append(unmanaged[check.Name], check)
+			continue
+		}
+		if _, ok := managed[check.Name]; ok {
+			return nil, fmt.Errorf("several checks named %q have the tag %s", check.Name, r.managedTag)
+		}
+		managed[check.Name] = check
+	}
+
+	plan := &Plan{}
+	declared := map[string]bool{}
+	for i := range f.Checks {
+		desired := &f.Checks[i]
+		declared[desired.Name] = true
+
+		action := Update
+		existing, ok := managed[desired.Name]
+		if !ok {
+			switch n := len(unmanaged[desired.Name]); {
+			case n == 0:
+				plan.Changes = append(plan.Changes, Change{Action: Create, Name: desired.Name, Check: desired})
+				continue
+			case !r.adopt:
+				plan.Unadopted = append(plan.Unadopted, desired.Name)
+				continue
+			case n == 1:
+				action = Adopt
+				existing = unmanaged[desired.Name][0]
+			default:
+				return nil, fmt.Errorf("several checks are named %q, tag the one to manage with %s", desired.Name, r.managedTag)
+			}
+		}
+
+		var fingerprint string
+		if r.skipUnchanged && action == Update {
+			fingerprint = r.fingerprint(desired, existing)
+			if r.unchanged[existing.ID] == fingerprint {
+				plan.Unchanged++
+				continue
+			}
+		}
+		delete(r.unchanged, existing.ID)
+
+		details, err := r.api.ReadCheck(ctx, existing.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read check %q: %v", desired.Name, err)
+		}
+		if len(details.Tags) == 0 {
+			details.Tags = existing.Tags
+		}
+		if details.Type.Name != desired.Type {
+			action = Replace
+		}
+
+		current := FromCheck(*details, r.managedTag)
+		d := diff(current, *desired)
+		if len(d) == 0 && action == Update {
+			if fingerprint != "" {
+				r.unchanged[existing.ID] = fingerprint
+			}
+			plan.Unchanged++
+			continue
+		}
+
+		check := desired
+		if p, ok := exporter.CheckPause(*details); ok && exporter.IsPaused(*details) && !desired.Paused {
+			// Checks paused with checks pause stay paused until resumed.
+			paused := *desired
+			paused.Paused = true
+			paused.Tags = append(append([]string(nil), desired.Tags...), p.Tags()...)
+			check = &paused
+		}
+
+		plan.Changes = append(plan.Changes, Change{
+			Action: action,
+			ID:     existing.ID,
+			Name:   desired.Name,
+			Check:  check,
+			Diff:   d,
+		})
+	}
+
+	var names []string
+	for name := range managed {
+		if !declared[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !r.prune {
+			plan.Undeclared = append(plan.Undeclared, name)
+			continue
+		}
+		plan.Changes = append(plan.Changes, Change{Action: Delete, ID: managed[name].ID, Name: name})
+	}
+
+	return plan, nil
+}
+
+// Apply applies the changes of the plan in order, and stops at the first
+// failed change.
+func (r *Reconciler) Apply(ctx context.Context, plan *Plan) error {
+	for _, change := range plan.Changes {
+		if err := r.apply(ctx, change); err != nil {
+			return fmt.Errorf("failed to %s check %q: %v", change.Action, change.Name, err)
+		}
+		level.Info(r.logger).Log("msg", "applied change", "action", change.Action, "check", change.Name)
+	}
+
+	return nil
+}
+
+func (r *Reconciler) apply(ctx context.Context, change Change) error {
+	switch change.Action {
+	case Create:
+		_, err := r.api.CreateCheck(ctx, change.Check.PingdomCheck(r.managedTag))
+		return err
+	case Update, Adopt:
+		return r.api.UpdateCheck(ctx, change.ID, change.Check.PingdomCheck(r.managedTag))
+	case Replace:
+		if err := r.api.DeleteCheck(ctx, change.ID); err != nil {
+			return err
+		}
+		_, err := r.api.CreateCheck(ctx, change.Check.PingdomCheck(r.managedTag))
+		return err
+	case Delete:
+		return r.api.DeleteCheck(ctx, change.ID)
+	default:
+		return fmt.Errorf("unknown action %s", change.Action)
+	}
+}
+
+// Write writes the plan in a human readable form.
+func (p *Plan) Write(w io.Writer) error {
+	counts := map[Action]int{}
+	for _, change := range p.Changes {
+		counts[change.Action]++
+
+		var err error
+		switch change.Action {
+		case Create:
+			_, err = fmt.Fprintf(w, "+ create %q\n", change.Name)
+		case Delete:
+			_, err = fmt.Fprintf(w, "- delete %q (%d)\n", change.Name, change.ID)
+		default:
+			_, err = fmt.Fprintf(w, "~ %s %q (%d)\n", change.Action, change.Name, change.ID)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, line := range change.Diff {
+			if _, err := fmt.Fprintf(w, "    %s\n", line); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range p.Undeclared {
+		if _, err := fmt.Fprintf(w, "! %q is not declared, use --prune to delete it\n", name); err != nil {
+			return err
+		}
+	}
+	for _, name := range p.Unadopted {
+		if _, err := fmt.Fprintf(w, "! %q is not managed, tag it with the managed tag to adopt it\n", name); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d to create, %d to update, %d to replace, %d to delete, %d unchanged.\n",
+		counts[Create], counts[Update]+counts[Adopt], counts[Replace], counts[Delete], p.Unchanged)
+
+	return err
+}
+
+// field is a setting of a check, formatted for comparison.
+type field struct {
+	name   string
+	value  string
+	secret bool
+}
+
+// fields returns the settings of the check compared by diff.
+func fields(c Check) []field {
+	fs := []field{
+		{name: "type", value: c.Type},
+		{name: "hostname", value: c.Hostname},
+		{name: "resolution", value: strconv.Itoa(c.Resolution)},
+		{name: "paused", value: strconv.FormatBool(c.Paused)},
+		{name: "tags", value: strings.Join(sortedStrings(c.Tags), ",")},
+		{name: "contact_ids", value: joinInts(sortedInts(c.ContactIDs))},
+		{name: "integration_ids", value: joinInts(sortedInts(c.IntegrationIDs))},
+		{name: "notify_after_failures", value: strconv.Itoa(c.NotifyAfterFailures)},
+		{name: "notify_again_every", value: strconv.Itoa(c.NotifyAgainEvery)},
+		{name: "notify_when_back_up", value: strconv.FormatBool(c.NotifyWhenBackUp)},
+	}
+
+	if c.HTTP != nil {
+		var headers []string
+		for name, value := range c.HTTP.RequestHeaders {
+			headers = append(headers, name+":"+value)
+		}
+
+		fs = append(fs,
+			field{name: "http.url", value: c.HTTP.URL},
+			field{name: "http.encryption", value: strconv.FormatBool(c.HTTP.Encryption)},
+			field{name: "http.port", value: strconv.Itoa(c.HTTP.Port)},
+			field{name: "http.username", value: c.HTTP.Username},
+			field{name: "http.password", value: c.HTTP.Password, secret: true},
+			field{name: "http.should_contain", value: c.HTTP.ShouldContain},
+			field{name: "http.should_not_contain", value: c.HTTP.ShouldNotContain},
+			field{name: "http.post_data", value: c.HTTP.PostData},
+			field{name: "http.request_headers", value: strings.Join(sortedStrings(headers), ",")},
+		)
+	}
+
+	return fs
+}
+
+// diff returns the settings which differ between the current and desired
+// checks, as "name: current -> desired". Secrets are not shown, and settings
+// the desired check leaves to Pingdom are ignored.
+func diff(current, desired Check) []string {
+	if current.HTTP != nil && desired.HTTP != nil && desired.HTTP.Port == 0 {
+		c := *current.HTTP
+		c.Port = 0
+		current.HTTP = &c
+	}
+
+	values := map[string]string{}
+	for _, f := range fields(current) {
+		values[f.name] = f.value
+	}
+
+	var d []string
+	for _, f := range fields(desired) {
+		old, ok := values[f.name]
+		if ok && old == f.value {
+			continue
+		}
+		if f.secret {
+			d = append(d, fmt.Sprintf("%s: (changed)", f.name))
+			continue
+		}
+		d = append(d, fmt.Sprintf("%s: %q -> %q", f.name, old, f.value))
+	}
+
+	return d
+}
+
+func sortedStrings(s []string) []string {
+	sorted := append([]string(nil), s...)
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+func joinInts(ints []int) string {
+	s := make([]string, len(ints))
+	for i, n := range ints {
+		s[i] = strconv.Itoa(n)
+	}
+
+	return strings.Join(s, ",")
+}
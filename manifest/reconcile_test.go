@@ -0,0 +1,256 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom"
+	"github.com/giantswarm/prometheus-pingdom-exporter/fakepingdom/fakepingdomtest"
+)
+
+const testFile = `
+checks:
+- name: Website
+  type: http
+  hostname: www.example.com
+  resolution: 1
+  tags: [team-web, production]
+  contact_ids: [2001]
+  http:
+    encryption: true
+    should_contain: OK
+- name: API
+  type: http
+  hostname: api.example.com
+  tags: [production]
+  contact_ids: [2001]
+  http:
+    encryption: true
+    should_contain: OK
+- name: Mail
+  type: ping
+  hostname: mail.example.com
+  resolution: 15
+  contact_ids: [2001]
+- name: Gateway
+  type: ping
+  hostname: gw.example.com
+  tags: [network]
+`
+
+func TestReconciler(t *testing.T) {
+	fixtures := fakepingdom.DefaultFixtures()
+	managed := pingdom.CheckResponseTag{Name: DefaultManagedTag, Type: "u"}
+	for _, i := range []int{0, 1, 3} {
+		fixtures.Checks[i].Tags = append(fixtures.Checks[i].Tags, managed)
+	}
+
+	fake := fakepingdomtest.NewServer(t, fixtures)
+	defer fake.Close()
+	api := fake.Client
+
+	tmp, err := ioutil.TempFile("", "checks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	tmp.WriteString(testFile)
+	tmp.Close()
+
+	f, err := LoadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := DefaultConfig()
+	c.API = api
+	c.Prune = true
+	r, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	plan, err := r.Plan(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actions []string
+	for _, change := range plan.Changes {
+		actions = append(actions, string(change.Action)+" "+change.Name)
+	}
+	expected := []string{"update API", "adopt Mail", "create Gateway", "delete Status page"}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("expected changes %v, got %v", expected, actions)
+	}
+	if plan.Unchanged != 1 {
+		t.Errorf("expected 1 unchanged check, got %d", plan.Unchanged)
+	}
+	if d := plan.Changes[0].Diff; !reflect.DeepEqual(d, []string{`resolution: "1" -> "5"`}) {
+		t.Errorf("unexpected diff of API: %v", d)
+	}
+
+	var buf bytes.Buffer
+	if err := plan.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "1 to create, 2 to update, 0 to replace, 1 to delete, 1 unchanged.") {
+		t.Errorf("unexpected plan output:\n%s", buf.String())
+	}
+
+	if err := r.Apply(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err = r.Plan(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 0 || plan.Unchanged != 4 {
+		t.Errorf("expected no changes after apply, got %+v", plan)
+	}
+
+	checks, err := api.ListChecks(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range checks {
+		if check.Name == "Status page" {
+			t.Errorf("expected Status page to be deleted")
+		}
+	}
+	if len(checks) != 5 {
+		t.Errorf("expected 5 checks, got %d", len(checks))
+	}
+}
+
+func TestReconcilerWithoutPrune(t *testing.T) {
+	fixtures := fakepingdom.DefaultFixtures()
+	fixtures.Checks[0].Tags = append(fixtures.Checks[0].Tags, pingdom.CheckResponseTag{Name: "managed", Type: "u"})
+
+	fake := fakepingdomtest.NewServer(t, fixtures)
+	defer fake.Close()
+	api := fake.Client
+
+	c := DefaultConfig()
+	c.API = api
+	c.ManagedTag = "managed"
+	r, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := r.Plan(context.Background(), &File{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 0 {
+		t.Errorf("expected no changes, got %+v", plan.Changes)
+	}
+	if !reflect.DeepEqual(plan.Undeclared, []string{"Website"}) {
+		t.Errorf("expected Website to be undeclared, got %v", plan.Undeclared)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []string{
+		"checks:\n- name: a\n  type: tcp\n  hostname: a\n",
+		"checks:\n- name: a\n  type: ping\n  hostname: a\n  resolution: 2\n",
+		"checks:\n- name: a\n  type: ping\n  hostname: a\n  http:\n    url: /\n",
+		"checks:\n- name: a\n  type: ping\n  hostname: a\n- name: a\n  type: ping\n  hostname: b\n",
+		"checks:\n- name: a\n  type: ping\n  host: a\n",
+	}
+
+	for _, content := range tests {
+		tmp, err := ioutil.TempFile("", "checks")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmp.WriteString(content)
+		tmp.Close()
+
+		if _, err := LoadFile(tmp.Name()); err == nil {
+			t.Errorf("expected an error loading %q", content)
+		}
+		os.Remove(tmp.Name())
+	}
+}
+
+func TestReconcilerKeepsPauses(t *testing.T) {
+	fixtures := fakepingdom.DefaultFixtures()
+	website := &fixtures.Checks[0]
+	website.Paused = true
+	website.Status = "paused"
+	website.Tags = append(website.Tags,
+		pingdom.CheckResponseTag{Name: DefaultManagedTag, Type: "u"},
+		pingdom.CheckResponseTag{Name: "paused-until-201805041330", Type: "u"},
+		pingdom.CheckResponseTag{Name: "paused-by-jane", Type: "u"},
+	)
+
+	fake := fakepingdomtest.NewServer(t, fixtures)
+	defer fake.Close()
+
+	c := DefaultConfig()
+	c.API = fake.Client
+	r, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	declared := Check{
+		Name:       "Website",
+		Type:       "http",
+		Hostname:   "www.example.com",
+		Resolution: 1,
+		Tags:       []string{"production", "team-web"},
+		ContactIDs: []int{2001},
+		HTTP:       &HTTP{URL: "/", Encryption: true, Port: 443, ShouldContain: "OK"},
+	}
+	f := &File{Checks: []Check{declared}}
+
+	// The pause and its tags are not a change.
+	ctx := context.Background()
+	plan, err := r.Plan(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 0 || plan.Unchanged != 1 {
+		t.Fatalf("expected the paused check to be unchanged, got %+v", plan.Changes)
+	}
+
+	// Other changes keep the pause.
+	f.Checks[0].Resolution = 5
+	plan, err = r.Plan(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 1 || !reflect.DeepEqual(plan.Changes[0].Diff, []string{`resolution: "1" -> "5"`}) {
+		t.Fatalf("expected a change of the resolution, got %+v", plan.Changes)
+	}
+	if err := r.Apply(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+
+	check, err := fake.Client.ReadCheck(ctx, 1001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	for _, tag := range check.Tags {
+		tags = append(tags, tag.Name)
+	}
+	sort.Strings(tags)
+	want := []string{"paused-by-jane", "paused-until-201805041330", DefaultManagedTag, "production", "team-web"}
+	if check.Resolution != 5 || !check.Paused || !reflect.DeepEqual(tags, want) {
+		t.Errorf("expected the check to stay paused with the tags %v, got paused %t, tags %v", want, check.Paused, tags)
+	}
+}
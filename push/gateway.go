@@ -0,0 +1,143 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// GatewayConfig represents the configuration used to create a Gateway.
+type GatewayConfig struct {
+	// URL is the address of the Pushgateway, e.g. http://pushgateway:9091.
+	URL string
+	// Job is the job label of the pushed group.
+	Job string
+	// Grouping holds the other labels of the grouping key.
+	Grouping map[string]string
+	Gatherer prometheus.Gatherer
+	// HTTPClient sends the requests, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// DefaultGatewayConfig provides a default configuration to create a Gateway.
+func DefaultGatewayConfig() GatewayConfig {
+	return GatewayConfig{
+		URL:        "",
+		Job:        "pingdom",
+		Grouping:   nil,
+		Gatherer:   nil,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Gateway pushes metrics to a Prometheus Pushgateway.
+type Gateway struct {
+	url        string
+	gatherer   prometheus.Gatherer
+	httpClient *http.Client
+}
+
+// NewGateway creates a new configured Gateway.
+func NewGateway(config GatewayConfig) (*Gateway, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("config.URL must not be empty")
+	}
+	if config.Job == "" {
+		return nil, fmt.Errorf("config.Job must not be empty")
+	}
+	if config.Gatherer == nil {
+		return nil, fmt.Errorf("config.Gatherer must not be empty")
+	}
+	if config.HTTPClient == nil {
+		return nil, fmt.Errorf("config.HTTPClient must not be empty")
+	}
+
+	groupURL, err := groupURL(config.URL, config.Job, config.Grouping)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Gateway{
+		url:        groupURL,
+		gatherer:   config.Gatherer,
+		httpClient: config.HTTPClient,
+	}
+
+	return g, nil
+}
+
+// groupURL returns the URL of the group with the job and grouping labels,
+// with the labels sorted by name.
+func groupURL(base, job string, grouping map[string]string) (string, error) {
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	if strings.Contains(job, "/") {
+		return "", fmt.Errorf("job must not contain /: %q", job)
+	}
+
+	names := make([]string, 0, len(grouping))
+	for name, value := range grouping {
+		if !model.LabelName(name).IsValid() {
+			return "", fmt.Errorf("invalid grouping label name %q", name)
+		}
+		if strings.Contains(value, "/") {
+			return "", fmt.Errorf("value of grouping label %s must not contain /: %q", name, value)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	path := []string{"metrics", "job", url.PathEscape(job)}
+	for _, name := range names {
+		path = append(path, name, url.PathEscape(grouping[name]))
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.Join(path, "/"), nil
+}
+
+// Push replaces the metrics of the group with the gathered metrics, so that
+// deleted checks disappear from the Pushgateway.
+func (g *Gateway) Push(ctx context.Context) error {
+	families, err := g.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.FmtProtoDelim)
+	for _, family := range families {
+		if err := enc.Encode(family); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest("PUT", g.url, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", string(expfmt.FmtProtoDelim))
+
+	resp, err := g.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected response %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
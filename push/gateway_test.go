@@ -0,0 +1,113 @@
+package push
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+func newTestGateway(t *testing.T, url string) *Gateway {
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: "pingdom_up", Help: "Whether the last poll succeeded."})
+	up.Set(1)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(up)
+
+	c := DefaultGatewayConfig()
+	c.URL = url
+	c.Grouping = map[string]string{"region": "eu", "instance": "a b"}
+	c.Gatherer = registry
+	g, err := NewGateway(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func TestGateway(t *testing.T) {
+	var method, path string
+	var values map[string]float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.EscapedPath()
+		if r.Header.Get("Content-Type") != string(expfmt.FmtProtoDelim) {
+			t.Errorf("Content-Type = %q, want %q", r.Header.Get("Content-Type"), expfmt.FmtProtoDelim)
+		}
+
+		values = map[string]float64{}
+		dec := expfmt.NewDecoder(r.Body, expfmt.FmtProtoDelim)
+		for {
+			family := &dto.MetricFamily{}
+			if err := dec.Decode(family); err != nil {
+				break
+			}
+			values[family.GetName()] = family.GetMetric()[0].GetGauge().GetValue()
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	if err := newTestGateway(t, server.URL+"/").Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if method != "PUT" {
+		t.Errorf("method = %s, want PUT", method)
+	}
+	if want := "/metrics/job/pingdom/instance/a%20b/region/eu"; path != want {
+		t.Errorf("path = %s, want %s", path, want)
+	}
+	if values["pingdom_up"] != 1 {
+		t.Errorf("pushed metrics = %v, want pingdom_up 1", values)
+	}
+}
+
+func TestGatewayErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "pushed metrics are invalid", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	if err := newTestGateway(t, server.URL).Push(context.Background()); err == nil {
+		t.Errorf("expected an error for a rejected push")
+	}
+
+	c := DefaultGatewayConfig()
+	c.URL = server.URL
+	c.Job = "a/b"
+	c.Gatherer = prometheus.NewRegistry()
+	if _, err := NewGateway(c); err == nil {
+		t.Errorf("expected an error for a job with a /")
+	}
+}
+
+func TestGatewayTimeout(t *testing.T) {
+	stop := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stop
+	}))
+	defer server.Close()
+	defer close(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- newTestGateway(t, server.URL).Push(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("expected an error for a Pushgateway not answering")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("push did not honour the context deadline")
+	}
+}
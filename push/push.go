@@ -0,0 +1,11 @@
+// Package push sends the metrics of a prometheus.Gatherer to a Pushgateway
+// or a remote write endpoint, for monitoring setups that cannot scrape the
+// exporter.
+package push
+
+import "context"
+
+// Pusher pushes the current metrics once.
+type Pusher interface {
+	Push(ctx context.Context) error
+}
@@ -0,0 +1,198 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// RemoteWriterConfig represents the configuration used to create a
+// RemoteWriter.
+type RemoteWriterConfig struct {
+	// URL is the remote write endpoint, e.g.
+	// http://prometheus:9090/api/v1/write.
+	URL string
+	// Labels are added to every series, unless the series has a label with
+	// the same name.
+	Labels   map[string]string
+	Gatherer prometheus.Gatherer
+	// HTTPClient sends the requests, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// DefaultRemoteWriterConfig provides a default configuration to create a
+// RemoteWriter.
+func DefaultRemoteWriterConfig() RemoteWriterConfig {
+	return RemoteWriterConfig{
+		URL:        "",
+		Labels:     nil,
+		Gatherer:   nil,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// RemoteWriter sends metrics through the Prometheus remote write protocol.
+// Samples keep the timestamps set by the collectors, see
+// exporter.Exporter.WithTestTimestamps, and are sent with the current time
+// otherwise.
+type RemoteWriter struct {
+	url        string
+	labels     map[string]string
+	gatherer   prometheus.Gatherer
+	httpClient *http.Client
+}
+
+// NewRemoteWriter creates a new configured RemoteWriter.
+func NewRemoteWriter(config RemoteWriterConfig) (*RemoteWriter, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("config.URL must not be empty")
+	}
+	if config.Gatherer == nil {
+		return nil, fmt.Errorf("config.Gatherer must not be empty")
+	}
+	if config.HTTPClient == nil {
+		return nil, fmt.Errorf("config.HTTPClient must not be empty")
+	}
+
+	w := &RemoteWriter{
+		url:        config.URL,
+		labels:     config.Labels,
+		gatherer:   config.Gatherer,
+		httpClient: config.HTTPClient,
+	}
+
+	return w, nil
+}
+
+// Push sends the gathered metrics in a single write request.
+func (w *RemoteWriter) Push(ctx context.Context) error {
+	families, err := w.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	writeRequest := &WriteRequest{
+		Timeseries: toTimeSeries(families, w.labels, time.Now()),
+	}
+
+	content, err := proto.Marshal(writeRequest)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(snappy.Encode(nil, content)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := w.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected response %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
+
+// toTimeSeries converts metric families to one time series per sample, with
+// the extra labels added. Samples without a timestamp are sent at now.
+func toTimeSeries(families []*dto.MetricFamily, extraLabels map[string]string, now time.Time) []*TimeSeries {
+	var series []*TimeSeries
+
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			timestamp := now.UnixNano() / int64(time.Millisecond)
+			if m.TimestampMs != nil {
+				timestamp = m.GetTimestampMs()
+			}
+
+			add := func(suffix string, value float64, extra ...string) {
+				labels := map[string]string{}
+				for name, value := range extraLabels {
+					labels[name] = value
+				}
+				for _, pair := range m.GetLabel() {
+					labels[pair.GetName()] = pair.GetValue()
+				}
+				for i := 0; i+1 < len(extra); i += 2 {
+					labels[extra[i]] = extra[i+1]
+				}
+				labels["__name__"] = family.GetName() + suffix
+
+				series = append(series, &TimeSeries{
+					Labels:  sortedLabels(labels),
+					Samples: []*Sample{{Value: value, Timestamp: timestamp}},
+				})
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_COUNTER:
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				for _, q := range m.GetSummary().GetQuantile() {
+					add("", q.GetValue(), "quantile", formatFloat(q.GetQuantile()))
+				}
+				add("_sum", m.GetSummary().GetSampleSum())
+				add("_count", float64(m.GetSummary().GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				infSeen := false
+				for _, b := range m.GetHistogram().GetBucket() {
+					add("_bucket", float64(b.GetCumulativeCount()), "le", formatFloat(b.GetUpperBound()))
+					infSeen = math.IsInf(b.GetUpperBound(), +1)
+				}
+				if !infSeen {
+					add("_bucket", float64(m.GetHistogram().GetSampleCount()), "le", "+Inf")
+				}
+				add("_sum", m.GetHistogram().GetSampleSum())
+				add("_count", float64(m.GetHistogram().GetSampleCount()))
+			}
+		}
+	}
+
+	return series
+}
+
+// sortedLabels returns the labels sorted by name, as remote write requires.
+func sortedLabels(labels map[string]string) []*Label {
+	sorted := make([]*Label, 0, len(labels))
+	for name, value := range labels {
+		sorted = append(sorted, &Label{Name: name, Value: value})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, +1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,108 @@
+package push
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+type checksAPI []pingdom.CheckResponse
+
+func (c checksAPI) ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error) {
+	return c, nil
+}
+
+func TestRemoteWriter(t *testing.T) {
+	var received *WriteRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			t.Errorf("Content-Encoding = %q, want snappy", r.Header.Get("Content-Encoding"))
+		}
+
+		compressed, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := snappy.Decode(nil, compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		received = &WriteRequest{}
+		if err := proto.Unmarshal(content, received); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer server.Close()
+
+	c := exporter.DefaultConfig()
+	c.API = checksAPI{
+		{ID: 1, Name: "a", Status: "up", LastTestTime: 1500000000, LastResponseTime: 42},
+	}
+	exp, err := exporter.New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exp.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(exp.WithTestTimestamps())
+
+	wc := DefaultRemoteWriterConfig()
+	wc.URL = server.URL
+	wc.Labels = map[string]string{"job": "pingdom"}
+	wc.Gatherer = registry
+	w, err := NewRemoteWriter(wc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := w.Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	series := map[string]*Sample{}
+	for _, ts := range received.Timeseries {
+		var labels []string
+		for i, l := range ts.Labels {
+			if i > 0 && ts.Labels[i-1].Name >= l.Name {
+				t.Errorf("labels are not sorted: %v", ts.Labels)
+			}
+			labels = append(labels, l.Name+"="+l.Value)
+		}
+		if len(ts.Samples) != 1 {
+			t.Fatalf("%v has %d samples, want 1", labels, len(ts.Samples))
+		}
+		series[strings.Join(labels, ",")] = ts.Samples[0]
+	}
+
+	responseTime := series["__name__=pingdom_check_response_time,hostname=,id=1,job=pingdom,name=a,paused=false,resolution=0,tags="]
+	if responseTime == nil {
+		t.Fatalf("pingdom_check_response_time not written, got %v", series)
+	}
+	if responseTime.Value != 42 || responseTime.Timestamp != 1500000000000 {
+		t.Errorf("pingdom_check_response_time = %v, want 42 at the last test time", responseTime)
+	}
+
+	up := series["__name__=pingdom_up,job=pingdom"]
+	if up == nil {
+		t.Fatalf("pingdom_up not written, got %v", series)
+	}
+	if up.Value != 1 || up.Timestamp < start.Unix()*1000 {
+		t.Errorf("pingdom_up = %v, want 1 at the current time", up)
+	}
+}
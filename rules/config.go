@@ -0,0 +1,100 @@
+package rules
+
+import (
+	"fmt"
+	"io/ioutil"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/slo"
+)
+
+// File is the configuration file of the generated rules.
+type File struct {
+	// For is how long a check must be down before alerting.
+	For slo.Duration `yaml:"for"`
+	// StaleFor is how long the exporter must fail to poll Pingdom, or be
+	// absent, before alerting.
+	StaleFor slo.Duration `yaml:"stale_for"`
+
+	ResponseTime ResponseTime `yaml:"response_time"`
+
+	// Labels are added to the alerts of every check. Values are templates,
+	// see Generator, and labels expanding to an empty value are left out.
+	Labels map[string]string `yaml:"labels"`
+	// Tags override labels for the checks with the tag, in alphabetical
+	// order of the tags.
+	Tags map[string]map[string]string `yaml:"tags"`
+}
+
+// ResponseTime configures the alerts on slow checks.
+type ResponseTime struct {
+	// Threshold is the response time in milliseconds above which to alert,
+	// 0 disables the alerts.
+	Threshold int          `yaml:"threshold"`
+	For       slo.Duration `yaml:"for"`
+}
+
+// DefaultFile is the configuration used without a configuration file.
+func DefaultFile() *File {
+	return &File{
+		For:      slo.Duration(5 * time.Minute),
+		StaleFor: slo.Duration(10 * time.Minute),
+		ResponseTime: ResponseTime{
+			Threshold: 0,
+			For:       slo.Duration(15 * time.Minute),
+		},
+		Labels: map[string]string{
+			"severity": "warning",
+		},
+		Tags: nil,
+	}
+}
+
+// LoadFile reads a configuration file. Unset fields keep the values of
+// DefaultFile.
+func LoadFile(path string) (*File, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Strict unmarshalling rejects keys already set in maps, so the default
+	// labels are only set if the file has none.
+	f := DefaultFile()
+	f.Labels = nil
+	if err := yaml.UnmarshalStrict(content, f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if f.Labels == nil {
+		f.Labels = DefaultFile().Labels
+	}
+
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+
+	return f, nil
+}
+
+func (f *File) validate() error {
+	if f.ResponseTime.Threshold < 0 {
+		return fmt.Errorf("response_time.threshold must not be negative")
+	}
+
+	templates := []map[string]string{f.Labels}
+	for _, labels := range f.Tags {
+		templates = append(templates, labels)
+	}
+	for _, labels := range templates {
+		for name, text := range labels {
+			if _, err := template.New(name).Funcs(templateFuncs(nil)).Parse(text); err != nil {
+				return fmt.Errorf("invalid template of label %s: %v", name, err)
+			}
+		}
+	}
+
+	return nil
+}
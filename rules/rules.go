@@ -0,0 +1,267 @@
+// Package rules generates Prometheus alerting and recording rules for the
+// checks of a Pingdom account.
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+// RuleFile is a Prometheus rule file.
+type RuleFile struct {
+	Groups []RuleGroup `yaml:"groups"`
+}
+
+// RuleGroup is a group of rules.
+type RuleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule is an alerting rule, or a recording rule if Record is set.
+type Rule struct {
+	Record      string            `yaml:"record,omitempty"`
+	Alert       string            `yaml:"alert,omitempty"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// PrometheusRule is the custom resource of the Prometheus operator holding
+// rule groups.
+type PrometheusRule struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   PrometheusRuleMeta `yaml:"metadata"`
+	Spec       RuleFile           `yaml:"spec"`
+}
+
+// PrometheusRuleMeta is the metadata of a PrometheusRule.
+type PrometheusRuleMeta struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+// templateData is the data label templates are executed with.
+type templateData struct {
+	ID       int
+	Name     string
+	Hostname string
+	Tags     []string
+}
+
+// templateFuncs are the functions available to label templates.
+func templateFuncs(tags []string) template.FuncMap {
+	return template.FuncMap{
+		// tagPrefix returns the rest of the first tag with the prefix,
+		// e.g. web for the tag team-web and the prefix team-.
+		"tagPrefix": func(prefix string) string {
+			for _, tag := range tags {
+				if strings.HasPrefix(tag, prefix) {
+					return strings.TrimPrefix(tag, prefix)
+				}
+			}
+			return ""
+		},
+		// hasTag returns true if the check has the tag.
+		"hasTag": func(name string) bool {
+			for _, tag := range tags {
+				if tag == name {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// availabilityWindows are the windows the availability of the checks is
+// recorded over.
+var availabilityWindows = []string{"1h", "1d"}
+
+// Generate returns the rules for the checks: an alert per check when its
+// pingdom_check_status is downValue and, if configured, when it is slow,
+// alerts when the exporter fails to poll Pingdom, and recording rules for the
+// availability of the checks and the ratio of checks down.
+func Generate(file *File, checks []pingdom.CheckResponse, downValue float64) (*RuleFile, error) {
+	sorted := append([]pingdom.CheckResponse{}, checks...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	exporterGroup := RuleGroup{
+		Name: "pingdom-exporter",
+		Rules: []Rule{
+			{
+				Alert:  "PingdomExporterDown",
+				Expr:   "pingdom_up == 0",
+				For:    file.StaleFor.String(),
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary":     "Pingdom exporter fails to poll Pingdom",
+					"description": "The Pingdom exporter {{ $labels.instance }} failed to poll the Pingdom API for " + file.StaleFor.String() + ", check statuses are stale.",
+				},
+			},
+			{
+				Alert:  "PingdomExporterAbsent",
+				Expr:   "absent(pingdom_up)",
+				For:    file.StaleFor.String(),
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary":     "Pingdom exporter is not scraped",
+					"description": "No Pingdom exporter was scraped for " + file.StaleFor.String() + ", Pingdom checks are not alerted on.",
+				},
+			},
+		},
+	}
+
+	checksGroup := RuleGroup{
+		Name: "pingdom-checks",
+	}
+
+	for _, check := range sorted {
+		checkLabels, err := CheckLabels(file, check)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate labels of check %d: %v", check.ID, err)
+		}
+		labels := map[string]string{}
+		for name, value := range checkLabels {
+			labels[name] = escapeTemplate(value)
+		}
+		selector := fmt.Sprintf(`id="%d"`, check.ID)
+		name, hostname := escapeTemplate(check.Name), escapeTemplate(check.Hostname)
+
+		checksGroup.Rules = append(checksGroup.Rules, Rule{
+			Alert:  "PingdomCheckDown",
+			Expr:   fmt.Sprintf(`pingdom_check_status{%s} == %s`, selector, strconv.FormatFloat(downValue, 'g', -1, 64)),
+			For:    file.For.String(),
+			Labels: labels,
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("Pingdom check %s is down", name),
+				"description": fmt.Sprintf("Pingdom reports %s (%s) down for %s.", name, hostname, file.For),
+			},
+		})
+
+		if file.ResponseTime.Threshold > 0 {
+			checksGroup.Rules = append(checksGroup.Rules, Rule{
+				Alert:  "PingdomCheckSlow",
+				Expr:   fmt.Sprintf(`pingdom_check_response_time{%s} > %d`, selector, file.ResponseTime.Threshold),
+				For:    file.ResponseTime.For.String(),
+				Labels: labels,
+				Annotations: map[string]string{
+					"summary":     fmt.Sprintf("Pingdom check %s is slow", name),
+					"description": fmt.Sprintf("%s (%s) responds in {{ $value }}ms, above %dms for %s.", name, hostname, file.ResponseTime.Threshold, file.ResponseTime.For),
+				},
+			})
+		}
+	}
+
+	rules := &RuleFile{
+		Groups: []RuleGroup{exporterGroup, checksGroup, recordingGroup(downValue)},
+	}
+
+	return rules, nil
+}
+
+// recordingGroup returns the recording rules: whether each check is
+// available, i.e. not down, its availability ratio over each window, and the
+// ratio of the checks down.
+func recordingGroup(downValue float64) RuleGroup {
+	down := strconv.FormatFloat(downValue, 'g', -1, 64)
+
+	group := RuleGroup{
+		Name: "pingdom-recording",
+		Rules: []Rule{
+			{
+				Record: "pingdom_check:available",
+				Expr:   "pingdom_check_status != bool " + down,
+			},
+		},
+	}
+	for _, window := range availabilityWindows {
+		group.Rules = append(group.Rules, Rule{
+			Record: "pingdom_check:availability:ratio_" + window,
+			Expr:   fmt.Sprintf("avg_over_time(pingdom_check:available[%s])", window),
+		})
+	}
+	group.Rules = append(group.Rules, Rule{
+		Record: "pingdom:checks_down:ratio",
+		Expr:   fmt.Sprintf("sum(pingdom_check_status == bool %s) / count(pingdom_check_status)", down),
+	})
+
+	return group
+}
+
+// escapeTemplate escapes the text for Prometheus, which executes the labels
+// and annotations of alerting rules as templates.
+func escapeTemplate(text string) string {
+	return strings.Replace(text, "{{", `{{ "{{" }}`, -1)
+}
+
+// CheckLabels returns the labels of the alerts of the check, from the label
+// templates and the overrides of its tags. Pause tags are left out.
+func CheckLabels(file *File, check pingdom.CheckResponse) (map[string]string, error) {
+	tags := exporter.WithoutPauseTags(check)
+
+	templates := map[string]string{}
+	for name, text := range file.Labels {
+		templates[name] = text
+	}
+
+	overridden := append([]string{}, tags...)
+	sort.Strings(overridden)
+	for _, tag := range overridden {
+		for name, text := range file.Tags[tag] {
+			templates[name] = text
+		}
+	}
+
+	data := templateData{
+		ID:       check.ID,
+		Name:     check.Name,
+		Hostname: check.Hostname,
+		Tags:     tags,
+	}
+
+	labels := map[string]string{
+		"check_id": strconv.Itoa(check.ID),
+	}
+	for name, text := range templates {
+		t, err := template.New(name).Funcs(templateFuncs(tags)).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		if value := strings.TrimSpace(buf.String()); value != "" {
+			labels[name] = value
+		}
+	}
+
+	return labels, nil
+}
+
+// PrometheusRule returns the rule groups as a PrometheusRule resource.
+func (f *RuleFile) PrometheusRule(name, namespace string, labels map[string]string) *PrometheusRule {
+	return &PrometheusRule{
+		APIVersion: "monitoring.coreos.com/v1",
+		Kind:       "PrometheusRule",
+		Metadata: PrometheusRuleMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: *f,
+	}
+}
@@ -0,0 +1,93 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/russellcardullo/go-pingdom/pingdom"
+)
+
+func TestGenerate(t *testing.T) {
+	file := DefaultFile()
+	file.ResponseTime.Threshold = 2000
+	file.Labels["team"] = `{{ tagPrefix "team-" }}`
+	file.Labels["page"] = `{{ if hasTag "production" }}yes{{ end }}`
+	file.Tags = map[string]map[string]string{
+		"production": {"severity": "critical"},
+	}
+
+	checks := []pingdom.CheckResponse{
+		{ID: 2, Name: "b {{ x }}"},
+		{ID: 1, Name: "a", Tags: []pingdom.CheckResponseTag{{Name: "production"}, {Name: "team-web"}}},
+	}
+
+	ruleFile, err := Generate(file, checks, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ruleFile.Groups) != 3 || len(ruleFile.Groups[0].Rules) != 2 {
+		t.Fatalf("unexpected exporter rules %+v", ruleFile.Groups)
+	}
+
+	rules := ruleFile.Groups[1].Rules
+	if len(rules) != 4 {
+		t.Fatalf("got %d check rules, want 4", len(rules))
+	}
+
+	down := rules[0]
+	if down.Alert != "PingdomCheckDown" || down.Expr != `pingdom_check_status{id="1"} == 2` || down.For != "5m" {
+		t.Errorf("unexpected rule %+v", down)
+	}
+	want := map[string]string{"check_id": "1", "severity": "critical", "team": "web", "page": "yes"}
+	for name, value := range want {
+		if down.Labels[name] != value {
+			t.Errorf("label %s = %q, want %q", name, down.Labels[name], value)
+		}
+	}
+
+	slow := rules[3]
+	if slow.Alert != "PingdomCheckSlow" || slow.Expr != `pingdom_check_response_time{id="2"} > 2000` {
+		t.Errorf("unexpected rule %+v", slow)
+	}
+	if slow.Labels["severity"] != "warning" {
+		t.Errorf("severity = %q, want warning", slow.Labels["severity"])
+	}
+	if _, ok := slow.Labels["team"]; ok {
+		t.Errorf("empty team label is set")
+	}
+	if want := `Pingdom check b {{ "{{" }} x }} is slow`; slow.Annotations["summary"] != want {
+		t.Errorf("summary = %q, want %q", slow.Annotations["summary"], want)
+	}
+
+	recording := ruleFile.Groups[2].Rules
+	wantRecording := []Rule{
+		{Record: "pingdom_check:available", Expr: "pingdom_check_status != bool 2"},
+		{Record: "pingdom_check:availability:ratio_1h", Expr: "avg_over_time(pingdom_check:available[1h])"},
+		{Record: "pingdom_check:availability:ratio_1d", Expr: "avg_over_time(pingdom_check:available[1d])"},
+		{Record: "pingdom:checks_down:ratio", Expr: "sum(pingdom_check_status == bool 2) / count(pingdom_check_status)"},
+	}
+	if !reflect.DeepEqual(recording, wantRecording) {
+		t.Errorf("got recording rules %+v, want %+v", recording, wantRecording)
+	}
+}
+
+func TestCheckLabelsWithoutPauseTags(t *testing.T) {
+	file := DefaultFile()
+	file.Labels["pause"] = `{{ tagPrefix "paused-" }}`
+	file.Tags = map[string]map[string]string{
+		"paused-by-jane": {"severity": "none"},
+	}
+	check := pingdom.CheckResponse{ID: 1, Paused: true, Tags: []pingdom.CheckResponseTag{{Name: "production"}, {Name: "paused-until-201805041330"}, {Name: "paused-by-jane"}}}
+
+	labels, err := CheckLabels(file, check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := labels["pause"]; ok {
+		t.Errorf("pause tags are available to templates: %v", labels)
+	}
+	if labels["severity"] != "warning" {
+		t.Errorf("pause tags override labels: %v", labels)
+	}
+}
@@ -0,0 +1,158 @@
+package slo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultBurnRateWindows are the windows of pingdom_slo_burn_rate, as used
+// by multi-window, multi-burn-rate alerts.
+var DefaultBurnRateWindows = []Duration{
+	Duration(time.Hour),
+	Duration(6 * time.Hour),
+	Duration(24 * time.Hour),
+	Duration(3 * 24 * time.Hour),
+}
+
+// File is the SLO configuration file.
+type File struct {
+	// BurnRateWindows default to DefaultBurnRateWindows.
+	BurnRateWindows []Duration  `yaml:"burn_rate_windows"`
+	Objectives      []Objective `yaml:"objectives"`
+}
+
+// Objective is an availability target for checks, selected by ID or tag.
+type Objective struct {
+	Name string `yaml:"name"`
+	// Checks are the IDs of the checks of the objective.
+	Checks []int `yaml:"checks"`
+	// Tags select the checks with any of the tags.
+	Tags []string `yaml:"tags"`
+	// Target is the ratio of time the checks must be up, e.g. 0.999.
+	Target float64  `yaml:"target"`
+	Window Duration `yaml:"window"`
+}
+
+// LoadFile reads and validates an SLO configuration file.
+func LoadFile(path string) (*File, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{}
+	if err := yaml.UnmarshalStrict(content, f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	if len(f.BurnRateWindows) == 0 {
+		f.BurnRateWindows = DefaultBurnRateWindows
+	}
+
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+
+	return f, nil
+}
+
+func (f *File) validate() error {
+	windows := map[Duration]bool{}
+	for _, window := range f.BurnRateWindows {
+		if window <= 0 {
+			return fmt.Errorf("burn rate windows must be positive")
+		}
+		if windows[window] {
+			return fmt.Errorf("burn rate window %s is defined twice", window)
+		}
+		windows[window] = true
+	}
+
+	names := map[string]bool{}
+	for i, o := range f.Objectives {
+		if o.Name == "" {
+			return fmt.Errorf("objective %d has no name", i)
+		}
+		if names[o.Name] {
+			return fmt.Errorf("objective %q is defined twice", o.Name)
+		}
+		names[o.Name] = true
+
+		if len(o.Checks) == 0 && len(o.Tags) == 0 {
+			return fmt.Errorf("objective %q selects no checks, set checks or tags", o.Name)
+		}
+		if o.Target <= 0 || o.Target >= 1 {
+			return fmt.Errorf("objective %q has target %v, expected a ratio between 0 and 1, e.g. 0.999", o.Name, o.Target)
+		}
+		if o.Window <= 0 {
+			return fmt.Errorf("objective %q has no window", o.Name)
+		}
+	}
+
+	return nil
+}
+
+// Duration is a time.Duration in YAML, which also accepts days and weeks
+// like Prometheus, e.g. 30d.
+type Duration time.Duration
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// String formats the duration in the largest whole unit, e.g. 30d or 6h.
+func (d Duration) String() string {
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+	}
+	for _, unit := range units {
+		if time.Duration(d)%unit.size == 0 {
+			return strconv.FormatInt(int64(time.Duration(d)/unit.size), 10) + unit.suffix
+		}
+	}
+
+	return time.Duration(d).String()
+}
+
+// ParseDuration parses a duration like time.ParseDuration, with the
+// additional d and w units for days and weeks.
+func ParseDuration(s string) (time.Duration, error) {
+	for suffix, size := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(n) * size, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,357 @@
+// Package slo exports availability objectives of Pingdom checks, with their
+// error budgets and burn rates, computed from the outage history of the
+// checks.
+package slo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+// objectiveLabels are the labels of the per objective and check metrics.
+var objectiveLabels = []string{"slo", "id", "name"}
+
+var (
+	upDesc = prometheus.NewDesc(
+		"pingdom_slo_up",
+		"Whether the last refresh of the SLO metrics was successful (1: up, 0: down)",
+		nil, nil,
+	)
+
+	targetDesc = prometheus.NewDesc(
+		"pingdom_slo_target",
+		"The ratio of time the check must be up over the window of the objective",
+		objectiveLabels, nil,
+	)
+
+	windowDesc = prometheus.NewDesc(
+		"pingdom_slo_window_seconds",
+		"The window of the objective",
+		objectiveLabels, nil,
+	)
+
+	availabilityDesc = prometheus.NewDesc(
+		"pingdom_slo_availability_ratio",
+		"The ratio of monitored time the check was up over the window of the objective",
+		objectiveLabels, nil,
+	)
+
+	errorBudgetRemainingDesc = prometheus.NewDesc(
+		"pingdom_slo_error_budget_remaining_ratio",
+		"The ratio of the error budget of the objective left, negative once exhausted",
+		objectiveLabels, nil,
+	)
+
+	burnRateDesc = prometheus.NewDesc(
+		"pingdom_slo_burn_rate",
+		"The rate at which the error budget is spent over the burn rate window (1: exhausted exactly at the end of the objective window)",
+		append(append([]string{}, objectiveLabels...), "window"), nil,
+	)
+)
+
+// PingdomAPI is the part of the Pingdom API used by the Collector.
+// exporter.Client implements it.
+type PingdomAPI interface {
+	ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error)
+	Outages(ctx context.Context, checkID int, params map[string]string) ([]exporter.OutageState, error)
+}
+
+// Config represents the configuration used to create a Collector.
+type Config struct {
+	API    PingdomAPI
+	Logger log.Logger
+	File   *File
+}
+
+// DefaultConfig provides a default configuration to create a Collector.
+func DefaultConfig() Config {
+	return Config{
+		API:    nil,
+		Logger: log.NewNopLogger(),
+		File:   nil,
+	}
+}
+
+// Collector exports the objectives of the configuration file for every
+// check they select.
+type Collector struct {
+	api    PingdomAPI
+	logger log.Logger
+	file   *File
+
+	mu        sync.RWMutex
+	refreshed bool
+	err       error
+	results   []result
+}
+
+// result is an objective evaluated for a check.
+type result struct {
+	objective Objective
+	check     pingdom.CheckResponse
+
+	// ok is false if the check was never monitored in the window, in which
+	// case only the target is exported.
+	ok           bool
+	availability float64
+	burnRates    map[Duration]float64
+}
+
+// New creates a new configured Collector.
+func New(config Config) (*Collector, error) {
+	if config.API == nil {
+		return nil, fmt.Errorf("config.API must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, fmt.Errorf("config.Logger must not be empty")
+	}
+	if config.File == nil {
+		return nil, fmt.Errorf("config.File must not be empty")
+	}
+
+	c := &Collector{
+		api:    config.API,
+		logger: config.Logger,
+		file:   config.File,
+	}
+
+	return c, nil
+}
+
+// Run refreshes the metrics every interval until the context is cancelled.
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	for {
+		c.Refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Refresh evaluates the objectives once, fetching the outage history of
+// every selected check. Checks whose history fails to be fetched keep their
+// previous results.
+func (c *Collector) Refresh(ctx context.Context) error {
+	checks, err := c.api.ListChecks(ctx, map[string]string{"include_tags": "true"})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		level.Error(c.logger).Log("msg", "failed to get checks", "endpoint", "/api/2.0/checks", "err", err)
+
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+
+		return err
+	}
+
+	previous := map[string]result{}
+	c.mu.RLock()
+	for _, r := range c.results {
+		previous[r.key()] = r
+	}
+	c.mu.RUnlock()
+
+	now := time.Now()
+	var results []result
+	var lastErr error
+
+	for _, check := range checks {
+		objectives := c.objectives(check)
+		if len(objectives) == 0 {
+			continue
+		}
+
+		// Fetch the history once per check, for the longest window.
+		var longest Duration
+		for _, o := range objectives {
+			if o.Window > longest {
+				longest = o.Window
+			}
+		}
+		for _, window := range c.file.BurnRateWindows {
+			if window > longest {
+				longest = window
+			}
+		}
+
+		states, err := c.api.Outages(ctx, check.ID, map[string]string{
+			"from": strconv.FormatInt(now.Add(-time.Duration(longest)).Unix(), 10),
+			"to":   strconv.FormatInt(now.Unix(), 10),
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			level.Error(c.logger).Log("msg", "failed to get outages", "check_id", check.ID, "err", err)
+			lastErr = err
+
+			for _, o := range objectives {
+				if r, ok := previous[result{objective: o, check: check}.key()]; ok {
+					results = append(results, r)
+				}
+			}
+			continue
+		}
+
+		for _, o := range objectives {
+			results = append(results, c.evaluate(o, check, states, now))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.refreshed = true
+	c.err = lastErr
+	c.results = results
+
+	return lastErr
+}
+
+// objectives returns the objectives selecting the check.
+func (c *Collector) objectives(check pingdom.CheckResponse) []Objective {
+	var objectives []Objective
+
+	for _, o := range c.file.Objectives {
+		if selects(o, check) {
+			objectives = append(objectives, o)
+		}
+	}
+
+	return objectives
+}
+
+func selects(o Objective, check pingdom.CheckResponse) bool {
+	for _, id := range o.Checks {
+		if id == check.ID {
+			return true
+		}
+	}
+	for _, tag := range o.Tags {
+		for _, checkTag := range exporter.WithoutPauseTags(check) {
+			if tag == checkTag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c *Collector) evaluate(o Objective, check pingdom.CheckResponse, states []exporter.OutageState, now time.Time) result {
+	r := result{
+		objective: o,
+		check:     check,
+		burnRates: map[Duration]float64{},
+	}
+
+	r.availability, r.ok = Availability(states, now.Add(-time.Duration(o.Window)), now)
+	if !r.ok {
+		return r
+	}
+
+	for _, window := range c.file.BurnRateWindows {
+		availability, ok := Availability(states, now.Add(-time.Duration(window)), now)
+		if ok {
+			r.burnRates[window] = (1 - availability) / (1 - o.Target)
+		}
+	}
+
+	return r
+}
+
+func (r result) key() string {
+	return r.objective.Name + "/" + strconv.Itoa(r.check.ID)
+}
+
+// Availability returns the ratio of time the check was up between from and
+// to, out of the time it was up or down. Unknown periods, e.g. while the
+// check was paused, are left out. It returns false if the check was neither
+// up nor down in the period.
+func Availability(states []exporter.OutageState, from, to time.Time) (float64, bool) {
+	var up, down int64
+
+	for _, state := range states {
+		start, end := state.TimeFrom, state.TimeTo
+		if start < from.Unix() {
+			start = from.Unix()
+		}
+		if end > to.Unix() {
+			end = to.Unix()
+		}
+		if end <= start {
+			continue
+		}
+
+		switch state.Status {
+		case "up":
+			up += end - start
+		case "down":
+			down += end - start
+		}
+	}
+
+	if up+down == 0 {
+		return 0, false
+	}
+
+	return float64(up) / float64(up+down), true
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	ch <- targetDesc
+	ch <- windowDesc
+	ch <- availabilityDesc
+	ch <- errorBudgetRemainingDesc
+	ch <- burnRateDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	up := 0.0
+	if c.refreshed && c.err == nil {
+		up = 1
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up)
+
+	for _, r := range c.results {
+		labels := []string{r.objective.Name, strconv.Itoa(r.check.ID), r.check.Name}
+
+		ch <- prometheus.MustNewConstMetric(targetDesc, prometheus.GaugeValue, r.objective.Target, labels...)
+		ch <- prometheus.MustNewConstMetric(windowDesc, prometheus.GaugeValue, time.Duration(r.objective.Window).Seconds(), labels...)
+
+		if !r.ok {
+			continue
+		}
+
+		errorBudgetRemaining := 1 - (1-r.availability)/(1-r.objective.Target)
+		ch <- prometheus.MustNewConstMetric(availabilityDesc, prometheus.GaugeValue, r.availability, labels...)
+		ch <- prometheus.MustNewConstMetric(errorBudgetRemainingDesc, prometheus.GaugeValue, errorBudgetRemaining, labels...)
+
+		for _, window := range c.file.BurnRateWindows {
+			if burnRate, ok := r.burnRates[window]; ok {
+				ch <- prometheus.MustNewConstMetric(burnRateDesc, prometheus.GaugeValue, burnRate, append(labels, window.String())...)
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package slo
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/russellcardullo/go-pingdom/pingdom"
+
+	"github.com/giantswarm/prometheus-pingdom-exporter/exporter"
+)
+
+type mockAPI struct {
+	checks  []pingdom.CheckResponse
+	outages map[int][]exporter.OutageState
+}
+
+func (m *mockAPI) ListChecks(ctx context.Context, params map[string]string) ([]pingdom.CheckResponse, error) {
+	return m.checks, nil
+}
+
+func (m *mockAPI) Outages(ctx context.Context, checkID int, params map[string]string) ([]exporter.OutageState, error) {
+	return m.outages[checkID], nil
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		content string
+		valid   bool
+	}{
+		{"objectives:\n- {name: a, tags: [production], target: 0.999, window: 30d}\n", true},
+		{"burn_rate_windows: [5m, 1h]\nobjectives:\n- {name: a, checks: [1], target: 0.99, window: 1w}\n", true},
+		{"objectives:\n- {name: a, target: 0.999, window: 30d}\n", false},
+		{"objectives:\n- {name: a, checks: [1], target: 99.9, window: 30d}\n", false},
+		{"objectives:\n- {name: a, checks: [1], target: 0.999, window: 30x}\n", false},
+		{"objectives:\n- {name: a, checks: [1], target: 0.999, window: 30d, unknown: 1}\n", false},
+	}
+
+	for _, tc := range tests {
+		path := filepath.Join(dir, "slo.yml")
+		if err := ioutil.WriteFile(path, []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := LoadFile(path)
+		if tc.valid && err != nil {
+			t.Errorf("LoadFile(%q) failed: %v", tc.content, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("LoadFile(%q) did not fail", tc.content)
+		}
+		if tc.valid && len(f.BurnRateWindows) == 0 {
+			t.Errorf("LoadFile(%q) has no burn rate windows", tc.content)
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	now := time.Now().Unix()
+	day := int64(24 * 3600)
+
+	api := &mockAPI{
+		checks: []pingdom.CheckResponse{
+			{ID: 1, Name: "a", Tags: []pingdom.CheckResponseTag{{Name: "production"}}},
+			{ID: 2, Name: "b"},
+		},
+		outages: map[int][]exporter.OutageState{
+			// Down for 43 minutes 10 days ago, and for 6 minutes an hour
+			// ago.
+			1: {
+				{Status: "up", TimeFrom: now - 30*day, TimeTo: now - 10*day},
+				{Status: "down", TimeFrom: now - 10*day, TimeTo: now - 10*day + 43*60},
+				{Status: "up", TimeFrom: now - 10*day + 43*60, TimeTo: now - 3600},
+				{Status: "down", TimeFrom: now - 3600, TimeTo: now - 3600 + 6*60},
+				{Status: "up", TimeFrom: now - 3600 + 6*60, TimeTo: now},
+			},
+		},
+	}
+
+	c := DefaultConfig()
+	c.API = api
+	c.File = &File{
+		BurnRateWindows: []Duration{Duration(time.Hour), Duration(30 * 24 * time.Hour)},
+		Objectives: []Objective{
+			{Name: "availability", Tags: []string{"production"}, Target: 0.999, Window: Duration(30 * 24 * time.Hour)},
+		},
+	}
+	collector, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := collector.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			key := family.GetName()
+			for _, pair := range m.GetLabel() {
+				if pair.GetName() == "window" || pair.GetName() == "id" {
+					key += "," + pair.GetValue()
+				}
+			}
+			if family.GetType() == dto.MetricType_GAUGE {
+				values[key] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	// 49 minutes down out of 30 days.
+	availability := 1 - 49.0/(30*24*60)
+	want := map[string]float64{
+		"pingdom_slo_up":                             1,
+		"pingdom_slo_target,1":                       0.999,
+		"pingdom_slo_availability_ratio,1":           availability,
+		"pingdom_slo_error_budget_remaining_ratio,1": 1 - (1-availability)/0.001,
+		"pingdom_slo_burn_rate,1,1h":                 (6.0 / 60) / 0.001,
+		"pingdom_slo_burn_rate,1,30d":                (1 - availability) / 0.001,
+	}
+	for key, value := range want {
+		got, ok := values[key]
+		if !ok {
+			t.Errorf("missing %s", key)
+			continue
+		}
+		if math.Abs(got-value) > 1e-6 {
+			t.Errorf("%s = %v, want %v", key, got, value)
+		}
+	}
+
+	if _, ok := values["pingdom_slo_target,2"]; ok {
+		t.Errorf("check without the tag of the objective is exported")
+	}
+}
+
+func TestSelectsWithoutPauseTags(t *testing.T) {
+	check := pingdom.CheckResponse{ID: 1, Paused: true, Tags: []pingdom.CheckResponseTag{{Name: "production"}, {Name: "paused-until-201805041330"}, {Name: "paused-by-jane"}}}
+
+	if !selects(Objective{Tags: []string{"production"}}, check) {
+		t.Errorf("expected the check to be selected by its tag")
+	}
+	if selects(Objective{Tags: []string{"paused-by-jane"}}, check) {
+		t.Errorf("expected the check not to be selected by a pause tag")
+	}
+}